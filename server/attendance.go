@@ -0,0 +1,556 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	attendanceKeyPrefix    = "attendance_"
+	activeMeetingKeyPrefix = "active_meeting_"
+
+	// occurrenceDateFormat is the format used to identify a meeting occurrence
+	occurrenceDateFormat = "2006-01-02"
+
+	// maxAttendanceReportDays bounds the date range of an attendance report
+	maxAttendanceReportDays = 366
+)
+
+// Attendance records who attended a meeting occurrence
+type Attendance struct {
+	ChannelID string   `json:"channelId"`
+	Date      string   `json:"date"`
+	Hashtag   string   `json:"hashtag"`
+	PostID    string   `json:"postId"`
+	StartedAt int64    `json:"startedAt"`
+	EndedAt   int64    `json:"endedAt"`
+	Attendees []string `json:"attendees"`
+}
+
+// AttendanceReportRow is the attendance of a single user over a date range
+type AttendanceReportRow struct {
+	UserID   string `json:"userId"`
+	Username string `json:"username"`
+	Attended int    `json:"attended"`
+}
+
+// AttendanceReport summarizes the attendance of a meeting over a date range
+type AttendanceReport struct {
+	ChannelID   string                 `json:"channelId"`
+	From        string                 `json:"from"`
+	To          string                 `json:"to"`
+	Occurrences []string               `json:"occurrences"`
+	Rows        []*AttendanceReportRow `json:"rows"`
+}
+
+func attendanceKey(channelID, date string) string {
+	return attendanceKeyPrefix + channelID + "_" + date
+}
+
+// addAttendee adds the user to the attendees if not already present
+func (a *Attendance) addAttendee(userID string) bool {
+	for _, attendee := range a.Attendees {
+		if attendee == userID {
+			return false
+		}
+	}
+	a.Attendees = append(a.Attendees, userID)
+	return true
+}
+
+// GetAttendance returns the attendance of the meeting occurrence on the given date,
+// or nil if none was recorded.
+func (p *Plugin) GetAttendance(channelID, date string) (*Attendance, error) {
+	attendanceBytes, appErr := p.API.KVGet(attendanceKey(channelID, date))
+	if appErr != nil {
+		return nil, appErr
+	}
+	if attendanceBytes == nil {
+		return nil, nil
+	}
+
+	var attendance *Attendance
+	if err := json.Unmarshal(attendanceBytes, &attendance); err != nil {
+		return nil, err
+	}
+
+	return attendance, nil
+}
+
+// updateAttendance applies update to the attendance of the meeting occurrence on the given date,
+// nil if none was recorded, and saves the attendance it returns unless it reports no change.
+// Members join concurrently, so the attendance is updated with compare and set.
+func (p *Plugin) updateAttendance(channelID, date string, update func(attendance *Attendance) (*Attendance, bool)) error {
+	for i := 0; i < 5; i++ {
+		oldBytes, appErr := p.API.KVGet(attendanceKey(channelID, date))
+		if appErr != nil {
+			return appErr
+		}
+
+		var attendance *Attendance
+		if oldBytes != nil {
+			if err := json.Unmarshal(oldBytes, &attendance); err != nil {
+				return err
+			}
+		}
+
+		attendance, changed := update(attendance)
+		if !changed {
+			return nil
+		}
+
+		newBytes, err := json.Marshal(attendance)
+		if err != nil {
+			return err
+		}
+
+		saved, appErr := p.API.KVSetWithOptions(attendanceKey(channelID, date), newBytes, model.PluginKVSetOptions{
+			Atomic:   true,
+			OldValue: oldBytes,
+		})
+		if appErr != nil {
+			return appErr
+		}
+		if saved {
+			return nil
+		}
+	}
+
+	return errors.New("the attendance was updated concurrently too many times")
+}
+
+// getActiveMeetingDate returns the date of the meeting in progress in the channel, if any.
+func (p *Plugin) getActiveMeetingDate(channelID string) (string, error) {
+	dateBytes, appErr := p.API.KVGet(activeMeetingKeyPrefix + channelID)
+	if appErr != nil {
+		return "", appErr
+	}
+
+	return string(dateBytes), nil
+}
+
+// currentOccurrence returns the start of the meeting occurrence started now: the occurrence of
+// today even if it is late, or else the next one that isn't cancelled. If weekday isn't -1 the
+// occurrence is on the next given day.
+func (m *Meeting) currentOccurrence(now time.Time, weekday int) (time.Time, error) {
+	year, month, day := now.In(m.location()).Date()
+	return m.upcomingOccurrence(time.Date(year, month, day, 0, 0, 0, 0, m.location()), false, weekday)
+}
+
+func (p *Plugin) executeCommandStart(args *model.CommandArgs) *model.CommandResponse {
+	split := strings.Fields(args.Command)
+
+	meeting, err := p.GetMeeting(args.ChannelId)
+	if err != nil {
+		return responsef("Error getting meeting information for this channel")
+	}
+
+	activeDate, err := p.getActiveMeetingDate(args.ChannelId)
+	if err != nil {
+		return responsef("Error checking for a meeting in progress")
+	}
+	if activeDate != "" {
+		return responsef("The meeting of %s is already in progress. Use `/agenda end` to end it.", activeDate)
+	}
+
+	weekday := -1
+	if len(split) > 2 {
+		parsedWeekday, parseErr := parseSchedule(split[2])
+		if parseErr != nil {
+			return responsef(parseErr.Error())
+		}
+		weekday = int(parsedWeekday)
+	}
+
+	start, err := meeting.currentOccurrence(time.Now(), weekday)
+	if err != nil {
+		return responsef("Error calculating the meeting date. Check the meeting settings for this channel.")
+	}
+	date := start.Format(occurrenceDateFormat)
+	hashtag := meeting.hashtagForDate(start)
+
+	message := fmt.Sprintf("#### Meeting %s has started\nClick **Join** or reply in this thread to be marked as attending.", hashtag)
+	roles, err := p.getRotationRoles(meeting, start)
+	if err != nil {
+		p.API.LogWarn("Failed to get the meeting roles", "error", err.Error(), "channel_id", args.ChannelId)
+	}
+	if roles != nil {
+		message += "\n" + p.rotationRolesMessage(roles)
+	}
+
+	post := &model.Post{
+		UserId:    p.botID,
+		ChannelId: args.ChannelId,
+		Message:   message,
+	}
+	model.ParseSlackAttachment(post, []*model.SlackAttachment{{
+		Actions: []*model.PostAction{{
+			Name: "Join",
+			Integration: &model.PostActionIntegration{
+				URL: fmt.Sprintf("/plugins/%s/api/v1/attendance/join", Manifest.Id),
+				Context: map[string]interface{}{
+					"channel_id": args.ChannelId,
+					"date":       date,
+				},
+			},
+		}},
+	}})
+
+	createdPost, appErr := p.API.CreatePost(post)
+	if appErr != nil {
+		return responsef("Error creating meeting post: %s", appErr.Message)
+	}
+
+	err = p.updateAttendance(args.ChannelId, date, func(attendance *Attendance) (*Attendance, bool) {
+		if attendance == nil {
+			attendance = &Attendance{
+				ChannelID: args.ChannelId,
+				Date:      date,
+			}
+		}
+		attendance.Hashtag = hashtag
+		attendance.PostID = createdPost.Id
+		attendance.StartedAt = model.GetMillis()
+		attendance.EndedAt = 0
+		attendance.addAttendee(args.UserId)
+		return attendance, true
+	})
+	if err != nil {
+		return responsef("Error saving attendance")
+	}
+
+	if appErr = p.API.KVSet(activeMeetingKeyPrefix+args.ChannelId, []byte(date)); appErr != nil {
+		return responsef("Error starting meeting")
+	}
+
+	p.notifyWebhooks(args.ChannelId, WebhookPayload{Event: webhookEventMeetingStarted, Hashtag: hashtag, Date: date, UserID: args.UserId})
+
+	return &model.CommandResponse{}
+}
+
+func (p *Plugin) executeCommandEnd(args *model.CommandArgs) *model.CommandResponse {
+	meeting, err := p.GetMeeting(args.ChannelId)
+	if err != nil {
+		return responsef("Error getting meeting information for this channel")
+	}
+
+	date, err := p.getActiveMeetingDate(args.ChannelId)
+	if err != nil {
+		return responsef("Error checking for a meeting in progress")
+	}
+	if date == "" {
+		return responsef("There is no meeting in progress. Use `/agenda start` to start one.")
+	}
+
+	attendance, err := p.GetAttendance(args.ChannelId, date)
+	if err != nil || attendance == nil {
+		return responsef("Error getting attendance for this meeting")
+	}
+
+	participants, err := p.threadParticipants(attendance)
+	if err != nil {
+		p.API.LogWarn("Failed to add meeting thread participants", "error", err.Error(), "channel_id", args.ChannelId)
+	}
+
+	err = p.updateAttendance(args.ChannelId, date, func(saved *Attendance) (*Attendance, bool) {
+		if saved == nil {
+			return nil, false
+		}
+		for _, userID := range participants {
+			saved.addAttendee(userID)
+		}
+		saved.EndedAt = model.GetMillis()
+		attendance = saved
+		return saved, true
+	})
+	if err != nil {
+		return responsef("Error saving attendance")
+	}
+
+	if appErr := p.API.KVDelete(activeMeetingKeyPrefix + args.ChannelId); appErr != nil {
+		return responsef("Error ending meeting")
+	}
+
+	items, err := p.getAgendaItems(meeting, args.TeamId, args.UserId, attendance.Hashtag)
+	if err != nil {
+		p.API.LogWarn("Failed to get agenda items for minutes", "error", err.Error(), "channel_id", args.ChannelId)
+	}
+
+	if _, appErr := p.API.CreatePost(&model.Post{
+		UserId:    p.botID,
+		ChannelId: args.ChannelId,
+		RootId:    attendance.PostID,
+		Message:   minutesMessage(attendance, p.usernames(attendance.Attendees), items),
+	}); appErr != nil {
+		return responsef("Error creating minutes post: %s", appErr.Message)
+	}
+
+	p.notifyWebhooks(args.ChannelId, WebhookPayload{
+		Event:     webhookEventMeetingEnded,
+		Hashtag:   attendance.Hashtag,
+		Date:      date,
+		UserID:    args.UserId,
+		Items:     items,
+		Attendees: attendance.Attendees,
+	})
+
+	return &model.CommandResponse{}
+}
+
+// threadParticipants returns the channel members who replied in the meeting thread, to be marked as attending.
+func (p *Plugin) threadParticipants(attendance *Attendance) ([]string, error) {
+	if attendance.PostID == "" {
+		return nil, nil
+	}
+
+	thread, appErr := p.API.GetPostThread(attendance.PostID)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	var participants []string
+	for _, post := range thread.Posts {
+		if post.UserId == p.botID || post.IsSystemMessage() {
+			continue
+		}
+		if _, appErr := p.API.GetChannelMember(attendance.ChannelID, post.UserId); appErr != nil {
+			continue
+		}
+		participants = append(participants, post.UserId)
+	}
+
+	return participants, nil
+}
+
+// usernames returns the usernames of the given users, falling back to the user ID.
+func (p *Plugin) usernames(userIDs []string) map[string]string {
+	names := make(map[string]string, len(userIDs))
+	for _, userID := range userIDs {
+		names[userID] = userID
+		if user, appErr := p.API.GetUser(userID); appErr == nil {
+			names[userID] = user.Username
+		}
+	}
+	return names
+}
+
+func minutesMessage(attendance *Attendance, usernames map[string]string, items []*AgendaItem) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "#### Minutes of meeting %s\n", attendance.Hashtag)
+
+	attendees := make([]string, 0, len(attendance.Attendees))
+	for _, userID := range attendance.Attendees {
+		attendees = append(attendees, "@"+usernames[userID])
+	}
+	sort.Strings(attendees)
+	fmt.Fprintf(&sb, "**Attendees (%d):** %s\n", len(attendees), strings.Join(attendees, ", "))
+
+	sb.WriteString("\n**Agenda:**\n")
+	if len(items) == 0 {
+		sb.WriteString("_No items were queued for this meeting._\n")
+	}
+	for _, item := range items {
+		fmt.Fprintf(&sb, "%d. %s\n", item.Number, item.summary())
+	}
+
+	return sb.String()
+}
+
+func (p *Plugin) executeCommandAttendance(args *model.CommandArgs) *model.CommandResponse {
+	split := strings.Fields(args.Command)
+
+	to := time.Now()
+	from := to.AddDate(0, 0, -30)
+	var err error
+	if len(split) > 2 {
+		if from, err = time.Parse(occurrenceDateFormat, split[2]); err != nil {
+			return responsef("Invalid date %s. Dates must be formatted as YYYY-MM-DD", split[2])
+		}
+	}
+	if len(split) > 3 {
+		if to, err = time.Parse(occurrenceDateFormat, split[3]); err != nil {
+			return responsef("Invalid date %s. Dates must be formatted as YYYY-MM-DD", split[3])
+		}
+	}
+
+	report, err := p.GetAttendanceReport(args.ChannelId, from, to)
+	if err != nil {
+		return responsef(err.Error())
+	}
+
+	return responsef(attendanceReportMessage(report))
+}
+
+// GetAttendanceReport returns the attendance of the channel meeting between two dates, inclusive.
+func (p *Plugin) GetAttendanceReport(channelID string, from, to time.Time) (*AttendanceReport, error) {
+	if to.Before(from) {
+		return nil, errors.New("the end date must be after the start date")
+	}
+	if to.Sub(from) > maxAttendanceReportDays*24*time.Hour {
+		return nil, errors.Errorf("the date range cannot be longer than %d days", maxAttendanceReportDays)
+	}
+
+	var records []*Attendance
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		attendance, err := p.GetAttendance(channelID, day.Format(occurrenceDateFormat))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get attendance")
+		}
+		if attendance != nil {
+			records = append(records, attendance)
+		}
+	}
+
+	report := buildAttendanceReport(records)
+	report.ChannelID = channelID
+	report.From = from.Format(occurrenceDateFormat)
+	report.To = to.Format(occurrenceDateFormat)
+
+	var userIDs []string
+	for _, row := range report.Rows {
+		userIDs = append(userIDs, row.UserID)
+	}
+	usernames := p.usernames(userIDs)
+	for _, row := range report.Rows {
+		row.Username = usernames[row.UserID]
+	}
+
+	return report, nil
+}
+
+// buildAttendanceReport counts the occurrences attended by each user, most frequent first.
+func buildAttendanceReport(records []*Attendance) *AttendanceReport {
+	report := &AttendanceReport{
+		Occurrences: []string{},
+		Rows:        []*AttendanceReportRow{},
+	}
+
+	rows := map[string]*AttendanceReportRow{}
+	for _, attendance := range records {
+		report.Occurrences = append(report.Occurrences, attendance.Date)
+		for _, userID := range attendance.Attendees {
+			row, ok := rows[userID]
+			if !ok {
+				row = &AttendanceReportRow{UserID: userID}
+				rows[userID] = row
+				report.Rows = append(report.Rows, row)
+			}
+			row.Attended++
+		}
+	}
+
+	sort.SliceStable(report.Rows, func(i, j int) bool {
+		return report.Rows[i].Attended > report.Rows[j].Attended
+	})
+
+	return report
+}
+
+func attendanceReportMessage(report *AttendanceReport) string {
+	if len(report.Occurrences) == 0 {
+		return fmt.Sprintf("No attendance was recorded between %s and %s.", report.From, report.To)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "#### Attendance between %s and %s\n", report.From, report.To)
+	fmt.Fprintf(&sb, "Meetings: %s\n\n", strings.Join(report.Occurrences, ", "))
+	sb.WriteString("| Member | Attended |\n| :-- | :-- |\n")
+	for _, row := range report.Rows {
+		fmt.Fprintf(&sb, "| @%s | %d of %d |\n", row.Username, row.Attended, len(report.Occurrences))
+	}
+
+	return sb.String()
+}
+
+func (p *Plugin) httpAttendanceJoin(w http.ResponseWriter, r *http.Request) {
+	mattermostUserID := r.Header.Get("Mattermost-User-Id")
+	if mattermostUserID == "" {
+		http.Error(w, "Not Authorized", http.StatusUnauthorized)
+		return
+	}
+
+	var request *model.PostActionIntegrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request == nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	channelID, _ := request.Context["channel_id"].(string)
+	date, _ := request.Context["date"].(string)
+	if !p.isChannelMember(channelID, mattermostUserID) {
+		http.Error(w, "Not Authorized", http.StatusForbidden)
+		return
+	}
+
+	var found *Attendance
+	var added bool
+	err := p.updateAttendance(channelID, date, func(attendance *Attendance) (*Attendance, bool) {
+		found, added = attendance, false
+		if attendance == nil || attendance.EndedAt != 0 {
+			return nil, false
+		}
+		added = attendance.addAttendee(mattermostUserID)
+		return attendance, added
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if found == nil {
+		http.Error(w, "Meeting not found", http.StatusNotFound)
+		return
+	}
+
+	response := &model.PostActionIntegrationResponse{}
+	switch {
+	case found.EndedAt != 0:
+		response.EphemeralText = "This meeting has already ended."
+	case added:
+		response.EphemeralText = fmt.Sprintf("You are marked as attending meeting %s.", found.Hashtag)
+	default:
+		response.EphemeralText = "You are already marked as attending this meeting."
+	}
+
+	p.writeJSON(w, response)
+}
+
+func (p *Plugin) httpAttendanceReport(w http.ResponseWriter, r *http.Request) {
+	mattermostUserID := r.Header.Get("Mattermost-User-Id")
+	if mattermostUserID == "" {
+		http.Error(w, "Not Authorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	channelID := query.Get("channelId")
+	if !p.isChannelMember(channelID, mattermostUserID) {
+		http.Error(w, "Not Authorized", http.StatusForbidden)
+		return
+	}
+
+	from, err := time.Parse(occurrenceDateFormat, query.Get("from"))
+	if err != nil {
+		http.Error(w, "Invalid from parameter", http.StatusBadRequest)
+		return
+	}
+	to, err := time.Parse(occurrenceDateFormat, query.Get("to"))
+	if err != nil {
+		http.Error(w, "Invalid to parameter", http.StatusBadRequest)
+		return
+	}
+
+	report, err := p.GetAttendanceReport(channelID, from, to)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	p.writeJSON(w, report)
+}
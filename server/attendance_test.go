@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttendance_addAttendee(t *testing.T) {
+	attendance := &Attendance{}
+
+	assert.True(t, attendance.addAttendee("user1"))
+	assert.True(t, attendance.addAttendee("user2"))
+	assert.False(t, attendance.addAttendee("user1"))
+	assert.Equal(t, []string{"user1", "user2"}, attendance.Attendees)
+}
+
+func Test_buildAttendanceReport(t *testing.T) {
+	report := buildAttendanceReport([]*Attendance{
+		{Date: "2022-01-06", Attendees: []string{"user1", "user2"}},
+		{Date: "2022-01-13", Attendees: []string{"user2"}},
+		{Date: "2022-01-20", Attendees: []string{"user3", "user2", "user1"}},
+	})
+
+	assert.Equal(t, []string{"2022-01-06", "2022-01-13", "2022-01-20"}, report.Occurrences)
+	assert.Equal(t, []*AttendanceReportRow{
+		{UserID: "user2", Attended: 3},
+		{UserID: "user1", Attended: 2},
+		{UserID: "user3", Attended: 1},
+	}, report.Rows)
+
+	empty := buildAttendanceReport(nil)
+	assert.Empty(t, empty.Occurrences)
+	assert.Empty(t, empty.Rows)
+}
+
+func Test_minutesMessage(t *testing.T) {
+	attendance := &Attendance{
+		Hashtag:   "#dev-Jan06",
+		Attendees: []string{"user2", "user1"},
+	}
+	usernames := map[string]string{"user1": "alice", "user2": "bob"}
+
+	t.Run("with items", func(t *testing.T) {
+		message := minutesMessage(attendance, usernames, []*AgendaItem{
+			newAgendaItem(1, "Release status\nWe are late", "", ""),
+			newAgendaItem(2, "Hiring", "", ""),
+		})
+		assert.Equal(t, "#### Minutes of meeting #dev-Jan06\n"+
+			"**Attendees (2):** @alice, @bob\n"+
+			"\n**Agenda:**\n"+
+			"1. Release status\n"+
+			"2. Hiring\n", message)
+	})
+
+	t.Run("without items", func(t *testing.T) {
+		message := minutesMessage(attendance, usernames, nil)
+		assert.Contains(t, message, "_No items were queued for this meeting._")
+	})
+}
+
+func TestMeeting_currentOccurrence(t *testing.T) {
+	meeting := &Meeting{Schedule: []time.Weekday{time.Thursday}, Time: "15:00", Timezone: "Europe/Paris"}
+	paris, _ := time.LoadLocation("Europe/Paris")
+	// Thursday, January 6 2022 at 16:00 in Paris, after the meeting should have started
+	now := time.Date(2022, 1, 6, 15, 0, 0, 0, time.UTC)
+
+	start, err := meeting.currentOccurrence(now, -1)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2022, 1, 6, 15, 0, 0, 0, paris), start)
+
+	meeting.SkipDates = []string{"2022-01-06"}
+	start, err = meeting.currentOccurrence(now, -1)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2022, 1, 13, 15, 0, 0, 0, paris), start)
+}
+
+func TestHTTPAttendanceJoin(t *testing.T) {
+	key := attendanceKey("channelID", "2022-01-06")
+	started, _ := json.Marshal(&Attendance{ChannelID: "channelID", Date: "2022-01-06", Hashtag: "#dev-Jan06", Attendees: []string{"user1"}})
+	joined, _ := json.Marshal(&Attendance{ChannelID: "channelID", Date: "2022-01-06", Hashtag: "#dev-Jan06", Attendees: []string{"user1", "user2"}})
+	want, _ := json.Marshal(&Attendance{ChannelID: "channelID", Date: "2022-01-06", Hashtag: "#dev-Jan06", Attendees: []string{"user1", "user2", "user3"}})
+
+	// user2 joins between the read and the write of user3
+	api := &plugintest.API{}
+	api.On("GetChannelMember", "channelID", "user3").Return(&model.ChannelMember{}, nil)
+	api.On("KVGet", key).Return(started, nil).Once()
+	api.On("KVGet", key).Return(joined, nil).Once()
+	api.On("KVSetWithOptions", key, mock.Anything, model.PluginKVSetOptions{Atomic: true, OldValue: started}).Return(false, nil).Once()
+	api.On("KVSetWithOptions", key, want, model.PluginKVSetOptions{Atomic: true, OldValue: joined}).Return(true, nil).Once()
+	p := Plugin{}
+	p.SetAPI(api)
+
+	body, _ := json.Marshal(&model.PostActionIntegrationRequest{Context: map[string]interface{}{"channel_id": "channelID", "date": "2022-01-06"}})
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/attendance/join", strings.NewReader(string(body)))
+	r.Header.Add("Mattermost-User-Id", "user3")
+	w := httptest.NewRecorder()
+	p.httpAttendanceJoin(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	api.AssertExpectations(t)
+}
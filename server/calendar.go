@@ -0,0 +1,416 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	// calendarTokenKeyPrefix prefixes the user ID of a calendar token, and calendarUserKeyPrefix
+	// the calendar token of a user
+	calendarTokenKeyPrefix = "calendar_token_"
+	calendarUserKeyPrefix  = "calendar_user_"
+
+	// calendarAgendaDays is how many days ahead the feed includes the agenda of the occurrences
+	calendarAgendaDays = 14
+	// calendarEventDuration is the duration of the events of meetings with a start time
+	calendarEventDuration = "PT1H"
+	// calendarTimezoneYears is how many years ahead the feed describes the offset changes of
+	// the meeting timezone
+	calendarTimezoneYears = 2
+
+	icsDateFormat     = "20060102"
+	icsDateTimeFormat = "20060102T150405"
+	// icsLineLength is the maximum length in octets of the lines of an iCalendar file
+	icsLineLength = 75
+)
+
+// icsWeekdays are the iCalendar names of the days of the week
+var icsWeekdays = map[time.Weekday]string{
+	time.Sunday:    "SU",
+	time.Monday:    "MO",
+	time.Tuesday:   "TU",
+	time.Wednesday: "WE",
+	time.Thursday:  "TH",
+	time.Friday:    "FR",
+	time.Saturday:  "SA",
+}
+
+// icsEscape escapes the text of an iCalendar property value
+func icsEscape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(text)
+}
+
+// icsFold folds an iCalendar content line into lines of at most 75 octets, without splitting characters
+func icsFold(line string) string {
+	var b strings.Builder
+	length := 0
+	for _, r := range line {
+		size := utf8.RuneLen(r)
+		if length+size > icsLineLength {
+			b.WriteString("\r\n ")
+			length = 1
+		}
+		b.WriteRune(r)
+		length += size
+	}
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+// icsTimezoneID returns the timezone of the dates of the meeting occurrences, or an empty string
+// for the all-day events of meetings without a start time and the meetings using UTC times
+func (m *Meeting) icsTimezoneID() string {
+	if m.Time == "" {
+		return ""
+	}
+	if tzid := m.location().String(); tzid != "Local" && tzid != "UTC" {
+		return tzid
+	}
+	return ""
+}
+
+// icsTime returns the parameters and the value of a date property of the meeting occurrence.
+// Meetings without a start time are all-day events, and meetings whose timezone has no IANA name
+// use UTC times.
+func (m *Meeting) icsTime(start time.Time) (string, string) {
+	if m.Time == "" {
+		return ";VALUE=DATE", start.Format(icsDateFormat)
+	}
+
+	if tzid := m.icsTimezoneID(); tzid != "" {
+		return ";TZID=" + tzid, start.Format(icsDateTimeFormat)
+	}
+	return "", start.UTC().Format(icsDateTimeFormat) + "Z"
+}
+
+// icsUTCOffset formats an offset from UTC in seconds, i.e. +0530
+func icsUTCOffset(offset int) string {
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, offset/3600, offset/60%60)
+}
+
+// timezoneTransitions returns the instants the offset from UTC of the location changes between
+// from and to, to the minute
+func timezoneTransitions(loc *time.Location, from, to time.Time) []time.Time {
+	var transitions []time.Time
+	for day := from; day.Before(to); day = day.Add(24 * time.Hour) {
+		next := day.Add(24 * time.Hour)
+		_, before := day.In(loc).Zone()
+		if _, after := next.In(loc).Zone(); after == before {
+			continue
+		}
+
+		low, high := day, next
+		for high.Sub(low) > time.Minute {
+			middle := low.Add(high.Sub(low) / 2)
+			if _, offset := middle.In(loc).Zone(); offset == before {
+				low = middle
+			} else {
+				high = middle
+			}
+		}
+		transitions = append(transitions, high.Truncate(time.Minute))
+	}
+	return transitions
+}
+
+// icsTimezone returns the VTIMEZONE component defining the TZID of the meeting dates, with the
+// offset from UTC at the start of the year of from and its changes until the end of the year of to
+func icsTimezone(tzid string, loc *time.Location, from, to time.Time) string {
+	var b strings.Builder
+	write := func(name, value string) {
+		b.WriteString(icsFold(name + ":" + value))
+	}
+	writeObservance := func(at time.Time, offsetFrom, offsetTo int) {
+		kind := "STANDARD"
+		if at.In(loc).IsDST() {
+			kind = "DAYLIGHT"
+		}
+		name, _ := at.In(loc).Zone()
+
+		write("BEGIN", kind)
+		// Observances start at a local time of the offset they replace
+		write("DTSTART", at.In(time.FixedZone("", offsetFrom)).Format(icsDateTimeFormat))
+		write("TZOFFSETFROM", icsUTCOffset(offsetFrom))
+		write("TZOFFSETTO", icsUTCOffset(offsetTo))
+		write("TZNAME", icsEscape(name))
+		write("END", kind)
+	}
+
+	start := time.Date(from.In(loc).Year(), time.January, 1, 0, 0, 0, 0, loc)
+	end := time.Date(to.In(loc).Year()+1, time.January, 1, 0, 0, 0, 0, loc)
+
+	write("BEGIN", "VTIMEZONE")
+	write("TZID", tzid)
+	_, offset := start.Zone()
+	writeObservance(start, offset, offset)
+	for _, transition := range timezoneTransitions(loc, start, end) {
+		_, before := transition.Add(-time.Minute).In(loc).Zone()
+		_, after := transition.In(loc).Zone()
+		writeObservance(transition, before, after)
+	}
+	write("END", "VTIMEZONE")
+
+	return b.String()
+}
+
+// icsRecurrence returns the weekly recurrence rule of the meeting schedule
+func (m *Meeting) icsRecurrence() string {
+	days := make([]string, 0, len(m.Schedule))
+	for _, weekday := range m.Schedule {
+		days = append(days, icsWeekdays[weekday])
+	}
+	return "FREQ=WEEKLY;BYDAY=" + strings.Join(days, ",")
+}
+
+// calendarAgenda returns the description of the agenda of a meeting occurrence
+func calendarAgenda(hashtag string, items []*AgendaItem) string {
+	if len(items) == 0 {
+		return fmt.Sprintf("Agenda %s\nNo items are queued yet.", hashtag)
+	}
+
+	lines := []string{"Agenda " + hashtag}
+	for _, item := range items {
+		line := fmt.Sprintf("%d. %s", item.Number, item.Title)
+		if item.Duration != "" {
+			line += fmt.Sprintf(" (%s)", item.Duration)
+		}
+		for _, label := range item.Labels {
+			line += fmt.Sprintf(" [%s]", label)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// calendarOccurrence is an upcoming meeting occurrence of the calendar feed with its agenda
+type calendarOccurrence struct {
+	Start   time.Time
+	Hashtag string
+	Items   []*AgendaItem
+}
+
+// buildCalendar returns the iCalendar feed of the meeting: a recurring event following its schedule,
+// and an event for each upcoming occurrence describing its agenda.
+func buildCalendar(meeting *Meeting, summary string, first time.Time, occurrences []calendarOccurrence, now time.Time) string {
+	var b strings.Builder
+	write := func(name, params, value string) {
+		b.WriteString(icsFold(name + params + ":" + value))
+	}
+
+	uid := meeting.ChannelID + "@" + Manifest.Id
+	stamp := now.UTC().Format(icsDateTimeFormat) + "Z"
+
+	duration := calendarEventDuration
+	if meeting.Time == "" {
+		duration = "P1D"
+	}
+
+	write("BEGIN", "", "VCALENDAR")
+	write("VERSION", "", "2.0")
+	write("PRODID", "", "-//Mattermost//Agenda Plugin//EN")
+	write("CALSCALE", "", "GREGORIAN")
+	write("METHOD", "", "PUBLISH")
+	write("X-WR-CALNAME", "", icsEscape(summary))
+	if tzid := meeting.icsTimezoneID(); tzid != "" {
+		// The TZID of the dates must be defined in the calendar
+		b.WriteString(icsTimezone(tzid, meeting.location(), first, now.AddDate(calendarTimezoneYears, 0, 0)))
+	}
+
+	write("BEGIN", "", "VEVENT")
+	write("UID", "", uid)
+	write("DTSTAMP", "", stamp)
+	params, value := meeting.icsTime(first)
+	write("DTSTART", params, value)
+	write("DURATION", "", duration)
+	write("RRULE", "", meeting.icsRecurrence())
+	for _, skipDate := range meeting.SkipDates {
+		day, err := time.ParseInLocation(occurrenceDateFormat, skipDate, meeting.location())
+		if err != nil {
+			continue
+		}
+		params, value = meeting.icsTime(meeting.startTime(day))
+		write("EXDATE", params, value)
+	}
+	write("SUMMARY", "", icsEscape(summary))
+	write("DESCRIPTION", "", icsEscape("Queue agenda items with /agenda queue in the meeting channel."))
+	write("END", "", "VEVENT")
+
+	for _, occurrence := range occurrences {
+		write("BEGIN", "", "VEVENT")
+		write("UID", "", uid)
+		write("DTSTAMP", "", stamp)
+		params, value = meeting.icsTime(occurrence.Start)
+		write("RECURRENCE-ID", params, value)
+		write("DTSTART", params, value)
+		write("DURATION", "", duration)
+		write("SUMMARY", "", icsEscape(summary))
+		write("DESCRIPTION", "", icsEscape(calendarAgenda(occurrence.Hashtag, occurrence.Items)))
+		write("END", "", "VEVENT")
+	}
+
+	write("END", "", "VCALENDAR")
+	return b.String()
+}
+
+// meetingCalendar returns the iCalendar feed of the meeting of the channel, with the agenda items
+// the user can see.
+func (p *Plugin) meetingCalendar(channelID, userID string, now time.Time) (string, error) {
+	meeting, err := p.GetMeeting(channelID)
+	if err != nil {
+		return "", err
+	}
+
+	channel, appErr := p.API.GetChannel(channelID)
+	if appErr != nil {
+		return "", appErr
+	}
+
+	summary := meeting.Name
+	if summary == "" {
+		summary = channel.DisplayName + " meeting"
+	}
+
+	first, err := meeting.nextOccurrence(now)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to calculate the meeting schedule")
+	}
+
+	var occurrences []calendarOccurrence
+	for _, start := range meeting.occurrencesBetween(first, now.AddDate(0, 0, calendarAgendaDays)) {
+		if meeting.isSkipped(start) {
+			continue
+		}
+
+		hashtag := meeting.hashtagForDate(start)
+		items, err := p.getAgendaItems(meeting, channel.TeamId, userID, hashtag)
+		if err != nil {
+			return "", err
+		}
+		occurrences = append(occurrences, calendarOccurrence{Start: start, Hashtag: hashtag, Items: items})
+	}
+
+	return buildCalendar(meeting, summary, first, occurrences, now), nil
+}
+
+// calendarToken returns the calendar token of the user, creating it if needed or if reset is set
+func (p *Plugin) calendarToken(userID string, reset bool) (string, error) {
+	tokenBytes, appErr := p.API.KVGet(calendarUserKeyPrefix + userID)
+	if appErr != nil {
+		return "", appErr
+	}
+
+	if tokenBytes != nil {
+		if !reset {
+			return string(tokenBytes), nil
+		}
+		if appErr = p.API.KVDelete(calendarTokenKeyPrefix + string(tokenBytes)); appErr != nil {
+			return "", appErr
+		}
+	}
+
+	token := model.NewId()
+	if appErr = p.API.KVSet(calendarTokenKeyPrefix+token, []byte(userID)); appErr != nil {
+		return "", appErr
+	}
+	if appErr = p.API.KVSet(calendarUserKeyPrefix+userID, []byte(token)); appErr != nil {
+		return "", appErr
+	}
+
+	return token, nil
+}
+
+// calendarUser returns the ID of the user of the calendar token, or an empty string if the token is unknown
+func (p *Plugin) calendarUser(token string) string {
+	if token == "" {
+		return ""
+	}
+
+	userBytes, appErr := p.API.KVGet(calendarTokenKeyPrefix + token)
+	if appErr != nil || userBytes == nil {
+		return ""
+	}
+	return string(userBytes)
+}
+
+// siteURL returns the site URL without its trailing slash, or an empty string if it is not configured
+func (p *Plugin) siteURL() string {
+	config := p.API.GetConfig()
+	if config == nil || config.ServiceSettings.SiteURL == nil {
+		return ""
+	}
+	return strings.TrimSuffix(*config.ServiceSettings.SiteURL, "/")
+}
+
+func (p *Plugin) executeCommandCalendar(args *model.CommandArgs) *model.CommandResponse {
+	split := strings.Fields(args.Command)
+	reset := len(split) > 2 && split[2] == "reset"
+	if len(split) > 2 && !reset {
+		return responsef("Unknown calendar action: %s. Usage: `/agenda calendar [reset]`", split[2])
+	}
+
+	siteURL := p.siteURL()
+	if siteURL == "" {
+		return responsef("The calendar feed requires the Site URL of the server to be configured")
+	}
+
+	token, err := p.calendarToken(args.UserId, reset)
+	if err != nil {
+		return responsef("Error getting your calendar token")
+	}
+
+	feedURL := fmt.Sprintf("%s/plugins/%s/api/v1/channels/%s/meeting.ics?token=%s", siteURL, Manifest.Id, args.ChannelId, token)
+	response := fmt.Sprintf("Subscribe to the meeting of this channel from your calendar client with this link:\n%s\n"+
+		"The link gives access to the agenda of the meetings of your channels. Keep it private, and use `/agenda calendar reset` to replace your links if one leaks.", feedURL)
+	if reset {
+		response = "Your previous calendar links no longer work. " + response
+	}
+
+	return responsef(response)
+}
+
+// httpMeetingCalendar serves the iCalendar feed of the meeting of the channel. Calendar clients
+// authenticate with the calendar token of the user in the `token` query parameter.
+func (p *Plugin) httpMeetingCalendar(w http.ResponseWriter, r *http.Request, channelID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Request: "+r.Method+" is not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := r.Header.Get("Mattermost-User-Id")
+	if userID == "" {
+		userID = p.calendarUser(r.URL.Query().Get("token"))
+	}
+	if userID == "" {
+		http.Error(w, "Not Authorized", http.StatusUnauthorized)
+		return
+	}
+
+	if !p.isChannelMember(channelID, userID) {
+		http.Error(w, "Not Authorized", http.StatusForbidden)
+		return
+	}
+
+	calendar, err := p.meetingCalendar(channelID, userID, time.Now())
+	if err != nil {
+		p.API.LogError("Failed to build the meeting calendar", "error", err.Error(), "channel_id", channelID)
+		http.Error(w, "Failed to build the meeting calendar", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	if _, err := w.Write([]byte(calendar)); err != nil {
+		p.API.LogWarn("Failed to write the meeting calendar", "error", err.Error())
+	}
+}
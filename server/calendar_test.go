@@ -0,0 +1,148 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestICSEscape(t *testing.T) {
+	assert.Equal(t, `Agenda #dev\, 1. Release\; QA\nC:\\tmp`, icsEscape("Agenda #dev, 1. Release; QA\nC:\\tmp"))
+}
+
+func TestICSFold(t *testing.T) {
+	assert.Equal(t, "SUMMARY:Standup\r\n", icsFold("SUMMARY:Standup"))
+
+	folded := icsFold("DESCRIPTION:" + strings.Repeat("é", 50))
+	lines := strings.Split(strings.TrimSuffix(folded, "\r\n"), "\r\n ")
+	assert.Len(t, lines, 2)
+	for _, line := range lines {
+		assert.LessOrEqual(t, len(line), icsLineLength)
+	}
+	assert.Equal(t, "DESCRIPTION:"+strings.Repeat("é", 50), strings.Join(lines, ""))
+}
+
+func TestBuildCalendar(t *testing.T) {
+	meeting := &Meeting{
+		ChannelID:     "channelID",
+		Schedule:      []time.Weekday{time.Monday, time.Thursday},
+		HashtagFormat: "dev-{{Jan02}}",
+		Time:          "15:30",
+		Timezone:      "Europe/Paris",
+		SkipDates:     []string{"2022-01-10"},
+	}
+	loc, _ := time.LoadLocation("Europe/Paris")
+	first := time.Date(2022, 1, 6, 15, 30, 0, 0, loc)
+	now := time.Date(2022, 1, 5, 9, 0, 0, 0, time.UTC)
+
+	calendar := buildCalendar(meeting, "Dev sync", first, []calendarOccurrence{
+		{Start: first, Hashtag: "#dev-Jan06", Items: []*AgendaItem{
+			{Number: 1, Title: "Release status", Duration: "15m0s", Labels: []string{"release"}},
+		}},
+		{Start: time.Date(2022, 1, 13, 15, 30, 0, 0, loc), Hashtag: "#dev-Jan13"},
+	}, now)
+
+	assert.True(t, strings.HasPrefix(calendar, "BEGIN:VCALENDAR\r\n"))
+	assert.True(t, strings.HasSuffix(calendar, "END:VCALENDAR\r\n"))
+	assert.Contains(t, calendar, "BEGIN:VTIMEZONE\r\nTZID:Europe/Paris\r\n")
+	assert.Contains(t, calendar, "DTSTART;TZID=Europe/Paris:20220106T153000\r\n")
+	assert.Contains(t, calendar, "RRULE:FREQ=WEEKLY;BYDAY=MO,TH\r\n")
+	assert.Contains(t, calendar, "EXDATE;TZID=Europe/Paris:20220110T153000\r\n")
+	assert.Contains(t, calendar, "RECURRENCE-ID;TZID=Europe/Paris:20220106T153000\r\n")
+	assert.Contains(t, calendar, `DESCRIPTION:Agenda #dev-Jan06\n1. Release status (15m0s) [release]`+"\r\n")
+	assert.Contains(t, calendar, `DESCRIPTION:Agenda #dev-Jan13\nNo items are queued yet.`+"\r\n")
+	assert.Equal(t, 3, strings.Count(calendar, "BEGIN:VEVENT"))
+}
+
+func TestICSTimezone(t *testing.T) {
+	loc, _ := time.LoadLocation("Europe/Paris")
+	day := time.Date(2022, 1, 6, 15, 30, 0, 0, loc)
+
+	assert.Equal(t, "BEGIN:VTIMEZONE\r\n"+
+		"TZID:Europe/Paris\r\n"+
+		"BEGIN:STANDARD\r\nDTSTART:20220101T000000\r\nTZOFFSETFROM:+0100\r\nTZOFFSETTO:+0100\r\nTZNAME:CET\r\nEND:STANDARD\r\n"+
+		"BEGIN:DAYLIGHT\r\nDTSTART:20220327T020000\r\nTZOFFSETFROM:+0100\r\nTZOFFSETTO:+0200\r\nTZNAME:CEST\r\nEND:DAYLIGHT\r\n"+
+		"BEGIN:STANDARD\r\nDTSTART:20221030T030000\r\nTZOFFSETFROM:+0200\r\nTZOFFSETTO:+0100\r\nTZNAME:CET\r\nEND:STANDARD\r\n"+
+		"END:VTIMEZONE\r\n", icsTimezone("Europe/Paris", loc, day, day))
+
+	assert.Equal(t, "-0330", icsUTCOffset(-(3*60+30)*60))
+
+	// Meetings using UTC times don't need a timezone
+	assert.Empty(t, (&Meeting{Time: "10:00", Timezone: "UTC"}).icsTimezoneID())
+	assert.Empty(t, (&Meeting{Timezone: "Europe/Paris"}).icsTimezoneID())
+}
+
+func TestMeeting_icsTime(t *testing.T) {
+	day := time.Date(2022, 1, 6, 0, 0, 0, 0, time.UTC)
+
+	params, value := (&Meeting{}).icsTime(day)
+	assert.Equal(t, ";VALUE=DATE", params)
+	assert.Equal(t, "20220106", value)
+
+	params, value = (&Meeting{Time: "10:00", Timezone: "UTC"}).icsTime(day.Add(10 * time.Hour))
+	assert.Equal(t, "", params)
+	assert.Equal(t, "20220106T100000Z", value)
+}
+
+func TestServeHTTPMeetingCalendar(t *testing.T) {
+	setupAPI := func() *plugintest.API {
+		api := &plugintest.API{}
+		api.On("KVGet", calendarTokenKeyPrefix+"token1").Return([]byte("member"), nil)
+		api.On("KVGet", calendarTokenKeyPrefix+"unknown").Return(nil, nil)
+		api.On("GetChannelMember", "channelID", "member").Return(&model.ChannelMember{}, nil)
+		api.On("KVGet", "channelID").Return([]byte(`{"channelId":"channelID","schedule":[4],"hashtagFormat":"dev-{{Jan02}}","time":"10:00","timezone":"UTC"}`), nil)
+		api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Name: "dev", DisplayName: "Dev", TeamId: "teamID"}, nil)
+		api.On("GetConfig").Return(&model.Config{})
+		api.On("SearchPostsInTeamForUser", "teamID", "member", mock.Anything).Return(model.MakePostSearchResults(model.NewPostList(), nil), nil)
+		return api
+	}
+
+	t.Run("valid token", func(t *testing.T) {
+		p := Plugin{}
+		p.SetAPI(setupAPI())
+
+		w := httptest.NewRecorder()
+		p.ServeHTTP(nil, w, httptest.NewRequest(http.MethodGet, "/api/v1/channels/channelID/meeting.ics?token=token1", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/calendar; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Body.String(), "SUMMARY:Dev meeting\r\n")
+		assert.Contains(t, w.Body.String(), "RRULE:FREQ=WEEKLY;BYDAY=TH\r\n")
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		p := Plugin{}
+		p.SetAPI(setupAPI())
+
+		w := httptest.NewRecorder()
+		p.ServeHTTP(nil, w, httptest.NewRequest(http.MethodGet, "/api/v1/channels/channelID/meeting.ics?token=unknown", nil))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestPlugin_calendarToken(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("KVGet", calendarUserKeyPrefix+"user1").Return([]byte("old"), nil)
+	api.On("KVDelete", calendarTokenKeyPrefix+"old").Return(nil)
+	api.On("KVSet", mock.Anything, mock.Anything).Return(nil)
+	p := Plugin{}
+	p.SetAPI(api)
+
+	token, err := p.calendarToken("user1", false)
+	assert.Nil(t, err)
+	assert.Equal(t, "old", token)
+	api.AssertNotCalled(t, "KVSet", mock.Anything, mock.Anything)
+
+	token, err = p.calendarToken("user1", true)
+	assert.Nil(t, err)
+	assert.NotEqual(t, "old", token)
+	api.AssertCalled(t, "KVDelete", calendarTokenKeyPrefix+"old")
+	api.AssertCalled(t, "KVSet", calendarTokenKeyPrefix+token, []byte("user1"))
+	api.AssertCalled(t, "KVSet", calendarUserKeyPrefix+"user1", []byte(token))
+}
@@ -19,7 +19,7 @@ const (
 
 // ParsedMeetingMessage is meeting message after being parsed
 type ParsedMeetingMessage struct {
-	date        string
+	hashtag     string
 	number      string
 	textMessage string
 }
@@ -27,9 +27,26 @@ type ParsedMeetingMessage struct {
 const helpCommandText = "###### Mattermost Agenda Plugin - Slash Command Help\n" +
 	"The Agenda plugin lets you queue up meeting topics for channel discussion at a later time.  When your meeting happens, you can click on the Hashtag to see all agenda items in the RHS. \n" +
 	"To configure the agenda for this channel, click on the Channel Name in Mattermost to access the channel options menu and select `Agenda Settings`" +
-	"\n* `/agenda queue [weekday (optional)] message` - Queue `message` as a topic on the next meeting. If `weekday` is provided, it will queue for the meeting for. \n" +
+	"\n* `/agenda queue [weekday (optional)] message` - Queue `message` as a topic on the next meeting. If `weekday` is provided, it will queue for the meeting for. Without a message, opens a dialog to enter the title, details, owner, duration and labels of the item. \n" +
+	"* `/agenda queue-post <permalink>` - Queue a message of the channel as a topic on the next meeting, quoting and linking to it. The author of the message owns the item. \n" +
+	"* `/agenda import [weekday|next-week(optional)] <list>` - Queue every item of a Markdown list or of a CSV with title, owner and duration columns, skipping the items already queued. The list can also be the ID of a file or a link to the message it is attached to. \n" +
 	"* `/agenda list [weekday(optional)]` - Show a list of items queued for the next meeting.  If `next-week` is provided, it will list the agenda for the next calendar week. \n" +
-	"* `/agenda setting <field> <value>` - Update the setting with the given value. Field can be one of `schedule` or `hashtag` \n" +
+	"* `/agenda start [weekday(optional)]` - Start the meeting. Channel members can click **Join** or reply in the meeting thread to be marked as attending. \n" +
+	"* `/agenda end` - End the meeting in progress and post the minutes with the attendees and agenda items. \n" +
+	"* `/agenda attendance [from(optional)] [to(optional)]` - Show the meeting attendance between two dates formatted as YYYY-MM-DD. Defaults to the last 30 days. \n" +
+	"* `/agenda rotation show|set|skip` - Show the facilitator and note-taker of the next meeting, set the rotation roster with `set @user1 @user2 ...` or hand the next meeting's facilitator role to the next person with `skip`. \n" +
+	"* `/agenda setting <field> <value>` - Update the setting with the given value. Field can be one of `schedule`, `hashtag`, `name`, `time`, `timezone`, `reminder`, `cutoff`, `empty-check` or `post-as` \n" +
+	"* `/agenda setting hashtag <format> --migrate` - Update the hashtag format and move the items queued for the upcoming meetings to the new format. \n" +
+	"* `/agenda setting hashtag --preview <format>` - Preview the hashtags of the next meetings with the given format and flag problems. \n" +
+	"* `/agenda doctor [weekday(optional)]` - List the posts of the next meeting that are not valid agenda items and offer to repair them. \n" +
+	"* `/agenda calendar [reset]` - Get the link to subscribe to the meeting of this channel from a calendar client, or replace your calendar links with `reset`. \n" +
+	"* `/agenda import-ics <fileId or message link>` - Import the schedule, time, timezone and cancelled meetings from a calendar file attached in this channel. \n" +
+	"* `/agenda template add <item>|remove <number>|list` - Manage the standing items, such as metrics review or open floor, queued by the Agenda bot as the first items of every meeting. \n" +
+	"* `/agenda team-template save <name>|set <name> <setting> <value>|remove <name>|list` - Manage the meeting templates of this team, bundling the schedule, hashtag format, standing items and reminder settings of a meeting. `save` uses the settings of this meeting, `set` changes one setting of a template. Team admins only. \n" +
+	"* `/agenda setup --template <name>` - Set up the meeting of this channel with the settings of a meeting template of this team. \n" +
+	"* `/agenda webhook add <url>|list|remove <id>|log` - Manage the webhooks notified when items are queued, edited, removed or reordered and when meetings start and end, and show the recent deliveries. Channel admins only. \n" +
+	"* `/agenda token create [name]|list|revoke <id>` - Manage the tokens of the incoming hook external systems use to queue items on the meeting of this channel. Channel admins only. \n" +
+	"* `/agenda export [from(optional)] [to(optional)] [--format md|csv|json]` - Export the agenda items of the meetings between two dates formatted as YYYY-MM-DD, with their author, status and link. Defaults to the last 30 days in Markdown. \n" +
 	"How can we make this better?  Submit an issue to the [Agenda Plugin repo here](https://github.com/mattermost/mattermost-plugin-agenda/issues) \n"
 
 func (p *Plugin) registerCommands() error {
@@ -45,7 +62,7 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 	split := strings.Fields(args.Command)
 
 	if len(split) < 2 {
-		return responsef("Missing command. You can try queue, list, setting"), nil
+		return responsef("Missing command. You can try queue, queue-post, import, list, start, end, attendance, rotation, setting, doctor, calendar, import-ics, template, team-template, setup, webhook, token, export"), nil
 	}
 
 	action := split[1]
@@ -57,9 +74,54 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 	case "queue":
 		return p.executeCommandQueue(args), nil
 
+	case "queue-post":
+		return p.executeCommandQueuePost(args), nil
+
+	case "import":
+		return p.executeCommandImport(args), nil
+
+	case "start":
+		return p.executeCommandStart(args), nil
+
+	case "end":
+		return p.executeCommandEnd(args), nil
+
+	case "attendance":
+		return p.executeCommandAttendance(args), nil
+
+	case "rotation":
+		return p.executeCommandRotation(args), nil
+
 	case "setting":
 		return p.executeCommandSetting(args), nil
 
+	case "doctor":
+		return p.executeCommandDoctor(args), nil
+
+	case "calendar":
+		return p.executeCommandCalendar(args), nil
+
+	case "import-ics":
+		return p.executeCommandImportICS(args), nil
+
+	case "template":
+		return p.executeCommandTemplate(args), nil
+
+	case "team-template":
+		return p.executeCommandTeamTemplate(args), nil
+
+	case "setup":
+		return p.executeCommandSetup(args), nil
+
+	case "webhook":
+		return p.executeCommandWebhook(args), nil
+
+	case "token":
+		return p.executeCommandToken(args), nil
+
+	case "export":
+		return p.executeCommandExport(args), nil
+
 	case "help":
 		return p.executeCommandHelp(args), nil
 	}
@@ -82,14 +144,7 @@ func (p *Plugin) executeCommandList(args *model.CommandArgs) *model.CommandRespo
 		return responsef("Error calculating hashtags")
 	}
 
-	// Send a websocket event to the web app that will open the RHS
-	p.API.PublishWebSocketEvent(
-		wsEventList,
-		map[string]interface{}{
-			"hashtag": hashtag,
-		},
-		&model.WebsocketBroadcast{UserId: args.UserId},
-	)
+	p.openAgendaSearch(args.UserId, hashtag)
 
 	return &model.CommandResponse{}
 }
@@ -104,12 +159,36 @@ func (p *Plugin) executeCommandSetting(args *model.CommandArgs) *model.CommandRe
 
 	field := split[2]
 	value := split[3]
+	migrate := false
+	if field == "hashtag" || field == "name" {
+		// Hashtag templates and meeting names can contain spaces
+		values := make([]string, 0, len(split)-3)
+		for _, token := range split[3:] {
+			if field == "hashtag" && token == "--migrate" {
+				migrate = true
+				continue
+			}
+			values = append(values, token)
+		}
+		value = strings.Join(values, " ")
+	}
+
+	if field == "hashtag" && split[3] == "--preview" {
+		return p.executeCommandHashtagPreview(args, strings.Join(split[4:], " "))
+	}
+
+	if !p.canManageMeeting(args.ChannelId, args.UserId) {
+		return responsef("You do not have permission to change the meeting settings of this channel")
+	}
 
 	meeting, err := p.GetMeeting(args.ChannelId)
 	if err != nil {
 		return responsef("Error getting meeting information for this channel")
 	}
 
+	// previous are the settings before the hashtag format changes, to migrate the queued items
+	var previous *Meeting
+
 	switch field {
 	case "schedule":
 		// Set schedule
@@ -121,99 +200,165 @@ func (p *Plugin) executeCommandSetting(args *model.CommandArgs) *model.CommandRe
 
 	case "hashtag":
 		// Set hashtag
+		if migrate {
+			copied := *meeting
+			previous = &copied
+		}
 		meeting.HashtagFormat = value
+		if err := p.loadChannelName(meeting); err != nil {
+			return responsef("Error getting the channel of the meeting")
+		}
+
+	case "name":
+		meeting.Name = value
+
+	case "time":
+		if _, err := time.Parse(meetingTimeFormat, value); err != nil {
+			return responsef("Invalid time %s. Must be formatted as HH:MM, i.e. 15:30", value)
+		}
+		meeting.Time = value
+
+	case "timezone":
+		if _, err := time.LoadLocation(value); err != nil {
+			return responsef("Invalid timezone %s. Must be an IANA timezone, i.e. America/New_York", value)
+		}
+		meeting.Timezone = value
+
+	case "reminder":
+		reminder, err := parseReminder(value)
+		if err != nil {
+			return responsef(err.Error())
+		}
+		meeting.Reminder = reminder
+
+	case "cutoff":
+		cutoff, err := parseOptionalDuration(value)
+		if err != nil {
+			return responsef(err.Error())
+		}
+		meeting.QueueCutoff = cutoff
+
+	case "empty-check":
+		emptyCheck, err := parseOptionalDuration(value)
+		if err != nil {
+			return responsef(err.Error())
+		}
+		meeting.EmptyCheck = emptyCheck
+
+	case "post-as":
+		if value != postAsUser && value != postAsBot && value != "default" {
+			return responsef("Invalid value %s. Must be user, bot or default", value)
+		}
+		meeting.PostAs = strings.TrimPrefix(value, "default")
 	default:
 		return responsef("Unknown setting %s", field)
 	}
 
+	if err := meeting.Validate(p.minimumHashtagLength()); err != nil {
+		return responsef("Invalid setting: %s", err.Error())
+	}
+
+	if previous != nil {
+		locked, err := p.lockHashtagMigration(args.ChannelId)
+		if err != nil {
+			return responsef("Error starting the hashtag migration")
+		}
+		if !locked {
+			return responsef("A hashtag migration is already running for this channel. Try again when it finishes.")
+		}
+	}
+
 	if err := p.SaveMeeting(meeting); err != nil {
+		if previous != nil {
+			_ = p.API.KVDelete(hashtagMigrationKeyPrefix + args.ChannelId)
+		}
 		return responsef("Error saving setting")
 	}
 
+	if previous != nil {
+		go p.runHashtagMigration(previous, meeting, args.UserId)
+		return responsef("Updated setting %v to %v. The items queued for the upcoming meetings are being migrated to the new format in the background.", field, value)
+	}
+
 	return responsef("Updated setting %v to %v", field, value)
 }
 
 func (p *Plugin) executeCommandQueue(args *model.CommandArgs) *model.CommandResponse {
 	split := strings.Fields(args.Command)
 
+	// skip is the number of words before the message
+	skip := 2
+	force := false
+	if len(split) > 2 && split[2] == "--force" {
+		force = true
+		skip++
+		split = append(split[:2], split[3:]...)
+	}
+
 	if len(split) <= 2 {
-		return responsef("Missing parameters for queue command")
+		return p.openQueueDialog(args)
+	}
+
+	if force && !p.isChannelAdmin(args.ChannelId, args.UserId) {
+		return responsef("Only channel admins can use `--force` to queue items on a frozen agenda")
 	}
 
 	meeting, err := p.GetMeeting(args.ChannelId)
 	if err != nil {
 		p.API.LogError("failed to get meeting for channel", "err", err.Error(), "channel_id", args.ChannelId)
+		return responsef("Error getting meeting information for this channel")
 	}
 
-	nextWeek := false
-	weekday := -1
-	message := strings.Join(split[2:], " ")
-
-	if split[2] == "next-week" {
-		nextWeek = true
-	} else {
-		parsedWeekday, _ := parseSchedule(split[2])
-		weekday = int(parsedWeekday)
-	}
-
+	nextWeek, weekday := parseQueueDay(split[2])
 	if nextWeek || weekday > -1 {
-		message = strings.Join(split[3:], " ")
+		skip++
 	}
-
-	hashtag, err := p.GenerateHashtag(args.ChannelId, nextWeek, weekday)
+	// Keep the newlines, code blocks and lists of the message
+	message := commandArguments(args.Command, skip)
+
+	result, err := p.queueForOccurrence(meeting, &queueRequest{
+		TeamID:  args.TeamId,
+		UserID:  args.UserId,
+		OwnerID: args.UserId,
+		RootID:  args.RootId,
+		Message: message,
+	}, nextWeek, weekday, force)
 	if err != nil {
-		return responsef("Error calculating hashtags. Check the meeting settings for this channel.")
-	}
-
-	numQueueItems, itemErr := p.calculateQueueItemNumberAndUpdateOldItems(meeting, args, hashtag)
-	if itemErr != nil {
-		return responsef(itemErr.Error())
+		return responsef(err.Error())
 	}
 
-	_, appErr := p.API.CreatePost(&model.Post{
-		UserId:    args.UserId,
-		ChannelId: args.ChannelId,
-		RootId:    args.RootId,
-		Message:   fmt.Sprintf("#### %v %v) %v", hashtag, numQueueItems, message),
-	})
-	if appErr != nil {
-		return responsef("Error creating post: %s", appErr.Message)
+	response := responsef(result.message(meeting))
+	if len(result.Broken) > 0 {
+		response.Text += fmt.Sprintf("\n%d posts of this meeting could not be read as agenda items and were skipped. Use `/agenda doctor` to repair them.", len(result.Broken))
 	}
 
-	return &model.CommandResponse{}
+	return response
 }
 
-func parseMeetingPost(meeting *Meeting, post *model.Post) (string, ParsedMeetingMessage, error) {
-	var (
-		prefix            string
-		hashtagDateFormat string
-	)
-	if matchGroups := meetingDateFormatRegex.FindStringSubmatch(meeting.HashtagFormat); len(matchGroups) == 4 {
-		prefix = matchGroups[1]
-		hashtagDateFormat = strings.TrimSpace(matchGroups[2])
-	} else {
-		return "", ParsedMeetingMessage{}, errors.New("error Parsing meeting post")
+func parseMeetingPost(meeting *Meeting, post *model.Post) (ParsedMeetingMessage, error) {
+	hashtagRegex, err := meeting.hashtagRegex()
+	if err != nil {
+		return ParsedMeetingMessage{}, err
 	}
 
-	var (
-		messageRegexFormat, err = regexp.Compile(fmt.Sprintf(`(?m)^#### #%s(?P<date>.*) ([0-9]+)\) (?P<message>.*)?$`, prefix))
-	)
-
+	// The header is the first line of the post, the message can span several lines
+	messageRegexFormat, err := regexp.Compile(fmt.Sprintf(`(?s)^\s*#### (?P<hashtag>%s) ([0-9]+)\)(?: (?P<message>.*))?$`, hashtagRegex))
 	if err != nil {
-		return "", ParsedMeetingMessage{}, err
+		return ParsedMeetingMessage{}, err
 	}
+
 	matchGroups := messageRegexFormat.FindStringSubmatch(post.Message)
 	if len(matchGroups) == 4 {
 		parsedMeetingMessage := ParsedMeetingMessage{
-			date:        matchGroups[1],
+			hashtag:     matchGroups[1],
 			number:      matchGroups[2],
 			textMessage: matchGroups[3],
 		}
 
-		return hashtagDateFormat, parsedMeetingMessage, nil
+		return parsedMeetingMessage, nil
 	}
 
-	return hashtagDateFormat, ParsedMeetingMessage{}, errors.New("failed to parse meeting post's header")
+	return ParsedMeetingMessage{}, errors.New("failed to parse meeting post's header")
 }
 
 func (p *Plugin) executeCommandHelp(args *model.CommandArgs) *model.CommandResponse {
@@ -229,17 +374,46 @@ func responsef(format string, args ...interface{}) *model.CommandResponse {
 }
 
 func createAgendaCommand() *model.Command {
-	agenda := model.NewAutocompleteData(commandTriggerAgenda, "[command]", "Available commands: list, queue, setting, help")
+	agenda := model.NewAutocompleteData(commandTriggerAgenda, "[command]", "Available commands: list, queue, queue-post, import, start, end, attendance, rotation, setting, doctor, calendar, import-ics, template, team-template, setup, webhook, token, export, help")
 
 	list := model.NewAutocompleteData("list", "", "Show a list of items queued for the next meeting")
 	list.AddDynamicListArgument("Day of the week for when to queue the meeting", "/api/v1/list-meeting-days-autocomplete", false)
 	agenda.AddCommand(list)
 
-	queue := model.NewAutocompleteData("queue", "", "Queue `message` as a topic on the next meeting.")
+	queue := model.NewAutocompleteData("queue", "", "Queue `message` as a topic on the next meeting, or open the queue dialog without a message.")
 	queue.AddDynamicListArgument("Day of the week for when to queue the meeting", "/api/v1/meeting-days-autocomplete", false)
 	queue.AddTextArgument("Message for the next meeting date.", "[message]", "")
 	agenda.AddCommand(queue)
 
+	queuePost := model.NewAutocompleteData("queue-post", "", "Queue an existing message as a topic on the next meeting.")
+	queuePost.AddTextArgument("Link to the message", "[permalink]", "")
+	agenda.AddCommand(queuePost)
+
+	importItems := model.NewAutocompleteData("import", "", "Queue the items of a Markdown list or CSV on the next meeting.")
+	importItems.AddDynamicListArgument("Day of the week for when to queue the items", "/api/v1/meeting-days-autocomplete", false)
+	importItems.AddTextArgument("Markdown list or CSV, or the ID of a file or link to the message it is attached to", "[list]", "")
+	agenda.AddCommand(importItems)
+
+	start := model.NewAutocompleteData("start", "", "Start the meeting and track attendance")
+	start.AddDynamicListArgument("Day of the week of the meeting to start", "/api/v1/list-meeting-days-autocomplete", false)
+	agenda.AddCommand(start)
+
+	end := model.NewAutocompleteData("end", "", "End the meeting in progress and post the minutes")
+	agenda.AddCommand(end)
+
+	attendance := model.NewAutocompleteData("attendance", "", "Show the meeting attendance between two dates")
+	attendance.AddTextArgument("Start date of the report", "[from YYYY-MM-DD]", "")
+	attendance.AddTextArgument("End date of the report", "[to YYYY-MM-DD]", "")
+	agenda.AddCommand(attendance)
+
+	rotation := model.NewAutocompleteData("rotation", "", "Manage the facilitator and note-taker rotation")
+	rotation.AddCommand(model.NewAutocompleteData("show", "", "Show the roles for the next meeting"))
+	rotationSet := model.NewAutocompleteData("set", "", "Set the ordered rotation roster")
+	rotationSet.AddTextArgument("Users of the rotation, in order", "@user1 @user2 ...", "")
+	rotation.AddCommand(rotationSet)
+	rotation.AddCommand(model.NewAutocompleteData("skip", "", "Hand the facilitator role to the next person in the rotation"))
+	agenda.AddCommand(rotation)
+
 	setting := model.NewAutocompleteData("setting", "", "Update the setting.")
 	schedule := model.NewAutocompleteData("schedule", "", "Update schedule.")
 	schedule.AddStaticListArgument("weekday", true, []model.AutocompleteListItem{
@@ -253,16 +427,116 @@ func createAgendaCommand() *model.Command {
 	})
 	setting.AddCommand(schedule)
 	hashtag := model.NewAutocompleteData("hashtag", "", "Update hastag.")
-	hashtag.AddTextArgument("input hashtag", "Default: Jan02", "")
+	hashtag.AddTextArgument("input hashtag, followed by --migrate to move the queued items to the new format, or --preview <format> to preview the hashtags of the next meetings", "Default: Jan02", "")
 	setting.AddCommand(hashtag)
+	name := model.NewAutocompleteData("name", "", "Update the meeting name.")
+	name.AddTextArgument("Name of the meeting, used by {{ .MeetingName }} in the hashtag", "[name]", "")
+	setting.AddCommand(name)
+	meetingTime := model.NewAutocompleteData("time", "", "Update the meeting start time.")
+	meetingTime.AddTextArgument("Start time of the meeting", "HH:MM", "")
+	setting.AddCommand(meetingTime)
+	timezone := model.NewAutocompleteData("timezone", "", "Update the meeting timezone.")
+	timezone.AddTextArgument("IANA timezone of the meeting", "i.e. America/New_York", "")
+	setting.AddCommand(timezone)
+	reminder := model.NewAutocompleteData("reminder", "", "Update when the agenda reminder is posted.")
+	reminder.AddStaticListArgument("When to post the reminder", true, []model.AutocompleteListItem{
+		{Item: "1h", HelpText: "1 hour before the meeting"},
+		{Item: "30m", HelpText: "30 minutes before the meeting"},
+		{Item: "morning", HelpText: "The morning of the meeting"},
+		{Item: "off", HelpText: "Disable reminders"},
+	})
+	setting.AddCommand(reminder)
+	cutoff := model.NewAutocompleteData("cutoff", "", "Update when the agenda is frozen before the meeting.")
+	cutoff.AddTextArgument("Duration before the meeting, or off", "i.e. 2h", "")
+	setting.AddCommand(cutoff)
+	emptyCheck := model.NewAutocompleteData("empty-check", "", "Update when to propose cancelling a meeting with an empty agenda.")
+	emptyCheck.AddTextArgument("Duration before the meeting, or off", "i.e. 3h", "")
+	setting.AddCommand(emptyCheck)
+	postAs := model.NewAutocompleteData("post-as", "", "Update who posts the agenda items.")
+	postAs.AddStaticListArgument("Who posts the agenda items", true, []model.AutocompleteListItem{
+		{Item: "user", HelpText: "The user queueing the item"},
+		{Item: "bot", HelpText: "The Agenda bot, recording who queued the item"},
+		{Item: "default", HelpText: "The setting of the plugin"},
+	})
+	setting.AddCommand(postAs)
 	agenda.AddCommand(setting)
 
+	doctor := model.NewAutocompleteData("doctor", "", "List and repair the broken agenda items of the next meeting")
+	doctor.AddDynamicListArgument("Day of the week of the meeting to check", "/api/v1/list-meeting-days-autocomplete", false)
+	agenda.AddCommand(doctor)
+
+	calendar := model.NewAutocompleteData("calendar", "", "Get the link to subscribe to the meeting from a calendar client")
+	calendar.AddCommand(model.NewAutocompleteData("reset", "", "Replace your calendar links, disabling the previous ones"))
+	agenda.AddCommand(calendar)
+
+	importICS := model.NewAutocompleteData("import-ics", "", "Import the meeting schedule from a calendar file attached in this channel")
+	importICS.AddTextArgument("ID of the file, or link to the message it is attached to", "[fileId or permalink]", "")
+	agenda.AddCommand(importICS)
+
+	template := model.NewAutocompleteData("template", "", "Manage the standing items queued first on every meeting")
+	templateAdd := model.NewAutocompleteData("add", "", "Add a standing item")
+	templateAdd.AddTextArgument("Title of the standing item", "[item]", "")
+	template.AddCommand(templateAdd)
+	templateRemove := model.NewAutocompleteData("remove", "", "Remove a standing item")
+	templateRemove.AddTextArgument("Number of the standing item", "[number]", "")
+	template.AddCommand(templateRemove)
+	template.AddCommand(model.NewAutocompleteData("list", "", "List the standing items"))
+	agenda.AddCommand(template)
+
+	teamTemplate := model.NewAutocompleteData("team-template", "", "Manage the meeting templates of this team. Team admins only.")
+	teamTemplateSave := model.NewAutocompleteData("save", "", "Save the settings of this meeting as a template")
+	teamTemplateSave.AddTextArgument("Name of the template", "[name]", "")
+	teamTemplate.AddCommand(teamTemplateSave)
+	teamTemplateSet := model.NewAutocompleteData("set", "", "Create a template or change one of its settings")
+	teamTemplateSet.AddTextArgument("Name of the template, setting and value", "[name] [schedule|hashtag|time|timezone|reminder|cutoff|empty-check] [value]", "")
+	teamTemplate.AddCommand(teamTemplateSet)
+	teamTemplateRemove := model.NewAutocompleteData("remove", "", "Remove a template")
+	teamTemplateRemove.AddTextArgument("Name of the template", "[name]", "")
+	teamTemplate.AddCommand(teamTemplateRemove)
+	teamTemplate.AddCommand(model.NewAutocompleteData("list", "", "List the templates of this team"))
+	agenda.AddCommand(teamTemplate)
+
+	setup := model.NewAutocompleteData("setup", "", "Set up the meeting of this channel from a meeting template of this team")
+	setup.AddNamedTextArgument("template", "Name of the template", "[name]", "", true)
+	agenda.AddCommand(setup)
+
+	webhook := model.NewAutocompleteData("webhook", "", "Manage the webhooks notified of the agenda events of this channel")
+	webhookAdd := model.NewAutocompleteData("add", "", "Register a webhook URL")
+	webhookAdd.AddTextArgument("URL receiving the signed JSON payloads", "[url]", "")
+	webhook.AddCommand(webhookAdd)
+	webhook.AddCommand(model.NewAutocompleteData("list", "", "List the webhooks of this channel"))
+	webhookRemove := model.NewAutocompleteData("remove", "", "Remove a webhook")
+	webhookRemove.AddTextArgument("ID of the webhook", "[id]", "")
+	webhook.AddCommand(webhookRemove)
+	webhook.AddCommand(model.NewAutocompleteData("log", "", "Show the recent webhook deliveries"))
+	agenda.AddCommand(webhook)
+
+	token := model.NewAutocompleteData("token", "", "Manage the tokens external systems use to queue items")
+	tokenCreate := model.NewAutocompleteData("create", "", "Create a token for the incoming hook")
+	tokenCreate.AddTextArgument("Name of the external system, shown on its items", "[name]", "")
+	token.AddCommand(tokenCreate)
+	token.AddCommand(model.NewAutocompleteData("list", "", "List the tokens of this channel"))
+	tokenRevoke := model.NewAutocompleteData("revoke", "", "Revoke a token")
+	tokenRevoke.AddTextArgument("ID of the token", "[id]", "")
+	token.AddCommand(tokenRevoke)
+	agenda.AddCommand(token)
+
+	export := model.NewAutocompleteData("export", "", "Export the agenda items of the meetings between two dates")
+	export.AddTextArgument("Start date of the export", "[from YYYY-MM-DD]", "")
+	export.AddTextArgument("End date of the export", "[to YYYY-MM-DD]", "")
+	export.AddNamedStaticListArgument("format", "Format of the export", false, []model.AutocompleteListItem{
+		{Item: exportFormatMarkdown, HelpText: "Markdown document"},
+		{Item: exportFormatCSV, HelpText: "CSV spreadsheet"},
+		{Item: exportFormatJSON, HelpText: "JSON"},
+	})
+	agenda.AddCommand(export)
+
 	help := model.NewAutocompleteData("help", "", "Mattermost Agenda plugin slash command help")
 	agenda.AddCommand(help)
 	return &model.Command{
 		Trigger:          commandTriggerAgenda,
 		AutoComplete:     true,
-		AutoCompleteDesc: "Available commands: list, queue, setting, help",
+		AutoCompleteDesc: "Available commands: list, queue, queue-post, import, start, end, attendance, rotation, setting, doctor, calendar, import-ics, template, team-template, setup, webhook, token, export, help",
 		AutoCompleteHint: "[command]",
 		AutocompleteData: agenda,
 	}
@@ -2,10 +2,18 @@ package main
 
 import (
 	"reflect"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 )
 
+const (
+	// postAsUser posts agenda items as the user queueing them, and postAsBot as the Agenda bot
+	postAsUser = "user"
+	postAsBot  = "bot"
+)
+
 // configuration captures the plugin's external configuration as exposed in the Mattermost server
 // configuration, as well as values computed from the configuration. Any public fields will be
 // deserialized from the Mattermost server configuration in OnConfigurationChange.
@@ -18,6 +26,79 @@ import (
 // If you add non-reference types to your configuration struct, be sure to rewrite Clone as a deep
 // copy appropriate for your types.
 type configuration struct {
+	// SettingsPermission is the role required to change meeting settings:
+	// member, channel_admin or team_admin.
+	SettingsPermission string
+
+	// DefaultSchedule is the comma-separated weekdays of the meetings of the channels without settings
+	DefaultSchedule string
+	// DefaultHashtagFormat is the hashtag format of the meetings of the channels without settings.
+	// Empty uses the channel name followed by the meeting date, i.e. dev-{{ Jan02 }}.
+	DefaultHashtagFormat string
+	// DefaultTimezone is the IANA timezone of the meetings of the channels without settings.
+	// Empty uses the timezone of the server.
+	DefaultTimezone string
+	// MaxItemsPerOccurrence is the maximum number of items queued for a meeting, or 0 for no limit
+	MaxItemsPerOccurrence int
+	// PostAs is who posts the agenda items: user or bot
+	PostAs string
+}
+
+// defaultSchedule returns the weekdays of the default schedule, Thursday if it is not set
+func (c *configuration) defaultSchedule() ([]time.Weekday, error) {
+	if strings.TrimSpace(c.DefaultSchedule) == "" {
+		return []time.Weekday{time.Thursday}, nil
+	}
+
+	var schedule []time.Weekday
+	for _, value := range strings.Split(c.DefaultSchedule, ",") {
+		weekday, err := parseSchedule(strings.TrimSpace(value))
+		if err != nil {
+			return nil, errors.Errorf("invalid default schedule %s: %s", c.DefaultSchedule, err.Error())
+		}
+		schedule = append(schedule, weekday)
+	}
+	return schedule, nil
+}
+
+// IsValid checks that the settings can be used to create meetings, with hashtags of at least
+// minHashtagLength characters
+func (c *configuration) IsValid(minHashtagLength int) error {
+	switch c.SettingsPermission {
+	case "", settingsPermissionMember, settingsPermissionChannelAdmin, settingsPermissionTeamAdmin:
+	default:
+		return errors.Errorf("invalid settings permission %s. Must be member, channel_admin or team_admin", c.SettingsPermission)
+	}
+
+	schedule, err := c.defaultSchedule()
+	if err != nil {
+		return err
+	}
+
+	if c.DefaultHashtagFormat != "" {
+		meeting := &Meeting{Schedule: schedule, HashtagFormat: c.DefaultHashtagFormat, ChannelName: "channel", Name: "meeting"}
+		if problem := meeting.validateHashtagFormat(minHashtagLength); problem != "" {
+			return errors.Errorf("invalid default hashtag format %s: %s", c.DefaultHashtagFormat, problem)
+		}
+	}
+
+	if c.DefaultTimezone != "" {
+		if _, err := time.LoadLocation(c.DefaultTimezone); err != nil {
+			return errors.Errorf("invalid default timezone %s. Must be an IANA timezone, i.e. America/New_York", c.DefaultTimezone)
+		}
+	}
+
+	if c.MaxItemsPerOccurrence < 0 {
+		return errors.Errorf("invalid maximum number of items per meeting %d. Must be 0 for no limit or more", c.MaxItemsPerOccurrence)
+	}
+
+	switch c.PostAs {
+	case "", postAsUser, postAsBot:
+	default:
+		return errors.Errorf("invalid post as %s. Must be user or bot", c.PostAs)
+	}
+
+	return nil
 }
 
 // Clone shallow copies the configuration. Your implementation may require a deep copy if
@@ -77,6 +158,11 @@ func (p *Plugin) OnConfigurationChange() error {
 		return errors.Wrap(err, "failed to load plugin configuration")
 	}
 
+	// Keep the previous configuration until the settings are fixed
+	if err := configuration.IsValid(p.minimumHashtagLength()); err != nil {
+		return errors.Wrap(err, "invalid plugin configuration")
+	}
+
 	p.setConfiguration(configuration)
 
 	return nil
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfiguration_IsValid(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  configuration
+		wantErr string
+	}{
+		{name: "empty", config: configuration{}},
+		{
+			name: "all set",
+			config: configuration{
+				SettingsPermission:    settingsPermissionChannelAdmin,
+				DefaultSchedule:       "Monday, 4",
+				DefaultHashtagFormat:  `{{ .ChannelName }}-{{ .Date "Jan02" }}`,
+				DefaultTimezone:       "America/New_York",
+				MaxItemsPerOccurrence: 10,
+				PostAs:                postAsBot,
+			},
+		},
+		{name: "invalid settings permission", config: configuration{SettingsPermission: "owner"}, wantErr: "invalid settings permission owner. Must be member, channel_admin or team_admin"},
+		{name: "invalid schedule", config: configuration{DefaultSchedule: "Monday, Someday"}, wantErr: "invalid default schedule Monday, Someday"},
+		{name: "invalid hashtag format", config: configuration{DefaultHashtagFormat: "dev {{ Jan02 }}"}, wantErr: "invalid default hashtag format dev {{ Jan02 }}"},
+		{name: "invalid timezone", config: configuration{DefaultTimezone: "Mars/Olympus"}, wantErr: "invalid default timezone Mars/Olympus. Must be an IANA timezone, i.e. America/New_York"},
+		{name: "negative maximum items", config: configuration{MaxItemsPerOccurrence: -1}, wantErr: "invalid maximum number of items per meeting -1. Must be 0 for no limit or more"},
+		{name: "invalid post as", config: configuration{PostAs: "admin"}, wantErr: "invalid post as admin. Must be user or bot"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.IsValid(minimumHashtagLength)
+			if tt.wantErr == "" {
+				assert.Nil(t, err)
+				return
+			}
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestPlugin_GetMeetingDefaults(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("KVGet", "channelID").Return(nil, nil)
+	api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Name: "dev"}, nil)
+
+	p := Plugin{}
+	p.SetAPI(api)
+	p.setConfiguration(&configuration{
+		DefaultSchedule:      "Monday, Thursday",
+		DefaultHashtagFormat: `{{ .ChannelName }}-{{ .Date "Jan02" }}`,
+		DefaultTimezone:      "Europe/Paris",
+	})
+
+	meeting, err := p.GetMeeting("channelID")
+	assert.Nil(t, err)
+	assert.Equal(t, &Meeting{
+		ChannelID:     "channelID",
+		Schedule:      []time.Weekday{time.Monday, time.Thursday},
+		HashtagFormat: `{{ .ChannelName }}-{{ .Date "Jan02" }}`,
+		Timezone:      "Europe/Paris",
+		ChannelName:   "dev",
+	}, meeting)
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+// brokenItemRegex matches the posts starting with the hashtag that are not valid agenda items,
+// i.e. `#dev-Jan02 my item` or `### #dev-Jan02 3. my item`. The message is the first group.
+func brokenItemRegex(hashtag string) *regexp.Regexp {
+	return regexp.MustCompile(`(?is)^\s*(?:#+\s*)?` + regexp.QuoteMeta(hashtag) + `\s*(?:[0-9]+\s*[).:\-]?)?\s*(.*)$`)
+}
+
+// brokenItemMessage returns the message of a post that is not a valid agenda item
+func brokenItemMessage(hashtag, message string) string {
+	if matchGroups := brokenItemRegex(hashtag).FindStringSubmatch(message); len(matchGroups) == 2 {
+		return strings.TrimSpace(matchGroups[1])
+	}
+	return message
+}
+
+// numberingProblem describes why the items are not numbered from 1 without gaps, or returns
+// an empty string if they are.
+func numberingProblem(items []*AgendaItem) string {
+	numbers := make([]string, 0, len(items))
+	valid := true
+	for i, item := range items {
+		numbers = append(numbers, fmt.Sprint(item.Number))
+		if item.Number != i+1 {
+			valid = false
+		}
+	}
+	if valid {
+		return ""
+	}
+
+	return fmt.Sprintf("The items are numbered %s instead of 1 to %d", strings.Join(numbers, ", "), len(items))
+}
+
+// permalink returns the link to the post, or its ID if the site URL is not configured
+func (p *Plugin) permalink(teamID, postID string) string {
+	siteURL := p.siteURL()
+	if siteURL == "" {
+		return postID
+	}
+
+	team, appErr := p.API.GetTeam(teamID)
+	if appErr != nil {
+		return postID
+	}
+
+	return fmt.Sprintf("%s/%s/pl/%s", siteURL, team.Name, postID)
+}
+
+func (p *Plugin) executeCommandDoctor(args *model.CommandArgs) *model.CommandResponse {
+	split := strings.Fields(args.Command)
+	nextWeek := len(split) > 2 && split[2] == "next-week"
+
+	weekday := -1
+	if !nextWeek && len(split) > 2 {
+		parsedWeekday, _ := parseSchedule(split[2])
+		weekday = int(parsedWeekday)
+	}
+
+	meeting, err := p.GetMeeting(args.ChannelId)
+	if err != nil {
+		return responsef("Error getting meeting information for this channel")
+	}
+
+	start, err := meeting.upcomingOccurrence(time.Now(), nextWeek, weekday)
+	if err != nil {
+		return responsef("Error calculating hashtags")
+	}
+	hashtag := meeting.hashtagForDate(start)
+
+	items, broken, err := p.scanAgenda(meeting, args.TeamId, args.UserId, hashtag)
+	if err != nil {
+		return responsef("Error reading the agenda of %s", hashtag)
+	}
+
+	var problems []string
+	for _, post := range broken {
+		firstLine := strings.SplitN(strings.TrimSpace(post.Message), "\n", 2)[0]
+		problems = append(problems, fmt.Sprintf("%s is not a valid agenda item: `%s`", p.permalink(args.TeamId, post.Id), firstLine))
+	}
+	if problem := numberingProblem(items); problem != "" {
+		problems = append(problems, problem)
+	}
+
+	if len(problems) == 0 {
+		return responsef("No problems found in the agenda of %s. %d items are queued.", hashtag, len(items))
+	}
+
+	response := responsef("#### Found %d problems in the agenda of %s\n* %s", len(problems), hashtag, strings.Join(problems, "\n* "))
+	response.Attachments = []*model.SlackAttachment{{
+		Text: "Repairing rewrites the broken items as agenda items and numbers all the items from 1.",
+		Actions: []*model.PostAction{{
+			Name: "Repair",
+			Integration: &model.PostActionIntegration{
+				URL: fmt.Sprintf("/plugins/%s/api/v1/doctor/repair", Manifest.Id),
+				Context: map[string]interface{}{
+					"channel_id": args.ChannelId,
+					"hashtag":    hashtag,
+				},
+			},
+		}},
+	}}
+
+	return response
+}
+
+// repairAgenda rewrites the broken items of the hashtag as agenda items after the valid items,
+// and numbers all the items from 1. It returns the number of broken items repaired.
+func (p *Plugin) repairAgenda(meeting *Meeting, hashtag string) (int, error) {
+	items, broken, err := p.scanAgenda(meeting, "", "", hashtag)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, post := range broken {
+		items = append(items, newAgendaItem(0, brokenItemMessage(hashtag, post.Message), post.Id, p.itemOwner(post)))
+	}
+
+	if err = p.renumberAgendaItems(hashtag, items); err != nil {
+		return 0, err
+	}
+
+	return len(broken), nil
+}
+
+func (p *Plugin) httpDoctorRepair(w http.ResponseWriter, r *http.Request) {
+	mattermostUserID := r.Header.Get("Mattermost-User-Id")
+	if mattermostUserID == "" {
+		http.Error(w, "Not Authorized", http.StatusUnauthorized)
+		return
+	}
+
+	var request *model.PostActionIntegrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request == nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	channelID, _ := request.Context["channel_id"].(string)
+	hashtag, _ := request.Context["hashtag"].(string)
+	if !p.isChannelMember(channelID, mattermostUserID) {
+		http.Error(w, "Not Authorized", http.StatusForbidden)
+		return
+	}
+
+	if !p.isChannelAdmin(channelID, mattermostUserID) {
+		p.writeJSON(w, &model.PostActionIntegrationResponse{
+			EphemeralText: "Only channel admins can repair the agenda items of other users.",
+		})
+		return
+	}
+
+	meeting, err := p.GetMeeting(channelID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	repaired, err := p.repairAgenda(meeting, hashtag)
+	if err != nil {
+		p.API.LogError("Failed to repair agenda", "error", err.Error(), "channel_id", channelID, "hashtag", hashtag)
+		p.writeJSON(w, &model.PostActionIntegrationResponse{
+			EphemeralText: fmt.Sprintf("Failed to repair the agenda of %s: %s", hashtag, err.Error()),
+		})
+		return
+	}
+
+	p.writeJSON(w, &model.PostActionIntegrationResponse{
+		EphemeralText: fmt.Sprintf("Repaired %d items and renumbered the agenda of %s.", repaired, hashtag),
+	})
+}
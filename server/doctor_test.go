@@ -0,0 +1,114 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// brokenAgendaPosts are the posts of 2022-01-06 with a hand-edited item and a post mentioning the hashtag
+var brokenAgendaPosts = []*model.Post{
+	{Id: "post1", UserId: "author", Message: "#### #dev-Jan06 1) First\nwith details", CreateAt: 1},
+	{Id: "post2", UserId: "other", Message: "### #dev-Jan06 2. Edited by hand", CreateAt: 2},
+	{Id: "post3", UserId: "author", Message: "Let's talk about it on #dev-Jan06", CreateAt: 3},
+	{Id: "post4", UserId: "author", Message: "#### #dev-Jan06 3) Third", CreateAt: 4},
+}
+
+var brokenAgendaMeeting = &Meeting{
+	ChannelID:     "channelID",
+	Schedule:      []time.Weekday{time.Thursday},
+	HashtagFormat: "dev-{{Jan02}}",
+}
+
+func TestPlugin_scanAgenda(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Name: "dev", TeamId: "teamID"}, nil)
+	api.On("SearchPostsInTeam", "teamID", model.ParseSearchParams("in:dev #dev-Jan06", 0)).Return(brokenAgendaPosts, nil)
+	api.On("LogWarn", "Failed to parse agenda item", "post_id", "post2", "hashtag", "#dev-Jan06").Return()
+	api.On("LogDebug", "Failed to parse meeting post", "post_id", "post3", "error", mock.AnythingOfType("string")).Return()
+	p := Plugin{}
+	p.SetAPI(api)
+
+	items, broken, err := p.scanAgenda(brokenAgendaMeeting, "", "", "#dev-Jan06")
+	assert.Nil(t, err)
+	assert.Equal(t, []*AgendaItem{
+		{Number: 1, Message: "First\nwith details", Title: "First", Details: "with details", PostID: "post1", UserID: "author"},
+		{Number: 3, Message: "Third", Title: "Third", PostID: "post4", UserID: "author"},
+	}, items)
+	assert.Len(t, broken, 1)
+	assert.Equal(t, "post2", broken[0].Id)
+	api.AssertExpectations(t)
+}
+
+func TestPlugin_calculateQueueItemNumberSkipsBrokenItems(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Name: "dev", TeamId: "teamID"}, nil)
+	api.On("SearchPostsInTeam", "teamID", model.ParseSearchParams("in:dev #dev-Jan06", 0)).Return(brokenAgendaPosts, nil)
+	api.On("LogWarn", "Failed to parse agenda item", "post_id", "post2", "hashtag", "#dev-Jan06").Return()
+	api.On("LogDebug", "Failed to parse meeting post", "post_id", "post3", "error", mock.AnythingOfType("string")).Return()
+	api.On("GetPost", "post4").Return(brokenAgendaPosts[3].Clone(), nil)
+	api.On("UpdatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.Id == "post4" && post.Message == "#### #dev-Jan06 2) Third"
+	})).Return(nil, nil)
+	p := Plugin{}
+	p.SetAPI(api)
+
+	number, broken, err := p.calculateQueueItemNumberAndUpdateOldItems(brokenAgendaMeeting, "", "", "#dev-Jan06")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, number)
+	assert.Len(t, broken, 1)
+	api.AssertNumberOfCalls(t, "UpdatePost", 1)
+}
+
+func TestPlugin_repairAgenda(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Name: "dev", TeamId: "teamID"}, nil)
+	api.On("SearchPostsInTeam", "teamID", model.ParseSearchParams("in:dev #dev-Jan06", 0)).Return(brokenAgendaPosts, nil)
+	api.On("LogWarn", "Failed to parse agenda item", "post_id", "post2", "hashtag", "#dev-Jan06").Return()
+	api.On("LogDebug", "Failed to parse meeting post", "post_id", "post3", "error", mock.AnythingOfType("string")).Return()
+	api.On("GetPost", "post2").Return(brokenAgendaPosts[1].Clone(), nil)
+	api.On("GetPost", "post4").Return(brokenAgendaPosts[3].Clone(), nil)
+	api.On("UpdatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.Id == "post4" && post.Message == "#### #dev-Jan06 2) Third"
+	})).Return(nil, nil)
+	api.On("UpdatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.Id == "post2" && post.Message == "#### #dev-Jan06 3) Edited by hand"
+	})).Return(nil, nil)
+	p := Plugin{}
+	p.SetAPI(api)
+
+	repaired, err := p.repairAgenda(brokenAgendaMeeting, "#dev-Jan06")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, repaired)
+	api.AssertNumberOfCalls(t, "UpdatePost", 2)
+}
+
+func TestBrokenItemMessage(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{message: "#dev-Jan06 my item", want: "my item"},
+		{message: "### #dev-Jan06 2. my item", want: "my item"},
+		{message: "####   #DEV-Jan06 12) my item\nwith details", want: "my item\nwith details"},
+		{message: "#### #dev-Jan06 4 - my item", want: "my item"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			assert.True(t, brokenItemRegex("#dev-Jan06").MatchString(tt.message))
+			assert.Equal(t, tt.want, brokenItemMessage("#dev-Jan06", tt.message))
+		})
+	}
+
+	assert.False(t, brokenItemRegex("#dev-Jan06").MatchString("Let's talk about it on #dev-Jan06"))
+}
+
+func TestNumberingProblem(t *testing.T) {
+	assert.Equal(t, "", numberingProblem(nil))
+	assert.Equal(t, "", numberingProblem([]*AgendaItem{{Number: 1}, {Number: 2}}))
+	assert.Equal(t, "The items are numbered 1, 1, 3 instead of 1 to 3", numberingProblem([]*AgendaItem{{Number: 1}, {Number: 1}, {Number: 3}}))
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	emptyCheckSentKeyPrefix = "empty_check_sent_"
+
+	// emptyCheckDateProp marks the bot posts proposing to cancel an occurrence with its date.
+	emptyCheckDateProp = "agenda_empty_check_date"
+
+	emptyAgendaActionCancel = "cancel"
+	emptyAgendaActionKeep   = "keep"
+)
+
+// emptyCheckTime returns when to check if the agenda of the occurrence starting at start is empty.
+// It returns false if the check is disabled.
+func (m *Meeting) emptyCheckTime(start time.Time) (time.Time, bool) {
+	if m.EmptyCheck == "" {
+		return time.Time{}, false
+	}
+
+	before, err := time.ParseDuration(m.EmptyCheck)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return start.Add(-before), true
+}
+
+// checkEmptyAgenda proposes to cancel the meeting occurrence starting at start if nothing
+// was queued for it. Every occurrence is checked once.
+func (p *Plugin) checkEmptyAgenda(meeting *Meeting, start time.Time) error {
+	date := start.Format(occurrenceDateFormat)
+	sentKey := emptyCheckSentKeyPrefix + meeting.ChannelID + "_" + date
+	firstTime, appErr := p.API.KVSetWithOptions(sentKey, []byte(start.Format(time.RFC3339)), model.PluginKVSetOptions{
+		Atomic:          true,
+		OldValue:        nil,
+		ExpireInSeconds: reminderSentExpiry,
+	})
+	if appErr != nil {
+		return appErr
+	}
+	if !firstTime {
+		return nil
+	}
+
+	hashtag := meeting.hashtagForDate(start)
+	items, err := p.getAgendaItems(meeting, "", "", hashtag)
+	if err != nil {
+		return errors.Wrap(err, "failed to get agenda items")
+	}
+	if len(items) > 0 {
+		return nil
+	}
+
+	if err = p.createEmptyAgendaPost(meeting, start, hashtag); err != nil {
+		if appErr = p.API.KVDelete(sentKey); appErr != nil {
+			p.API.LogWarn("Failed to release the empty agenda check", "error", appErr.Error(), "channel_id", meeting.ChannelID)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// createEmptyAgendaPost posts the proposal to cancel the occurrence starting at start.
+func (p *Plugin) createEmptyAgendaPost(meeting *Meeting, start time.Time, hashtag string) error {
+	date := start.Format(occurrenceDateFormat)
+	actionURL := fmt.Sprintf("/plugins/%s/api/v1/empty-agenda", Manifest.Id)
+	post := &model.Post{
+		UserId:    p.botID,
+		ChannelId: meeting.ChannelID,
+		Message: fmt.Sprintf("#### Nothing is queued for meeting %s on %s\nShould the meeting be cancelled? Items queued after a cancellation go to the next meeting.",
+			hashtag, meeting.formatOccurrence(start)),
+	}
+	model.ParseSlackAttachment(post, []*model.SlackAttachment{{
+		Actions: []*model.PostAction{
+			{
+				Name:  "Cancel meeting",
+				Style: "danger",
+				Integration: &model.PostActionIntegration{
+					URL: actionURL,
+					Context: map[string]interface{}{
+						"action":     emptyAgendaActionCancel,
+						"channel_id": meeting.ChannelID,
+						"date":       date,
+					},
+				},
+			},
+			{
+				Name: "Keep meeting",
+				Integration: &model.PostActionIntegration{
+					URL: actionURL,
+					Context: map[string]interface{}{
+						"action":     emptyAgendaActionKeep,
+						"channel_id": meeting.ChannelID,
+						"date":       date,
+					},
+				},
+			},
+		},
+	}})
+	post.AddProp(emptyCheckDateProp, date)
+
+	if _, appErr := p.API.CreatePost(post); appErr != nil {
+		return appErr
+	}
+
+	return nil
+}
+
+func (p *Plugin) httpEmptyAgendaAction(w http.ResponseWriter, r *http.Request) {
+	mattermostUserID := r.Header.Get("Mattermost-User-Id")
+	if mattermostUserID == "" {
+		http.Error(w, "Not Authorized", http.StatusUnauthorized)
+		return
+	}
+
+	var request *model.PostActionIntegrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request == nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	action, _ := request.Context["action"].(string)
+	channelID, _ := request.Context["channel_id"].(string)
+	date, _ := request.Context["date"].(string)
+	if !p.isChannelMember(channelID, mattermostUserID) {
+		http.Error(w, "Not Authorized", http.StatusForbidden)
+		return
+	}
+
+	day, err := time.Parse(occurrenceDateFormat, date)
+	if err != nil {
+		http.Error(w, "Invalid date", http.StatusBadRequest)
+		return
+	}
+
+	post, appErr := p.API.GetPost(request.PostId)
+	if appErr != nil {
+		http.Error(w, appErr.Error(), http.StatusInternalServerError)
+		return
+	}
+	// Only the proposal posted by the bot for this occurrence can be answered.
+	if post.ChannelId != channelID || post.UserId != p.botID || post.GetProp(emptyCheckDateProp) != date {
+		http.Error(w, "Not Authorized", http.StatusForbidden)
+		return
+	}
+
+	meeting, err := p.GetMeeting(channelID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	username := mattermostUserID
+	if user, appErr := p.API.GetUser(mattermostUserID); appErr == nil {
+		username = user.Username
+	}
+
+	var message string
+	switch action {
+	case emptyAgendaActionCancel:
+		if !p.canManageMeeting(channelID, mattermostUserID) {
+			p.writeJSON(w, &model.PostActionIntegrationResponse{
+				EphemeralText: "You do not have permission to cancel this meeting.",
+			})
+			return
+		}
+		if meeting.skipOccurrence(date) {
+			if err = p.SaveMeeting(meeting); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		message = fmt.Sprintf("The meeting of %s was cancelled by @%s.", day.Format("Monday, January 2"), username)
+	case emptyAgendaActionKeep:
+		message = fmt.Sprintf("@%s kept the meeting of %s.", username, day.Format("Monday, January 2"))
+	default:
+		http.Error(w, "Unknown action", http.StatusBadRequest)
+		return
+	}
+
+	post.Message = message
+	post.DelProp("attachments")
+
+	p.writeJSON(w, &model.PostActionIntegrationResponse{Update: post})
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMeeting_emptyCheckTime(t *testing.T) {
+	start := time.Date(2022, 1, 6, 15, 0, 0, 0, time.UTC)
+
+	_, ok := (&Meeting{}).emptyCheckTime(start)
+	assert.False(t, ok)
+
+	checkAt, ok := (&Meeting{EmptyCheck: "3h0m0s"}).emptyCheckTime(start)
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2022, 1, 6, 12, 0, 0, 0, time.UTC), checkAt)
+}
+
+func TestHTTPEmptyAgendaAction(t *testing.T) {
+	meeting := &Meeting{
+		ChannelID:     "channelID",
+		Schedule:      []time.Weekday{time.Thursday},
+		HashtagFormat: "{{Jan02}}",
+	}
+	jsonMeeting, err := json.Marshal(meeting)
+	assert.Nil(t, err)
+
+	newRequest := func(action string) *http.Request {
+		body, _ := json.Marshal(&model.PostActionIntegrationRequest{
+			PostId: "postID",
+			Context: map[string]interface{}{
+				"action":     action,
+				"channel_id": "channelID",
+				"date":       "2022-01-06",
+			},
+		})
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/empty-agenda", strings.NewReader(string(body)))
+		r.Header.Add("Mattermost-User-Id", "userID")
+		return r
+	}
+
+	proposal := func() *model.Post {
+		post := &model.Post{Id: "postID", ChannelId: "channelID", UserId: "botID", Message: "Nothing is queued"}
+		post.AddProp(emptyCheckDateProp, "2022-01-06")
+		return post
+	}
+
+	setupAPI := func() *plugintest.API {
+		api := &plugintest.API{}
+		api.On("GetChannelMember", "channelID", "userID").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionTo", "userID", model.PermissionManageSystem).Return(false)
+		api.On("KVGet", "channelID").Return(jsonMeeting, nil)
+		api.On("GetUser", "userID").Return(&model.User{Id: "userID", Username: "alice"}, nil)
+		api.On("GetPost", "postID").Return(proposal(), nil)
+		return api
+	}
+
+	t.Run("cancel records a skip date", func(t *testing.T) {
+		api := setupAPI()
+		api.On("KVSet", "channelID", mock.MatchedBy(func(value []byte) bool {
+			var saved *Meeting
+			return json.Unmarshal(value, &saved) == nil && len(saved.SkipDates) == 1 && saved.SkipDates[0] == "2022-01-06"
+		})).Return(nil)
+		p := Plugin{botID: "botID"}
+		p.SetAPI(api)
+
+		w := httptest.NewRecorder()
+		p.ServeHTTP(nil, w, newRequest(emptyAgendaActionCancel))
+
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		var response *model.PostActionIntegrationResponse
+		assert.Nil(t, json.NewDecoder(w.Body).Decode(&response))
+		assert.Equal(t, "The meeting of Thursday, January 6 was cancelled by @alice.", response.Update.Message)
+		api.AssertCalled(t, "KVSet", "channelID", mock.Anything)
+	})
+
+	t.Run("keep leaves the meeting unchanged", func(t *testing.T) {
+		api := setupAPI()
+		p := Plugin{botID: "botID"}
+		p.SetAPI(api)
+
+		w := httptest.NewRecorder()
+		p.ServeHTTP(nil, w, newRequest(emptyAgendaActionKeep))
+
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		api.AssertNotCalled(t, "KVSet", mock.Anything, mock.Anything)
+	})
+
+	t.Run("other posts are rejected", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("GetChannelMember", "channelID", "userID").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionTo", "userID", model.PermissionManageSystem).Return(false)
+		api.On("GetPost", "postID").Return(&model.Post{Id: "postID", ChannelId: "otherChannelID", UserId: "otherUserID", Message: "private"}, nil)
+		p := Plugin{botID: "botID"}
+		p.SetAPI(api)
+
+		w := httptest.NewRecorder()
+		p.ServeHTTP(nil, w, newRequest(emptyAgendaActionKeep))
+
+		assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+		assert.NotContains(t, w.Body.String(), "private")
+	})
+
+	t.Run("non members are rejected", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("GetChannelMember", "channelID", "userID").Return(nil, model.NewAppError("GetChannelMember", "not found", nil, "", http.StatusNotFound))
+		api.On("HasPermissionTo", "userID", model.PermissionManageSystem).Return(false)
+		p := Plugin{botID: "botID"}
+		p.SetAPI(api)
+
+		w := httptest.NewRecorder()
+		p.ServeHTTP(nil, w, newRequest(emptyAgendaActionCancel))
+
+		assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+	})
+}
+
+func TestPlugin_checkEmptyAgenda(t *testing.T) {
+	meeting := &Meeting{ChannelID: "channelID", Schedule: []time.Weekday{time.Thursday}, HashtagFormat: "dev-{{Jan02}}", Time: "15:00", Timezone: "UTC"}
+	start := time.Date(2022, 1, 6, 15, 0, 0, 0, time.UTC)
+	sentKey := emptyCheckSentKeyPrefix + "channelID_2022-01-06"
+
+	api := &plugintest.API{}
+	api.On("KVSetWithOptions", sentKey, mock.Anything, mock.Anything).Return(true, nil)
+	api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Name: "dev", TeamId: "teamID"}, nil)
+	api.On("SearchPostsInTeam", "teamID", mock.Anything).Return([]*model.Post{}, nil)
+	api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.UserId == "botID" && post.GetProp(emptyCheckDateProp) == "2022-01-06"
+	})).Return(nil, model.NewAppError("CreatePost", "app.post.save.app_error", nil, "", http.StatusInternalServerError))
+	api.On("KVDelete", sentKey).Return(nil)
+	p := Plugin{botID: "botID"}
+	p.SetAPI(api)
+
+	assert.NotNil(t, p.checkEmptyAgenda(meeting, start))
+	api.AssertCalled(t, "KVDelete", sentKey)
+}
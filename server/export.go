@@ -0,0 +1,347 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	// maxExportDays is the longest date range of an export
+	maxExportDays = 366
+
+	exportFormatMarkdown = "md"
+	exportFormatCSV      = "csv"
+	exportFormatJSON     = "json"
+
+	// The status of an exported item: queued for an upcoming meeting, discussed in a meeting
+	// recorded with /agenda start, queued for a cancelled meeting, or queued for a past meeting
+	// that was not recorded.
+	itemStatusQueued    = "queued"
+	itemStatusDiscussed = "discussed"
+	itemStatusCancelled = "cancelled"
+	itemStatusPast      = "past"
+)
+
+// exportContentTypes are the content types of the export formats
+var exportContentTypes = map[string]string{
+	exportFormatMarkdown: "text/markdown; charset=utf-8",
+	exportFormatCSV:      "text/csv; charset=utf-8",
+	exportFormatJSON:     "application/json",
+}
+
+// ExportedItem is an agenda item of the export of a meeting
+type ExportedItem struct {
+	Date     string   `json:"date"`
+	Hashtag  string   `json:"hashtag"`
+	Number   int      `json:"number"`
+	Title    string   `json:"title"`
+	Details  string   `json:"details,omitempty"`
+	Duration string   `json:"duration,omitempty"`
+	Labels   []string `json:"labels,omitempty"`
+	Author   string   `json:"author"`
+	Status   string   `json:"status"`
+	Link     string   `json:"link"`
+}
+
+// AgendaExport is the history of the agenda of a meeting between two dates, inclusive
+type AgendaExport struct {
+	ChannelID string          `json:"channelId"`
+	Meeting   string          `json:"meeting"`
+	From      string          `json:"from"`
+	To        string          `json:"to"`
+	Items     []*ExportedItem `json:"items"`
+}
+
+// occurrenceStatus returns the status of the items of a meeting occurrence
+func occurrenceStatus(meeting *Meeting, start time.Time, attendance *Attendance, now time.Time) string {
+	switch {
+	case meeting.isSkipped(start):
+		return itemStatusCancelled
+	case attendance != nil && attendance.StartedAt > 0:
+		return itemStatusDiscussed
+	case !meeting.isPast(start, now):
+		return itemStatusQueued
+	default:
+		return itemStatusPast
+	}
+}
+
+// checkExportRange returns why the date range can't be exported, or nil if it can
+func checkExportRange(from, to time.Time) error {
+	if to.Before(from) {
+		return errors.New("the end date must be after the start date")
+	}
+	if to.Sub(from) > maxExportDays*24*time.Hour {
+		return errors.Errorf("the date range cannot be longer than %d days", maxExportDays)
+	}
+	return nil
+}
+
+// exportAgenda gathers the items of the meeting occurrences between two dates the user can see
+func (p *Plugin) exportAgenda(channelID, userID string, from, to time.Time) (*AgendaExport, error) {
+	if err := checkExportRange(from, to); err != nil {
+		return nil, err
+	}
+
+	meeting, err := p.GetMeeting(channelID)
+	if err != nil {
+		return nil, errors.New("failed to get the meeting information of the channel")
+	}
+
+	channel, appErr := p.API.GetChannel(channelID)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	// The range covers whole days in the meeting timezone
+	loc := meeting.location()
+	year, month, day := from.Date()
+	from = time.Date(year, month, day, 0, 0, 0, 0, loc)
+	year, month, day = to.Date()
+	to = time.Date(year, month, day, 23, 59, 59, 0, loc)
+
+	export := &AgendaExport{
+		ChannelID: channelID,
+		Meeting:   meeting.Name,
+		From:      from.Format(occurrenceDateFormat),
+		To:        to.Format(occurrenceDateFormat),
+		Items:     []*ExportedItem{},
+	}
+	if export.Meeting == "" {
+		export.Meeting = channel.DisplayName
+	}
+
+	now := time.Now()
+	usernames := map[string]string{}
+	for _, start := range meeting.occurrencesBetween(from, to) {
+		date := start.Format(occurrenceDateFormat)
+		hashtag := meeting.hashtagForDate(start)
+		items, err := p.getAgendaItems(meeting, channel.TeamId, userID, hashtag)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get the items of %s", hashtag)
+		}
+		if len(items) == 0 {
+			continue
+		}
+
+		attendance, err := p.GetAttendance(channelID, date)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get attendance")
+		}
+		status := occurrenceStatus(meeting, start, attendance, now)
+
+		for _, item := range items {
+			if _, ok := usernames[item.UserID]; !ok {
+				usernames[item.UserID] = p.usernames([]string{item.UserID})[item.UserID]
+			}
+			export.Items = append(export.Items, &ExportedItem{
+				Date:     date,
+				Hashtag:  hashtag,
+				Number:   item.Number,
+				Title:    item.Title,
+				Details:  item.Details,
+				Duration: item.Duration,
+				Labels:   item.Labels,
+				Author:   usernames[item.UserID],
+				Status:   status,
+				Link:     p.permalink(channel.TeamId, item.PostID),
+			})
+		}
+	}
+
+	return export, nil
+}
+
+// markdown returns the export as a Markdown document, with a section per meeting occurrence
+func (e *AgendaExport) markdown() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Agenda of %s from %s to %s\n", e.Meeting, e.From, e.To)
+	if len(e.Items) == 0 {
+		sb.WriteString("\n_No items were queued in this period._\n")
+	}
+
+	hashtag := ""
+	for _, item := range e.Items {
+		if item.Hashtag != hashtag {
+			hashtag = item.Hashtag
+			fmt.Fprintf(&sb, "\n## %s %s\n\n", item.Date, item.Hashtag)
+		}
+
+		fmt.Fprintf(&sb, "%d. **%s**", item.Number, item.Title)
+		if item.Duration != "" {
+			fmt.Fprintf(&sb, " (%s)", item.Duration)
+		}
+		for _, label := range item.Labels {
+			fmt.Fprintf(&sb, " `%s`", label)
+		}
+		fmt.Fprintf(&sb, " by @%s, %s. [Link](%s)\n", item.Author, item.Status, item.Link)
+		if item.Details != "" {
+			for _, line := range strings.Split(item.Details, "\n") {
+				sb.WriteString(strings.TrimRight("   "+line, " ") + "\n")
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+// csv returns the export as CSV, with a row per item
+func (e *AgendaExport) csv() ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	rows := [][]string{{"date", "hashtag", "number", "title", "details", "duration", "labels", "author", "status", "link"}}
+	for _, item := range e.Items {
+		rows = append(rows, []string{
+			item.Date, item.Hashtag, strconv.Itoa(item.Number), item.Title, item.Details,
+			item.Duration, strings.Join(item.Labels, ", "), item.Author, item.Status, item.Link,
+		})
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encode returns the export in the given format
+func (e *AgendaExport) encode(format string) ([]byte, error) {
+	switch format {
+	case exportFormatMarkdown:
+		return []byte(e.markdown()), nil
+	case exportFormatCSV:
+		return e.csv()
+	case exportFormatJSON:
+		return json.MarshalIndent(e, "", "  ")
+	}
+	return nil, errors.Errorf("unknown format %s. Must be md, csv or json", format)
+}
+
+// filename returns the name of the file of the export in the given format
+func (e *AgendaExport) filename(format string) string {
+	return fmt.Sprintf("agenda-%s-%s.%s", e.From, e.To, format)
+}
+
+// parseExportArgs parses the `[from] [to] --format md|csv|json` arguments of the export command
+func parseExportArgs(args []string, now time.Time) (from, to time.Time, format string, err error) {
+	to = now
+	from = to.AddDate(0, 0, -30)
+	format = exportFormatMarkdown
+
+	var dates []string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--format" && i+1 < len(args):
+			format = args[i+1]
+			i++
+		case strings.HasPrefix(args[i], "--format="):
+			format = strings.TrimPrefix(args[i], "--format=")
+		default:
+			dates = append(dates, args[i])
+		}
+	}
+
+	if _, ok := exportContentTypes[format]; !ok {
+		return from, to, "", errors.Errorf("unknown format %s. Must be md, csv or json", format)
+	}
+	if len(dates) > 2 {
+		return from, to, "", errors.New("too many arguments. Usage: `/agenda export [from] [to] --format md|csv|json`")
+	}
+	if len(dates) > 0 {
+		if from, err = time.Parse(occurrenceDateFormat, dates[0]); err != nil {
+			return from, to, "", errors.Errorf("invalid date %s. Dates must be formatted as YYYY-MM-DD", dates[0])
+		}
+	}
+	if len(dates) > 1 {
+		if to, err = time.Parse(occurrenceDateFormat, dates[1]); err != nil {
+			return from, to, "", errors.Errorf("invalid date %s. Dates must be formatted as YYYY-MM-DD", dates[1])
+		}
+	}
+
+	return from, to, format, nil
+}
+
+// executeCommandExport replies with the link downloading the export. Exporting a long range
+// searches the items of every occurrence, which takes longer than a slash command can.
+func (p *Plugin) executeCommandExport(args *model.CommandArgs) *model.CommandResponse {
+	from, to, format, err := parseExportArgs(strings.Fields(args.Command)[2:], time.Now())
+	if err != nil {
+		return responsef(err.Error())
+	}
+	if err = checkExportRange(from, to); err != nil {
+		return responsef("Error exporting the agenda: %s", err.Error())
+	}
+
+	siteURL := p.siteURL()
+	if siteURL == "" {
+		return responsef("The agenda export requires the Site URL of the server to be configured")
+	}
+
+	fromDate, toDate := from.Format(occurrenceDateFormat), to.Format(occurrenceDateFormat)
+	query := url.Values{"channelId": {args.ChannelId}, "from": {fromDate}, "to": {toDate}, "format": {format}}
+	message := fmt.Sprintf("[Download the agenda items from %s to %s](%s/plugins/%s/api/v1/export?%s).",
+		fromDate, toDate, siteURL, Manifest.Id, query.Encode())
+	// The items are found by the hashtags of the current format only
+	message += "\nItems queued with a previous hashtag format are not exported, unless they were moved with `/agenda setting hashtag <format> --migrate`."
+
+	return responsef(message)
+}
+
+// httpExport downloads the export of the agenda of a meeting:
+//
+//	GET /api/v1/export?channelId=&from=YYYY-MM-DD&to=YYYY-MM-DD&format=md|csv|json
+func (p *Plugin) httpExport(w http.ResponseWriter, r *http.Request) {
+	mattermostUserID := r.Header.Get("Mattermost-User-Id")
+	if mattermostUserID == "" {
+		http.Error(w, "Not Authorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	channelID := query.Get("channelId")
+	if !p.isChannelMember(channelID, mattermostUserID) {
+		http.Error(w, "Not Authorized", http.StatusForbidden)
+		return
+	}
+
+	var args []string
+	for _, name := range []string{"from", "to"} {
+		if value := query.Get(name); value != "" {
+			args = append(args, value)
+		}
+	}
+	if format := query.Get("format"); format != "" {
+		args = append(args, "--format", format)
+	}
+	from, to, format, err := parseExportArgs(args, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	export, err := p.exportAgenda(channelID, mattermostUserID, from, to)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data, err := export.encode(format)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", exportContentTypes[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", export.filename(format)))
+	if _, err := w.Write(data); err != nil {
+		p.API.LogWarn("Failed to write the agenda export", "error", err.Error())
+	}
+}
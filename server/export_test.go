@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseExportArgs(t *testing.T) {
+	now := time.Date(2022, 1, 31, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		args       string
+		wantFrom   string
+		wantTo     string
+		wantFormat string
+		wantErr    bool
+	}{
+		{name: "defaults", args: "", wantFrom: "2022-01-01", wantTo: "2022-01-31", wantFormat: "md"},
+		{name: "dates and format", args: "2022-01-03 2022-01-10 --format csv", wantFrom: "2022-01-03", wantTo: "2022-01-10", wantFormat: "csv"},
+		{name: "format first", args: "--format=json 2022-01-03", wantFrom: "2022-01-03", wantTo: "2022-01-31", wantFormat: "json"},
+		{name: "unknown format", args: "--format pdf", wantErr: true},
+		{name: "invalid date", args: "yesterday", wantErr: true},
+		{name: "too many dates", args: "2022-01-01 2022-01-02 2022-01-03", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, format, err := parseExportArgs(strings.Fields(tt.args), now)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.wantFrom, from.Format(occurrenceDateFormat))
+			assert.Equal(t, tt.wantTo, to.Format(occurrenceDateFormat))
+			assert.Equal(t, tt.wantFormat, format)
+		})
+	}
+}
+
+func TestOccurrenceStatus(t *testing.T) {
+	meeting := &Meeting{Schedule: []time.Weekday{time.Thursday}, SkipDates: []string{"2022-01-13"}}
+	now := time.Date(2022, 1, 15, 12, 0, 0, 0, time.Local)
+
+	assert.Equal(t, itemStatusDiscussed, occurrenceStatus(meeting, time.Date(2022, 1, 6, 0, 0, 0, 0, time.Local), &Attendance{StartedAt: 1}, now))
+	assert.Equal(t, itemStatusPast, occurrenceStatus(meeting, time.Date(2022, 1, 6, 0, 0, 0, 0, time.Local), nil, now))
+	assert.Equal(t, itemStatusCancelled, occurrenceStatus(meeting, time.Date(2022, 1, 13, 0, 0, 0, 0, time.Local), nil, now))
+	assert.Equal(t, itemStatusQueued, occurrenceStatus(meeting, time.Date(2022, 1, 20, 0, 0, 0, 0, time.Local), nil, now))
+
+	// Meetings without a start time are upcoming for the whole day
+	today := time.Date(2022, 1, 20, 12, 0, 0, 0, time.Local)
+	assert.Equal(t, itemStatusQueued, occurrenceStatus(meeting, time.Date(2022, 1, 20, 0, 0, 0, 0, time.Local), nil, today))
+	timed := &Meeting{Schedule: []time.Weekday{time.Thursday}, Time: "09:00"}
+	assert.Equal(t, itemStatusPast, occurrenceStatus(timed, time.Date(2022, 1, 20, 9, 0, 0, 0, time.Local), nil, today))
+}
+
+func TestPlugin_executeCommandExport(t *testing.T) {
+	siteURL := "https://mattermost.example.com"
+	api := &plugintest.API{}
+	api.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+	p := Plugin{}
+	p.SetAPI(api)
+
+	response := p.executeCommandExport(&model.CommandArgs{Command: "/agenda export 2022-01-01 2022-01-31 --format csv", ChannelId: "channelID", UserId: "userID"})
+	assert.Equal(t, "[Download the agenda items from 2022-01-01 to 2022-01-31]"+
+		"(https://mattermost.example.com/plugins/com.mattermost.agenda/api/v1/export?channelId=channelID&format=csv&from=2022-01-01&to=2022-01-31).\n"+
+		"Items queued with a previous hashtag format are not exported, unless they were moved with `/agenda setting hashtag <format> --migrate`.", response.Text)
+
+	// The items are only searched when the link is followed
+	api.AssertNotCalled(t, "SearchPostsInTeamForUser", mock.Anything, mock.Anything, mock.Anything)
+	api.AssertNotCalled(t, "UploadFile", mock.Anything, mock.Anything, mock.Anything)
+
+	response = p.executeCommandExport(&model.CommandArgs{Command: "/agenda export 2021-01-01 2022-01-31", ChannelId: "channelID", UserId: "userID"})
+	assert.Equal(t, "Error exporting the agenda: the date range cannot be longer than 366 days", response.Text)
+}
+
+var testExport = &AgendaExport{
+	ChannelID: "channelID",
+	Meeting:   "Dev sync",
+	From:      "2022-01-01",
+	To:        "2022-01-31",
+	Items: []*ExportedItem{
+		{Date: "2022-01-06", Hashtag: "#dev-Jan06", Number: 1, Title: "Release status", Details: "- QA is blocked", Duration: "15m0s", Labels: []string{"release"}, Author: "alice", Status: "discussed", Link: "post1"},
+		{Date: "2022-01-06", Hashtag: "#dev-Jan06", Number: 2, Title: "Hiring, interviews", Author: "bob", Status: "discussed", Link: "post2"},
+		{Date: "2022-01-13", Hashtag: "#dev-Jan13", Number: 1, Title: "Retro", Author: "alice", Status: "past", Link: "post3"},
+	},
+}
+
+func TestAgendaExport_encode(t *testing.T) {
+	markdown, err := testExport.encode(exportFormatMarkdown)
+	assert.Nil(t, err)
+	assert.Equal(t, "# Agenda of Dev sync from 2022-01-01 to 2022-01-31\n"+
+		"\n## 2022-01-06 #dev-Jan06\n\n"+
+		"1. **Release status** (15m0s) `release` by @alice, discussed. [Link](post1)\n"+
+		"   - QA is blocked\n"+
+		"2. **Hiring, interviews** by @bob, discussed. [Link](post2)\n"+
+		"\n## 2022-01-13 #dev-Jan13\n\n"+
+		"1. **Retro** by @alice, past. [Link](post3)\n", string(markdown))
+
+	csv, err := testExport.encode(exportFormatCSV)
+	assert.Nil(t, err)
+	assert.Equal(t, "date,hashtag,number,title,details,duration,labels,author,status,link\n"+
+		"2022-01-06,#dev-Jan06,1,Release status,- QA is blocked,15m0s,release,alice,discussed,post1\n"+
+		"2022-01-06,#dev-Jan06,2,\"Hiring, interviews\",,,,bob,discussed,post2\n"+
+		"2022-01-13,#dev-Jan13,1,Retro,,,,alice,past,post3\n", string(csv))
+
+	data, err := testExport.encode(exportFormatJSON)
+	assert.Nil(t, err)
+	var decoded AgendaExport
+	assert.Nil(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, testExport, &decoded)
+}
+
+func TestPlugin_exportAgenda(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("KVGet", "channelID").Return([]byte(`{"channelId":"channelID","schedule":[4],"hashtagFormat":"dev-{{Jan02}}","skipDates":["2022-01-13"]}`), nil)
+	api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Name: "dev", DisplayName: "Dev", TeamId: "teamID"}, nil)
+	api.On("GetConfig").Return(&model.Config{})
+	api.On("KVGet", attendanceKey("channelID", "2022-01-06")).Return([]byte(`{"startedAt":1}`), nil)
+	api.On("KVGet", mock.Anything).Return(nil, nil)
+	api.On("GetUser", "author").Return(&model.User{Id: "author", Username: "alice"}, nil)
+
+	postsFor := func(hashtag string, posts ...*model.Post) {
+		list := model.NewPostList()
+		for _, post := range posts {
+			list.AddPost(post)
+			list.AddOrder(post.Id)
+		}
+		api.On("SearchPostsInTeamForUser", "teamID", "member", mock.MatchedBy(func(params model.SearchParameter) bool {
+			return strings.Contains(*params.Terms, hashtag)
+		})).Return(model.MakePostSearchResults(list, nil), nil)
+	}
+	postsFor("#dev-Jan06", &model.Post{Id: "post1", ChannelId: "channelID", UserId: "author", Message: "#### #dev-Jan06 1) Release status\n- QA is blocked"})
+	postsFor("#dev-Jan13", &model.Post{Id: "post2", ChannelId: "channelID", UserId: "author", Message: "#### #dev-Jan13 1) Retro"})
+	postsFor("#dev-Jan")
+
+	p := Plugin{}
+	p.SetAPI(api)
+
+	export, err := p.exportAgenda("channelID", "member", time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2022, 1, 31, 0, 0, 0, 0, time.UTC))
+	assert.Nil(t, err)
+	assert.Equal(t, "Dev", export.Meeting)
+	assert.Equal(t, []*ExportedItem{
+		{Date: "2022-01-06", Hashtag: "#dev-Jan06", Number: 1, Title: "Release status", Details: "- QA is blocked", Author: "alice", Status: itemStatusDiscussed, Link: "post1"},
+		{Date: "2022-01-13", Hashtag: "#dev-Jan13", Number: 1, Title: "Retro", Author: "alice", Status: itemStatusCancelled, Link: "post2"},
+	}, export.Items)
+
+	_, err = p.exportAgenda("channelID", "member", time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	hashtagMigrationKeyPrefix = "hashtag_migration_"
+	// hashtagMigrationExpiry is how long a migration keeps other migrations of the channel from starting, in seconds
+	hashtagMigrationExpiry = 60 * 60
+	// hashtagMigrationProgressInterval is the number of meetings checked between progress messages
+	hashtagMigrationProgressInterval = 10
+)
+
+// HashtagMigration records the items of a meeting occurrence moved to the new hashtag format
+type HashtagMigration struct {
+	Date  string
+	From  string
+	To    string
+	Items int
+}
+
+// lockHashtagMigration returns false if a migration is already running for the channel
+func (p *Plugin) lockHashtagMigration(channelID string) (bool, error) {
+	locked, appErr := p.API.KVSetWithOptions(hashtagMigrationKeyPrefix+channelID, []byte(time.Now().Format(time.RFC3339)), model.PluginKVSetOptions{
+		Atomic:          true,
+		OldValue:        nil,
+		ExpireInSeconds: hashtagMigrationExpiry,
+	})
+	if appErr != nil {
+		return false, appErr
+	}
+
+	return locked, nil
+}
+
+// migrateHashtags moves the items queued for the upcoming meetings under the hashtags of the
+// previous format to the hashtags of the new format, after the items already queued under them.
+func (p *Plugin) migrateHashtags(previous, meeting *Meeting, now time.Time, progress func(checked, total, moved int)) ([]*HashtagMigration, error) {
+	occurrences := previous.occurrencesBetween(now, now.AddDate(1, 0, 0))
+
+	var migrations []*HashtagMigration
+	moved := 0
+	migrated := map[string]bool{}
+	for i, start := range occurrences {
+		if i > 0 && i%hashtagMigrationProgressInterval == 0 {
+			progress(i, len(occurrences), moved)
+		}
+
+		from := previous.hashtagForDate(start)
+		to := meeting.hashtagForDate(start)
+		// Formats without a date render the same hashtag for every meeting, which is only migrated once
+		if from == to || migrated[from] {
+			continue
+		}
+		migrated[from] = true
+
+		items, err := p.getAgendaItems(previous, "", "", from)
+		if err != nil {
+			return migrations, errors.Wrapf(err, "failed to get the items of %s", from)
+		}
+		if len(items) == 0 {
+			continue
+		}
+
+		queued, err := p.getAgendaItems(meeting, "", "", to)
+		if err != nil {
+			return migrations, errors.Wrapf(err, "failed to get the items of %s", to)
+		}
+
+		for j, item := range items {
+			post, appErr := p.API.GetPost(item.PostID)
+			if appErr != nil {
+				return migrations, appErr
+			}
+			post.Message = itemMessage(to, len(queued)+j+1, item.Message)
+			if _, appErr = p.API.UpdatePost(post); appErr != nil {
+				return migrations, errors.Wrap(appErr, "Error updating post")
+			}
+		}
+
+		moved += len(items)
+		migrations = append(migrations, &HashtagMigration{
+			Date:  start.Format(occurrenceDateFormat),
+			From:  from,
+			To:    to,
+			Items: len(items),
+		})
+	}
+
+	return migrations, nil
+}
+
+// runHashtagMigration migrates the hashtags of the meeting items in the background, keeping the
+// user who changed the format informed, and releases the migration lock of the channel when done.
+func (p *Plugin) runHashtagMigration(previous, meeting *Meeting, userID string) {
+	defer func() {
+		if appErr := p.API.KVDelete(hashtagMigrationKeyPrefix + meeting.ChannelID); appErr != nil {
+			p.API.LogWarn("Failed to release the hashtag migration lock", "error", appErr.Error(), "channel_id", meeting.ChannelID)
+		}
+	}()
+
+	notify := func(message string) {
+		p.API.SendEphemeralPost(userID, &model.Post{
+			UserId:    p.botID,
+			ChannelId: meeting.ChannelID,
+			Message:   message,
+		})
+	}
+
+	migrations, err := p.migrateHashtags(previous, meeting, time.Now(), func(checked, total, moved int) {
+		notify(fmt.Sprintf("Migrating hashtags: checked %d of %d meetings, moved %d items so far.", checked, total, moved))
+	})
+	if err != nil {
+		p.API.LogError("Failed to migrate hashtags", "error", err.Error(), "channel_id", meeting.ChannelID)
+		notify(fmt.Sprintf("The hashtag migration failed: %s\n%s", err.Error(), hashtagMigrationMessage(migrations)))
+		return
+	}
+
+	notify(hashtagMigrationMessage(migrations))
+}
+
+func hashtagMigrationMessage(migrations []*HashtagMigration) string {
+	if len(migrations) == 0 {
+		return "No items of the upcoming meetings needed to be migrated to the new hashtag format."
+	}
+
+	var sb strings.Builder
+	sb.WriteString("#### Hashtag migration finished\n")
+	for _, migration := range migrations {
+		fmt.Fprintf(&sb, "* %s: moved %d items from `%s` to `%s`\n", migration.Date, migration.Items, migration.From, migration.To)
+	}
+
+	return sb.String()
+}
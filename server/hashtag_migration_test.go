@@ -0,0 +1,92 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func searchingHashtag(hashtag string) interface{} {
+	return mock.MatchedBy(func(params []*model.SearchParams) bool {
+		return len(params) == 1 && strings.Contains(params[0].Terms, hashtag)
+	})
+}
+
+func TestPlugin_migrateHashtags(t *testing.T) {
+	previous := &Meeting{
+		ChannelID:     "channelID",
+		Schedule:      []time.Weekday{time.Thursday},
+		HashtagFormat: "dev-{{Jan02}}",
+		Timezone:      "UTC",
+	}
+	meeting := *previous
+	meeting.HashtagFormat = `dev-{{ .Date "2006.01.02" }}`
+
+	oldPosts := []*model.Post{
+		{Id: "post1", Message: "#### #dev-Jan06 1) First", CreateAt: 1},
+		{Id: "post2", Message: "#### #dev-Jan06 2) Second", CreateAt: 2},
+	}
+	newPosts := []*model.Post{
+		{Id: "post3", Message: "#### #dev-2022.01.06 1) Already migrated", CreateAt: 3},
+	}
+
+	api := &plugintest.API{}
+	api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Name: "dev", TeamId: "teamID"}, nil)
+	api.On("SearchPostsInTeam", "teamID", searchingHashtag("#dev-Jan06")).Return(oldPosts, nil)
+	api.On("SearchPostsInTeam", "teamID", searchingHashtag("#dev-2022.01.06")).Return(newPosts, nil)
+	api.On("SearchPostsInTeam", "teamID", mock.Anything).Return([]*model.Post{}, nil)
+	for _, post := range oldPosts {
+		api.On("GetPost", post.Id).Return(post.Clone(), nil)
+	}
+	api.On("UpdatePost", mock.Anything).Return(nil, nil)
+
+	p := Plugin{}
+	p.SetAPI(api)
+
+	var progress []int
+	// Monday
+	now := time.Date(2022, time.January, 3, 10, 0, 0, 0, time.UTC)
+	migrations, err := p.migrateHashtags(previous, &meeting, now, func(checked, total, moved int) {
+		assert.Equal(t, 2, moved)
+		progress = append(progress, checked)
+	})
+	assert.Nil(t, err)
+
+	assert.Equal(t, []*HashtagMigration{
+		{Date: "2022-01-06", From: "#dev-Jan06", To: "#dev-2022.01.06", Items: 2},
+	}, migrations)
+	api.AssertCalled(t, "UpdatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.Id == "post1" && post.Message == "#### #dev-2022.01.06 2) First"
+	}))
+	api.AssertCalled(t, "UpdatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.Id == "post2" && post.Message == "#### #dev-2022.01.06 3) Second"
+	}))
+	api.AssertNumberOfCalls(t, "UpdatePost", 2)
+	assert.Equal(t, []int{10, 20, 30, 40, 50}, progress)
+}
+
+func TestExecuteCommandSettingHashtagMigrationRunning(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("GetChannelMember", "channelID", "userID").Return(&model.ChannelMember{}, nil)
+	api.On("HasPermissionTo", "userID", model.PermissionManageSystem).Return(false)
+	api.On("KVGet", "channelID").Return([]byte(`{"channelId":"channelID","schedule":[4],"hashtagFormat":"dev-{{Jan02}}"}`), nil)
+	api.On("KVSetWithOptions", hashtagMigrationKeyPrefix+"channelID", mock.Anything, mock.Anything).Return(false, nil)
+	api.On("GetConfig").Return(&model.Config{})
+
+	p := Plugin{}
+	p.SetAPI(api)
+
+	resp := p.executeCommandSetting(&model.CommandArgs{
+		Command:   `/agenda setting hashtag dev-{{ .Date "Jan02" }} --migrate`,
+		ChannelId: "channelID",
+		UserId:    "userID",
+	})
+
+	assert.Contains(t, resp.Text, "already running")
+	api.AssertNotCalled(t, "KVSet", mock.Anything, mock.Anything)
+}
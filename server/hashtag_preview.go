@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+const (
+	// hashtagPreviewCount is the number of upcoming occurrences shown in a hashtag preview
+	hashtagPreviewCount = 5
+)
+
+// HashtagPreviewOccurrence is the hashtag of a meeting occurrence
+type HashtagPreviewOccurrence struct {
+	Date    string `json:"date"`
+	Hashtag string `json:"hashtag"`
+}
+
+// HashtagPreview shows the hashtags a format produces for the upcoming meeting occurrences
+type HashtagPreview struct {
+	Format      string                      `json:"format"`
+	Occurrences []*HashtagPreviewOccurrence `json:"occurrences"`
+	Problems    []string                    `json:"problems"`
+}
+
+// buildHashtagPreview renders the format for the next occurrences of the meeting the same way
+// agenda items are queued, and flags the hashtags Mattermost would not make searchable and
+// the hashtags that repeat within a year.
+func buildHashtagPreview(meeting *Meeting, format string, now time.Time, minLength int) *HashtagPreview {
+	previewMeeting := *meeting
+	previewMeeting.HashtagFormat = format
+
+	preview := &HashtagPreview{
+		Format:      format,
+		Occurrences: []*HashtagPreviewOccurrence{},
+		Problems:    []string{},
+	}
+
+	if len(previewMeeting.Schedule) == 0 {
+		preview.Problems = append(preview.Problems, "The meeting has no scheduled days")
+		return preview
+	}
+
+	if _, err := previewMeeting.hashtagRegex(); err != nil {
+		preview.Problems = append(preview.Problems, err.Error())
+		return preview
+	}
+
+	// Only the first problematic hashtag of each kind is reported, as a format usually
+	// produces the same problem for every occurrence.
+	var invalid, repeated bool
+	firstDates := map[string]string{}
+	for _, start := range previewMeeting.occurrencesBetween(now, now.AddDate(1, 0, 0)) {
+		hashtag, err := previewMeeting.renderHashtag(start)
+		if err != nil {
+			preview.Problems = append(preview.Problems, err.Error())
+			return preview
+		}
+		date := start.Format(occurrenceDateFormat)
+
+		if len(preview.Occurrences) < hashtagPreviewCount {
+			preview.Occurrences = append(preview.Occurrences, &HashtagPreviewOccurrence{
+				Date:    date,
+				Hashtag: hashtag,
+			})
+		}
+
+		if problem := hashtagProblem(hashtag, minLength); problem != "" && !invalid {
+			invalid = true
+			preview.Problems = append(preview.Problems, problem)
+		}
+
+		firstDate, ok := firstDates[hashtag]
+		if !ok {
+			firstDates[hashtag] = date
+		} else if !repeated {
+			repeated = true
+			preview.Problems = append(preview.Problems,
+				fmt.Sprintf("The hashtag %s repeats within a year, on %s and %s", hashtag, firstDate, date))
+		}
+	}
+
+	return preview
+}
+
+// minimumHashtagLength returns the minimum length of hashtags configured on the server
+func (p *Plugin) minimumHashtagLength() int {
+	if config := p.API.GetConfig(); config != nil && config.ServiceSettings.MinimumHashtagLength != nil {
+		return *config.ServiceSettings.MinimumHashtagLength
+	}
+	return minimumHashtagLength
+}
+
+func hashtagPreviewMessage(preview *HashtagPreview) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "#### Hashtag preview of `%s`\n", preview.Format)
+	for _, occurrence := range preview.Occurrences {
+		fmt.Fprintf(&sb, "* %s: `%s`\n", occurrence.Date, occurrence.Hashtag)
+	}
+
+	if len(preview.Problems) == 0 {
+		sb.WriteString("\nNo problems found.")
+		return sb.String()
+	}
+
+	sb.WriteString("\n**Problems:**\n")
+	for _, problem := range preview.Problems {
+		fmt.Fprintf(&sb, "* %s\n", problem)
+	}
+
+	return sb.String()
+}
+
+func (p *Plugin) executeCommandHashtagPreview(args *model.CommandArgs, format string) *model.CommandResponse {
+	if format == "" {
+		return responsef("Missing hashtag format to preview. Usage: `/agenda setting hashtag --preview <format>`")
+	}
+
+	meeting, err := p.GetMeeting(args.ChannelId)
+	if err != nil {
+		return responsef("Error getting meeting information for this channel")
+	}
+
+	meeting.HashtagFormat = format
+	if err = p.loadChannelName(meeting); err != nil {
+		return responsef("Error getting the channel of the meeting")
+	}
+
+	return responsef(hashtagPreviewMessage(buildHashtagPreview(meeting, format, time.Now(), p.minimumHashtagLength())))
+}
+
+func (p *Plugin) httpHashtagPreview(w http.ResponseWriter, r *http.Request) {
+	mattermostUserID := r.Header.Get("Mattermost-User-Id")
+	if mattermostUserID == "" {
+		http.Error(w, "Not Authorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	channelID := query.Get("channelId")
+	if !p.isChannelMember(channelID, mattermostUserID) {
+		http.Error(w, "Not Authorized", http.StatusForbidden)
+		return
+	}
+
+	meeting, err := p.GetMeeting(channelID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if format := query.Get("format"); format != "" {
+		meeting.HashtagFormat = format
+	}
+	if err = p.loadChannelName(meeting); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	p.writeJSON(w, buildHashtagPreview(meeting, meeting.HashtagFormat, time.Now(), p.minimumHashtagLength()))
+}
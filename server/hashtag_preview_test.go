@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildHashtagPreview(t *testing.T) {
+	meeting := &Meeting{
+		ChannelID: "myChannelId",
+		Schedule:  []time.Weekday{time.Thursday},
+		Timezone:  "UTC",
+	}
+	// Monday
+	now := time.Date(2022, time.January, 3, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		format   string
+		hashtags []string
+		problems int
+	}{
+		{
+			name:     "valid format",
+			format:   "dev-{{ Jan02 }}",
+			hashtags: []string{"#dev-Jan06", "#dev-Jan13", "#dev-Jan20", "#dev-Jan27", "#dev-Feb03"},
+			problems: 0,
+		},
+		{
+			name:     "illegal characters",
+			format:   "dev/{{ Jan02 }}",
+			hashtags: []string{"#dev/Jan06", "#dev/Jan13", "#dev/Jan20", "#dev/Jan27", "#dev/Feb03"},
+			problems: 1,
+		},
+		{
+			name:     "too short",
+			format:   "a{{ 2 }}",
+			hashtags: []string{"#a6", "#a13", "#a20", "#a27", "#a3"},
+			problems: 2,
+		},
+		{
+			name:     "repeats within a year",
+			format:   "dev-{{ Monday }}",
+			hashtags: []string{"#dev-Thursday", "#dev-Thursday", "#dev-Thursday", "#dev-Thursday", "#dev-Thursday"},
+			problems: 1,
+		},
+		{
+			name:     "no date",
+			format:   "dev",
+			hashtags: []string{"#dev", "#dev", "#dev", "#dev", "#dev"},
+			problems: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preview := buildHashtagPreview(meeting, tt.format, now, minimumHashtagLength)
+
+			var hashtags []string
+			for _, occurrence := range preview.Occurrences {
+				hashtags = append(hashtags, occurrence.Hashtag)
+			}
+			assert.Equal(t, tt.hashtags, hashtags)
+			assert.Equal(t, "2022-01-06", preview.Occurrences[0].Date)
+			assert.Len(t, preview.Problems, tt.problems, preview.Problems)
+		})
+	}
+
+	t.Run("invalid format", func(t *testing.T) {
+		preview := buildHashtagPreview(meeting, "dev-{{ .Unknown }}", now, minimumHashtagLength)
+		assert.Empty(t, preview.Occurrences)
+		assert.Len(t, preview.Problems, 1)
+		assert.Contains(t, preview.Problems[0], "invalid hashtag format")
+		assert.Contains(t, preview.Problems[0], "Unknown")
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		preview := buildHashtagPreview(meeting, `dev-{{ if .Year }}{{ .Date "Jan02" }}{{ end }}`, now, minimumHashtagLength)
+		assert.Empty(t, preview.Occurrences)
+		assert.Len(t, preview.Problems, 1)
+		assert.Contains(t, preview.Problems[0], "unsupported hashtag format")
+	})
+
+	t.Run("no schedule", func(t *testing.T) {
+		preview := buildHashtagPreview(&Meeting{}, "dev-{{ Jan02 }}", now, minimumHashtagLength)
+		assert.Empty(t, preview.Occurrences)
+		assert.Len(t, preview.Problems, 1)
+	})
+
+	t.Run("does not change the meeting", func(t *testing.T) {
+		buildHashtagPreview(meeting, "other-{{ Jan02 }}", now, minimumHashtagLength)
+		assert.Equal(t, "", meeting.HashtagFormat)
+	})
+}
+
+func TestServeHTTPHashtagPreview(t *testing.T) {
+	plugin := Plugin{}
+	api := &plugintest.API{}
+	plugin.SetAPI(api)
+
+	jsonMeeting, err := json.Marshal(&Meeting{
+		ChannelID:     "myChannelId",
+		Schedule:      []time.Weekday{time.Thursday},
+		HashtagFormat: "dev-{{ Jan02 }}",
+	})
+	assert.Nil(t, err)
+
+	minimumLength := 5
+	api.On("KVGet", "myChannelId").Return(jsonMeeting, nil)
+	api.On("GetChannelMember", "myChannelId", "member").Return(&model.ChannelMember{}, nil)
+	api.On("GetChannelMember", "myChannelId", "outsider").Return(nil, model.NewAppError("GetChannelMember", "app.channel.get_member.missing.app_error", nil, "", http.StatusNotFound))
+	api.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{MinimumHashtagLength: &minimumLength}})
+
+	t.Run("preview the given format", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/hashtag-preview?channelId=myChannelId&format=ab{{2}}", nil)
+		r.Header.Add("Mattermost-User-Id", "member")
+		w := httptest.NewRecorder()
+		plugin.ServeHTTP(nil, w, r)
+
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		var preview HashtagPreview
+		assert.Nil(t, json.NewDecoder(w.Result().Body).Decode(&preview))
+		assert.Equal(t, "ab{{2}}", preview.Format)
+		assert.Len(t, preview.Occurrences, hashtagPreviewCount)
+		assert.Contains(t, preview.Problems[0], "shorter than the minimum of 5 characters")
+	})
+
+	t.Run("preview the current format", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/hashtag-preview?channelId=myChannelId", nil)
+		r.Header.Add("Mattermost-User-Id", "member")
+		w := httptest.NewRecorder()
+		plugin.ServeHTTP(nil, w, r)
+
+		var preview HashtagPreview
+		assert.Nil(t, json.NewDecoder(w.Result().Body).Decode(&preview))
+		assert.Equal(t, "dev-{{ Jan02 }}", preview.Format)
+		assert.Empty(t, preview.Problems)
+	})
+
+	t.Run("not a channel member", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/hashtag-preview?channelId=myChannelId", nil)
+		r.Header.Add("Mattermost-User-Id", "outsider")
+		w := httptest.NewRecorder()
+		plugin.ServeHTTP(nil, w, r)
+
+		assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+	})
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// occurrenceCounterEpoch is the date the occurrences are counted from by the meetings without a
+// CounterStart, i.e. the unsaved meetings using the default hashtag format of the plugin
+const occurrenceCounterEpoch = "2022-01-03"
+
+// hashtagTemplateData are the placeholders available to the hashtag format of a meeting,
+// i.e. {{ .ChannelName }}-{{ .Date "Jan02" }} or {{ .MeetingName }}-w{{ .ISOWeek }}-{{ .Year }}
+type hashtagTemplateData struct {
+	ChannelName string
+	MeetingName string
+	ISOWeek     int
+	Year        int
+	// Occurrence is the number of the meeting, counted from the first meeting that used it
+	Occurrence int
+
+	date time.Time
+}
+
+// Date formats the meeting date with a Go time layout
+func (d *hashtagTemplateData) Date(layout string) string {
+	return d.date.Format(layout)
+}
+
+// hashtagTemplate returns the hashtag format as a template. Legacy formats with a single
+// {{ layout }} block, i.e. dev-{{ Jan02 }}, are converted to {{ .Date "layout" }}.
+func hashtagTemplate(format string) string {
+	if strings.Count(format, "{{") != 1 {
+		return format
+	}
+	if matchGroups := meetingDateFormatRegex.FindStringSubmatch(format); len(matchGroups) == 4 {
+		layout := strings.TrimSpace(matchGroups[2])
+		if !strings.HasPrefix(layout, ".") {
+			return matchGroups[1] + "{{ .Date " + strconv.Quote(layout) + " }}" + matchGroups[3]
+		}
+	}
+
+	return format
+}
+
+func parseHashtagTemplate(format string) (*template.Template, error) {
+	tmpl, err := template.New("hashtag").Option("missingkey=error").Parse(hashtagTemplate(format))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid hashtag format")
+	}
+
+	return tmpl, nil
+}
+
+// usesHashtagPlaceholder returns true if the hashtag format of the meeting uses the given placeholder
+func (m *Meeting) usesHashtagPlaceholder(name string) bool {
+	return strings.Contains(m.HashtagFormat, "."+name)
+}
+
+// occurrenceNumber returns the running number of the meeting occurrence on the given date.
+// The occurrence on CounterStart, or else on occurrenceCounterEpoch, is number 1. Cancelled
+// occurrences are counted, so that cancelling a meeting doesn't renumber the items queued
+// for the next ones.
+func (m *Meeting) occurrenceNumber(date time.Time) int {
+	counterStart, err := time.ParseInLocation(occurrenceDateFormat, m.CounterStart, date.Location())
+	if err != nil {
+		counterStart, _ = time.ParseInLocation(occurrenceDateFormat, occurrenceCounterEpoch, date.Location())
+		// Count from the first meeting day on or after the epoch
+		for i := 0; i < 7 && !m.isScheduled(counterStart.Weekday()); i++ {
+			counterStart = counterStart.AddDate(0, 0, 1)
+		}
+	}
+
+	if date.Before(counterStart) {
+		return 1 - countOccurrences(m.Schedule, date, counterStart)
+	}
+
+	return 1 + countOccurrences(m.Schedule, counterStart, date)
+}
+
+// renderHashtag renders the hashtag of the meeting occurrence on the given date
+func (m *Meeting) renderHashtag(date time.Time) (string, error) {
+	tmpl, err := parseHashtagTemplate(m.HashtagFormat)
+	if err != nil {
+		return "", err
+	}
+
+	year, week := date.ISOWeek()
+	data := &hashtagTemplateData{
+		ChannelName: m.ChannelName,
+		MeetingName: m.Name,
+		ISOWeek:     week,
+		Year:        year,
+		date:        date,
+	}
+	if m.usesHashtagPlaceholder("Occurrence") {
+		data.Occurrence = m.occurrenceNumber(date)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("#")
+	if err = tmpl.Execute(&sb, data); err != nil {
+		return "", errors.Wrap(err, "invalid hashtag format")
+	}
+
+	return sb.String(), nil
+}
+
+// hashtagRegex returns a regular expression matching every hashtag the format of the meeting can render
+func (m *Meeting) hashtagRegex() (string, error) {
+	tmpl, err := parseHashtagTemplate(m.HashtagFormat)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("#")
+	for _, node := range tmpl.Tree.Root.Nodes {
+		switch node := node.(type) {
+		case *parse.TextNode:
+			sb.WriteString(regexp.QuoteMeta(string(node.Text)))
+		case *parse.ActionNode:
+			sb.WriteString(m.placeholderRegex(node))
+		default:
+			return "", errors.Errorf("unsupported hashtag format %s", node.String())
+		}
+	}
+
+	return sb.String(), nil
+}
+
+func (m *Meeting) placeholderRegex(node *parse.ActionNode) string {
+	if len(node.Pipe.Cmds) == 1 && len(node.Pipe.Cmds[0].Args) > 0 {
+		if field, ok := node.Pipe.Cmds[0].Args[0].(*parse.FieldNode); ok && len(field.Ident) == 1 {
+			switch field.Ident[0] {
+			case "ChannelName":
+				return regexp.QuoteMeta(m.ChannelName)
+			case "MeetingName":
+				return regexp.QuoteMeta(m.Name)
+			case "ISOWeek", "Year":
+				return `[0-9]+`
+			case "Occurrence":
+				return `-?[0-9]+`
+			}
+		}
+	}
+
+	return `[^\n]+?`
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashtagTemplate(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{format: "dev-{{ Jan02 }}", want: `dev-{{ .Date "Jan02" }}`},
+		{format: "{{Jan 2}}-dev", want: `{{ .Date "Jan 2" }}-dev`},
+		{format: `dev-{{ .Date "Jan02" }}`, want: `dev-{{ .Date "Jan02" }}`},
+		{format: "{{ .MeetingName }}-{{ .Year }}", want: "{{ .MeetingName }}-{{ .Year }}"},
+		{format: "dev", want: "dev"},
+		{format: `dev-{{ if .Year }}{{ .Year }}{{ end }}`, want: `dev-{{ if .Year }}{{ .Year }}{{ end }}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			assert.Equal(t, tt.want, hashtagTemplate(tt.format))
+		})
+	}
+}
+
+func TestMeeting_renderHashtag(t *testing.T) {
+	// Thursday of ISO week 1
+	date := time.Date(2022, time.January, 6, 15, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		meeting Meeting
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "legacy format",
+			meeting: Meeting{HashtagFormat: "dev-{{ Jan02 }}"},
+			want:    "#dev-Jan06",
+		},
+		{
+			name:    "date",
+			meeting: Meeting{HashtagFormat: `dev-{{ .Date "2006.01.02" }}`},
+			want:    "#dev-2022.01.06",
+		},
+		{
+			name:    "names",
+			meeting: Meeting{HashtagFormat: `{{ .ChannelName }}-{{ .MeetingName }}-{{ .Date "Jan02" }}`, ChannelName: "town-square", Name: "standup"},
+			want:    "#town-square-standup-Jan06",
+		},
+		{
+			name:    "week and year",
+			meeting: Meeting{HashtagFormat: "dev-{{ .Year }}w{{ .ISOWeek }}"},
+			want:    "#dev-2022w1",
+		},
+		{
+			name: "occurrence",
+			meeting: Meeting{
+				HashtagFormat: "dev-{{ .Occurrence }}",
+				Schedule:      []time.Weekday{time.Monday, time.Thursday},
+				CounterStart:  "2021-12-20",
+				SkipDates:     []string{"2021-12-27"},
+			},
+			// Dec 20, Dec 23, the cancelled Dec 27, Dec 30, Jan 3 and Jan 6
+			want: "#dev-6",
+		},
+		{
+			name: "occurrence without counter start",
+			meeting: Meeting{
+				HashtagFormat: "dev-{{ .Occurrence }}",
+				Schedule:      []time.Weekday{time.Thursday},
+			},
+			// Counted from the first Thursday on or after the epoch
+			want: "#dev-1",
+		},
+		{
+			name:    "static",
+			meeting: Meeting{HashtagFormat: "dev-meeting"},
+			want:    "#dev-meeting",
+		},
+		{
+			name:    "unknown placeholder",
+			meeting: Meeting{HashtagFormat: "dev-{{ .Unknown }}"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid template",
+			meeting: Meeting{HashtagFormat: "dev-{{ .Date }"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.meeting.renderHashtag(date)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseMeetingPost(t *testing.T) {
+	date := time.Date(2022, time.January, 6, 15, 0, 0, 0, time.UTC)
+
+	meetings := []*Meeting{
+		{HashtagFormat: "dev-{{ Jan02 }}"},
+		{HashtagFormat: "{{January 02 2006}}-dev"},
+		{HashtagFormat: `{{ .ChannelName }}-{{ .Date "Jan02" }}`, ChannelName: "town-square"},
+		{HashtagFormat: `{{ .MeetingName }}.{{ .Year }}w{{ .ISOWeek }}.{{ .Date "Mon" }}`, Name: "standup"},
+		{HashtagFormat: "dev-{{ .Occurrence }}", Schedule: []time.Weekday{time.Thursday}, CounterStart: "2021-12-30"},
+		{HashtagFormat: "dev-meeting"},
+	}
+	for _, meeting := range meetings {
+		t.Run(meeting.HashtagFormat, func(t *testing.T) {
+			hashtag := meeting.hashtagForDate(date)
+			post := &model.Post{Message: itemMessage(hashtag, 3, "Discuss the release")}
+
+			parsed, err := parseMeetingPost(meeting, post)
+			assert.Nil(t, err)
+			assert.Equal(t, hashtag, parsed.hashtag)
+			assert.Equal(t, "3", parsed.number)
+			assert.Equal(t, "Discuss the release", parsed.textMessage)
+		})
+	}
+
+	t.Run("item of another meeting", func(t *testing.T) {
+		meeting := &Meeting{HashtagFormat: `{{ .MeetingName }}-{{ .Date "Jan02" }}`, Name: "standup"}
+		post := &model.Post{Message: "#### #retro-Jan06 1) Discuss the release"}
+
+		_, err := parseMeetingPost(meeting, post)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestPlugin_queuedItemSurvivesCancellation(t *testing.T) {
+	meeting := &Meeting{
+		ChannelID:     "channelID",
+		HashtagFormat: "dev-{{ .Occurrence }}",
+		Schedule:      []time.Weekday{time.Thursday},
+		Time:          "15:00",
+		Timezone:      "UTC",
+		CounterStart:  "2021-12-30",
+	}
+	thisWeek := time.Date(2022, 1, 6, 15, 0, 0, 0, time.UTC)
+	nextWeek := thisWeek.AddDate(0, 0, 7)
+	hashtag := meeting.hashtagForDate(nextWeek)
+	assert.Equal(t, "#dev-3", hashtag)
+
+	var posts []*model.Post
+	api := &plugintest.API{}
+	api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Name: "dev", TeamId: "teamID"}, nil)
+	api.On("SearchPostsInTeamForUser", "teamID", "userID", mock.Anything).Return(model.MakePostSearchResults(model.NewPostList(), nil), nil)
+	api.On("CreatePost", mock.Anything).Return(func(post *model.Post) *model.Post {
+		posts = append(posts, post)
+		return post
+	}, nil)
+	api.On("SearchPostsInTeam", "teamID", mock.MatchedBy(func(params []*model.SearchParams) bool {
+		return len(params) == 1 && params[0].Terms == "#dev-3" && params[0].IsHashtag
+	})).Return(func(string, []*model.SearchParams) []*model.Post {
+		return posts
+	}, nil)
+	api.On("KVGet", webhooksKeyPrefix+"channelID").Return(nil, nil)
+	p := Plugin{}
+	p.SetAPI(api)
+
+	_, _, err := p.queueAgendaItem(meeting, "teamID", "userID", "", hashtag, "Discuss the release")
+	assert.Nil(t, err)
+
+	// The meeting of this week is cancelled after the item was queued for the next one
+	assert.True(t, meeting.skipOccurrence(thisWeek.Format(occurrenceDateFormat)))
+	assert.Equal(t, hashtag, meeting.hashtagForDate(nextWeek))
+
+	items, err := p.getAgendaItems(meeting, "", "", meeting.hashtagForDate(nextWeek))
+	assert.Nil(t, err)
+	if assert.Len(t, items, 1) {
+		assert.Equal(t, "Discuss the release", items[0].Message)
+	}
+}
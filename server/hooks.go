@@ -0,0 +1,309 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	// hookTokenKeyPrefix prefixes the hash of an incoming hook token, and hookTokensKeyPrefix
+	// the tokens of a channel
+	hookTokenKeyPrefix  = "hook_token_"
+	hookTokensKeyPrefix = "hook_tokens_"
+
+	// maxHookRequestSize is the maximum size of the body of an incoming hook request
+	maxHookRequestSize = 64 * 1024
+
+	defaultHookTokenName = "External system"
+)
+
+// HookToken authenticates the requests of an external system queueing items on the meeting of a channel.
+// Only the hash of the token is stored.
+type HookToken struct {
+	ID        string `json:"id"`
+	ChannelID string `json:"channelId"`
+	Name      string `json:"name"`
+	Hash      string `json:"hash"`
+	CreatedBy string `json:"createdBy"`
+	CreateAt  int64  `json:"createAt"`
+}
+
+// HookRequest is the body of an incoming hook request
+type HookRequest struct {
+	Message string `json:"message"`
+	// Day is the meeting day of the item, `next-week` or a weekday. Defaults to the next meeting.
+	Day      string   `json:"day,omitempty"`
+	Source   string   `json:"source,omitempty"`
+	Duration string   `json:"duration,omitempty"`
+	Labels   []string `json:"labels,omitempty"`
+}
+
+// HookResponse is the response to an incoming hook request
+type HookResponse struct {
+	Number  int    `json:"number"`
+	Hashtag string `json:"hashtag"`
+	Date    string `json:"date"`
+	PostID  string `json:"postId"`
+}
+
+// hashHookToken returns the hash identifying a token in the KV store
+func hashHookToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// getHookTokens returns the tokens of the channel
+func (p *Plugin) getHookTokens(channelID string) ([]*HookToken, error) {
+	tokensBytes, appErr := p.API.KVGet(hookTokensKeyPrefix + channelID)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if tokensBytes == nil {
+		return nil, nil
+	}
+
+	var tokens []*HookToken
+	if err := json.Unmarshal(tokensBytes, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// saveHookTokens stores the tokens of the channel
+func (p *Plugin) saveHookTokens(channelID string, tokens []*HookToken) error {
+	tokensBytes, err := json.Marshal(tokens)
+	if err != nil {
+		return err
+	}
+	if appErr := p.API.KVSet(hookTokensKeyPrefix+channelID, tokensBytes); appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+// createHookToken creates a token for the channel. It returns the token, which is not stored.
+func (p *Plugin) createHookToken(channelID, userID, name string) (*HookToken, string, error) {
+	tokens, err := p.getHookTokens(channelID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := newWebhookSecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	hookToken := &HookToken{
+		ID:        model.NewId(),
+		ChannelID: channelID,
+		Name:      name,
+		Hash:      hashHookToken(token),
+		CreatedBy: userID,
+		CreateAt:  model.GetMillis(),
+	}
+
+	hookTokenBytes, err := json.Marshal(hookToken)
+	if err != nil {
+		return nil, "", err
+	}
+	if appErr := p.API.KVSet(hookTokenKeyPrefix+hookToken.Hash, hookTokenBytes); appErr != nil {
+		return nil, "", appErr
+	}
+	if err = p.saveHookTokens(channelID, append(tokens, hookToken)); err != nil {
+		return nil, "", err
+	}
+
+	return hookToken, token, nil
+}
+
+// revokeHookToken deletes the token of the channel with the given ID
+func (p *Plugin) revokeHookToken(channelID, id string) (*HookToken, error) {
+	tokens, err := p.getHookTokens(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, hookToken := range tokens {
+		if hookToken.ID != id {
+			continue
+		}
+		if appErr := p.API.KVDelete(hookTokenKeyPrefix + hookToken.Hash); appErr != nil {
+			return nil, appErr
+		}
+		if err = p.saveHookTokens(channelID, append(tokens[:i], tokens[i+1:]...)); err != nil {
+			return nil, err
+		}
+		return hookToken, nil
+	}
+
+	return nil, errors.Errorf("no token %s exists for this channel", id)
+}
+
+// hookToken returns the stored token matching the given token, or nil if there is none
+func (p *Plugin) hookToken(token string) (*HookToken, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	hookTokenBytes, appErr := p.API.KVGet(hookTokenKeyPrefix + hashHookToken(token))
+	if appErr != nil {
+		return nil, appErr
+	}
+	if hookTokenBytes == nil {
+		return nil, nil
+	}
+
+	var hookToken *HookToken
+	if err := json.Unmarshal(hookTokenBytes, &hookToken); err != nil {
+		return nil, err
+	}
+	return hookToken, nil
+}
+
+// hookItemMessage returns the message of an item queued from an external system, attributed to its source
+func hookItemMessage(message, source string) string {
+	return fmt.Sprintf("%s\n\n_Queued from %s_", strings.TrimSpace(message), source)
+}
+
+func (p *Plugin) executeCommandToken(args *model.CommandArgs) *model.CommandResponse {
+	split := strings.Fields(args.Command)
+	if len(split) < 3 {
+		return responsef("Missing action. Usage: `/agenda token create [name]|list|revoke <id>`")
+	}
+
+	if !p.isChannelAdmin(args.ChannelId, args.UserId) {
+		return responsef("Only channel admins can manage the tokens of this channel")
+	}
+
+	switch split[2] {
+	case "create":
+		siteURL := p.siteURL()
+		if siteURL == "" {
+			return responsef("Incoming hooks require the Site URL of the server to be configured")
+		}
+
+		name := strings.Join(split[3:], " ")
+		if name == "" {
+			name = defaultHookTokenName
+		}
+
+		hookToken, token, err := p.createHookToken(args.ChannelId, args.UserId, name)
+		if err != nil {
+			return responsef("Error creating the token")
+		}
+
+		return responsef("Created token `%s` for %s. External systems can queue items on the meeting of this channel with:\n"+
+			"```\ncurl -X POST -H 'Content-Type: application/json' -d '{\"message\": \"Review failed release\"}' %s/plugins/%s/api/v1/hooks/%s\n```\n"+
+			"Store the URL now, the token will not be shown again.", hookToken.ID, hookToken.Name, siteURL, Manifest.Id, token)
+
+	case "list":
+		tokens, err := p.getHookTokens(args.ChannelId)
+		if err != nil {
+			return responsef("Error getting the tokens of this channel")
+		}
+		if len(tokens) == 0 {
+			return responsef("No tokens exist for this channel.")
+		}
+		lines := []string{"#### Tokens of this channel"}
+		for _, hookToken := range tokens {
+			lines = append(lines, fmt.Sprintf("* `%s` %s", hookToken.ID, hookToken.Name))
+		}
+		return responsef(strings.Join(lines, "\n"))
+
+	case "revoke":
+		if len(split) < 4 {
+			return responsef("Missing token ID. Usage: `/agenda token revoke <id>`")
+		}
+		hookToken, err := p.revokeHookToken(args.ChannelId, split[3])
+		if err != nil {
+			return responsef("Error revoking the token: %s", err.Error())
+		}
+		return responsef("Revoked token `%s` for %s.", hookToken.ID, hookToken.Name)
+	}
+
+	return responsef("Unknown token action: %s", split[2])
+}
+
+// httpIncomingHook queues the item of the request on the meeting of the channel of the token,
+// posted by the Agenda bot:
+//
+//	POST /api/v1/hooks/{token}
+func (p *Plugin) httpIncomingHook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Request: "+r.Method+" is not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	hookToken, err := p.hookToken(strings.TrimPrefix(r.URL.Path, "/api/v1/hooks/"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if hookToken == nil {
+		http.Error(w, "Not Authorized", http.StatusUnauthorized)
+		return
+	}
+
+	var request *HookRequest
+	if err = json.NewDecoder(io.LimitReader(r.Body, maxHookRequestSize)).Decode(&request); err != nil || request == nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(request.Message) == "" {
+		http.Error(w, "Missing message", http.StatusBadRequest)
+		return
+	}
+
+	nextWeek, weekday := false, -1
+	if request.Day != "" {
+		if nextWeek, weekday = parseQueueDay(request.Day); !nextWeek && weekday == -1 {
+			http.Error(w, "Invalid day. Must be a weekday or next-week", http.StatusBadRequest)
+			return
+		}
+	}
+
+	duration, err := parseOptionalDuration(request.Duration)
+	if err != nil {
+		http.Error(w, "Invalid duration", http.StatusBadRequest)
+		return
+	}
+
+	source := strings.TrimSpace(request.Source)
+	if source == "" {
+		source = hookToken.Name
+	}
+
+	meeting, err := p.GetMeeting(hookToken.ChannelID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	result, err := p.queueForOccurrence(meeting, &queueRequest{
+		OwnerID:  p.botID,
+		Message:  hookItemMessage(request.Message, source),
+		Duration: duration,
+		Labels:   request.Labels,
+		Source:   source,
+	}, nextWeek, weekday, false)
+	if err != nil {
+		p.API.LogError("Failed to queue item from incoming hook", "error", err.Error(), "channel_id", hookToken.ChannelID, "token_id", hookToken.ID)
+		p.writeItemError(w, err)
+		return
+	}
+
+	p.writeJSON(w, &HookResponse{
+		Number:  result.Item.Number,
+		Hashtag: result.Hashtag,
+		Date:    result.Start.Format(occurrenceDateFormat),
+		PostID:  result.Item.PostID,
+	})
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeHTTPIncomingHook(t *testing.T) {
+	hookTokenBytes, _ := json.Marshal(&HookToken{ID: "token1", ChannelID: "channelID", Name: "CI", Hash: hashHookToken("secret")})
+
+	setupAPI := func() *plugintest.API {
+		api := &plugintest.API{}
+		api.On("KVGet", hookTokenKeyPrefix+hashHookToken("secret")).Return(hookTokenBytes, nil)
+		api.On("KVGet", hookTokenKeyPrefix+hashHookToken("unknown")).Return(nil, nil)
+		api.On("KVGet", "channelID").Return([]byte(`{"channelId":"channelID","schedule":[0,1,2,3,4,5,6],"hashtagFormat":"dev-{{Jan02}}"}`), nil)
+		api.On("KVGet", webhooksKeyPrefix+"channelID").Return(nil, nil)
+		api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Name: "dev", TeamId: "teamID"}, nil)
+		api.On("GetConfig").Return(&model.Config{})
+		api.On("SearchPostsInTeam", "teamID", mock.Anything).Return([]*model.Post{}, nil)
+		api.On("CreatePost", mock.Anything).Return(&model.Post{Id: "item1"}, nil)
+		return api
+	}
+
+	t.Run("queued by the bot", func(t *testing.T) {
+		api := setupAPI()
+		p := Plugin{botID: "botID"}
+		p.SetAPI(api)
+
+		w := httptest.NewRecorder()
+		body := `{"message": "Review failed release", "source": "Release pipeline", "labels": ["release"]}`
+		p.ServeHTTP(nil, w, httptest.NewRequest(http.MethodPost, "/api/v1/hooks/secret", strings.NewReader(body)))
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response HookResponse
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 1, response.Number)
+		assert.Equal(t, "item1", response.PostID)
+		api.AssertCalled(t, "CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.UserId == "botID" && post.ChannelId == "channelID" &&
+				strings.HasSuffix(post.Message, " 1) Review failed release\n\n_Queued from Release pipeline_") &&
+				post.GetProp(itemSourceProp) == "Release pipeline"
+		}))
+	})
+
+	tests := []struct {
+		name string
+		path string
+		body string
+		want int
+	}{
+		{name: "unknown token", path: "/api/v1/hooks/unknown", body: `{"message": "Review"}`, want: http.StatusUnauthorized},
+		{name: "missing token", path: "/api/v1/hooks/", body: `{"message": "Review"}`, want: http.StatusUnauthorized},
+		{name: "missing message", path: "/api/v1/hooks/secret", body: `{"message": " "}`, want: http.StatusBadRequest},
+		{name: "invalid day", path: "/api/v1/hooks/secret", body: `{"message": "Review", "day": "someday"}`, want: http.StatusBadRequest},
+		{name: "invalid duration", path: "/api/v1/hooks/secret", body: `{"message": "Review", "duration": "long"}`, want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := setupAPI()
+			p := Plugin{botID: "botID"}
+			p.SetAPI(api)
+
+			w := httptest.NewRecorder()
+			p.ServeHTTP(nil, w, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body)))
+			assert.Equal(t, tt.want, w.Code)
+			api.AssertNotCalled(t, "CreatePost", mock.Anything)
+		})
+	}
+}
+
+func TestPlugin_revokeHookToken(t *testing.T) {
+	tokensBytes, _ := json.Marshal([]*HookToken{{ID: "token1", Hash: "hash1"}, {ID: "token2", Hash: "hash2"}})
+
+	api := &plugintest.API{}
+	api.On("KVGet", hookTokensKeyPrefix+"channelID").Return(tokensBytes, nil)
+	api.On("KVDelete", hookTokenKeyPrefix+"hash1").Return(nil)
+	api.On("KVSet", hookTokensKeyPrefix+"channelID", mock.Anything).Return(nil)
+	p := Plugin{}
+	p.SetAPI(api)
+
+	revoked, err := p.revokeHookToken("channelID", "token1")
+	assert.Nil(t, err)
+	assert.Equal(t, "token1", revoked.ID)
+	api.AssertCalled(t, "KVSet", hookTokensKeyPrefix+"channelID", mock.MatchedBy(func(value []byte) bool {
+		var tokens []*HookToken
+		return json.Unmarshal(value, &tokens) == nil && len(tokens) == 1 && tokens[0].ID == "token2"
+	}))
+
+	_, err = p.revokeHookToken("channelID", "token3")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+)
+
+// maxICSFileSize is the maximum size of the calendar files imported
+const maxICSFileSize = 1024 * 1024
+
+// icsProperty is a content line of an iCalendar file, i.e. `DTSTART;TZID=Europe/Paris:20220106T153000`
+type icsProperty struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// parseICSProperties unfolds the lines of an iCalendar file and parses them as properties,
+// skipping the lines that are not properties.
+func parseICSProperties(data string) []icsProperty {
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	data = strings.ReplaceAll(data, "\n ", "")
+	data = strings.ReplaceAll(data, "\n\t", "")
+
+	var properties []icsProperty
+	for _, line := range strings.Split(data, "\n") {
+		// The value starts at the first colon outside of quoted parameter values
+		quoted := false
+		separator := -1
+		for i, r := range line {
+			if r == '"' {
+				quoted = !quoted
+			} else if r == ':' && !quoted {
+				separator = i
+				break
+			}
+		}
+		if separator < 1 {
+			continue
+		}
+
+		parts := strings.Split(line[:separator], ";")
+		property := icsProperty{
+			Name:   strings.ToUpper(strings.TrimSpace(parts[0])),
+			Params: map[string]string{},
+			Value:  strings.TrimSpace(line[separator+1:]),
+		}
+		for _, param := range parts[1:] {
+			if key, value, found := strings.Cut(param, "="); found {
+				property.Params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+			}
+		}
+		properties = append(properties, property)
+	}
+
+	return properties
+}
+
+// icsSchedule is the schedule of a recurring event of an iCalendar file
+type icsSchedule struct {
+	Schedule []time.Weekday
+	// Time is empty for all-day events, and Timezone for events in floating time
+	Time      string
+	Timezone  string
+	SkipDates []string
+}
+
+// parseICSTime parses a date or date-time property value. Floating times are returned in UTC.
+func parseICSTime(property icsProperty) (time.Time, bool, error) {
+	value := property.Value
+	if property.Params["VALUE"] == "DATE" || len(value) == len(icsDateFormat) {
+		day, err := time.Parse(icsDateFormat, value)
+		return day, true, err
+	}
+
+	loc := time.UTC
+	if tzid := property.Params["TZID"]; tzid != "" && !strings.HasSuffix(value, "Z") {
+		var err error
+		if loc, err = icsLocation(tzid); err != nil {
+			return time.Time{}, false, err
+		}
+	}
+
+	t, err := time.ParseInLocation(icsDateTimeFormat, strings.TrimSuffix(value, "Z"), loc)
+	return t, false, err
+}
+
+// icsLocation returns the location of a TZID parameter, which is an IANA timezone or,
+// in files exported by Outlook, a Windows timezone.
+func icsLocation(tzid string) (*time.Location, error) {
+	if loc, err := time.LoadLocation(tzid); err == nil {
+		return loc, nil
+	}
+	if name, ok := windowsTimezones[tzid]; ok {
+		return time.LoadLocation(name)
+	}
+	return nil, errors.Errorf("unknown timezone %s. Only IANA timezones, such as America/New_York, and standard Windows timezones, such as Eastern Standard Time, are supported", tzid)
+}
+
+// parseICSRecurrence returns the weekdays of a weekly recurrence rule, or of the start if the rule has none
+func parseICSRecurrence(rule string, start time.Time) ([]time.Weekday, error) {
+	parts := map[string]string{}
+	for _, part := range strings.Split(rule, ";") {
+		if key, value, found := strings.Cut(part, "="); found {
+			parts[strings.ToUpper(key)] = strings.ToUpper(value)
+		}
+	}
+
+	if parts["FREQ"] != "WEEKLY" {
+		return nil, errors.Errorf("only weekly meetings can be imported, the meeting repeats %s", strings.ToLower(parts["FREQ"]))
+	}
+	if interval := parts["INTERVAL"]; interval != "" && interval != "1" {
+		return nil, errors.Errorf("meetings repeating every %s weeks are not supported", interval)
+	}
+
+	if parts["BYDAY"] == "" {
+		return []time.Weekday{start.Weekday()}, nil
+	}
+
+	var schedule []time.Weekday
+	for _, day := range strings.Split(parts["BYDAY"], ",") {
+		// Weekly rules can't have ordinals such as 1MO, but be lenient with the files of other tools
+		if len(day) > 2 {
+			day = day[len(day)-2:]
+		}
+		found := false
+		for weekday, name := range icsWeekdays {
+			if name == day {
+				schedule = append(schedule, weekday)
+				found = true
+			}
+		}
+		if !found {
+			return nil, errors.Errorf("unknown day %s in the recurrence rule", day)
+		}
+	}
+
+	sort.Slice(schedule, func(i, j int) bool {
+		return schedule[i] < schedule[j]
+	})
+	return schedule, nil
+}
+
+// parseICSSchedule returns the schedule of the first recurring event of an iCalendar file
+func parseICSSchedule(data string) (*icsSchedule, error) {
+	var event []icsProperty
+	inEvent := false
+	for _, property := range parseICSProperties(data) {
+		switch {
+		case property.Name == "BEGIN" && strings.EqualFold(property.Value, "VEVENT"):
+			inEvent = true
+			event = nil
+		case property.Name == "END" && strings.EqualFold(property.Value, "VEVENT"):
+			inEvent = false
+			if schedule, ok, err := icsEventSchedule(event); ok || err != nil {
+				return schedule, err
+			}
+		case inEvent:
+			event = append(event, property)
+		}
+	}
+
+	return nil, errors.New("the file has no recurring event")
+}
+
+// icsEventSchedule returns the schedule of an event. ok is false if the event is not recurring.
+func icsEventSchedule(event []icsProperty) (schedule *icsSchedule, ok bool, err error) {
+	var start, rule *icsProperty
+	var exdates []icsProperty
+	for i, property := range event {
+		switch property.Name {
+		case "DTSTART":
+			start = &event[i]
+		case "RRULE":
+			rule = &event[i]
+		case "EXDATE":
+			exdates = append(exdates, property)
+		case "RECURRENCE-ID":
+			// The event modifies an occurrence of a recurring event
+			return nil, false, nil
+		}
+	}
+	if rule == nil {
+		return nil, false, nil
+	}
+	if start == nil {
+		return nil, true, errors.New("the recurring event has no start")
+	}
+
+	startTime, allDay, err := parseICSTime(*start)
+	if err != nil {
+		return nil, true, errors.Wrap(err, "invalid start of the recurring event")
+	}
+
+	schedule = &icsSchedule{}
+	if !allDay {
+		schedule.Time = startTime.Format(meetingTimeFormat)
+		if strings.HasSuffix(start.Value, "Z") {
+			schedule.Timezone = "UTC"
+		} else if tzid := start.Params["TZID"]; tzid != "" {
+			schedule.Timezone = startTime.Location().String()
+		}
+	}
+
+	if schedule.Schedule, err = parseICSRecurrence(rule.Value, startTime); err != nil {
+		return nil, true, err
+	}
+
+	for _, exdate := range exdates {
+		for _, value := range strings.Split(exdate.Value, ",") {
+			excluded, _, err := parseICSTime(icsProperty{Name: exdate.Name, Params: exdate.Params, Value: strings.TrimSpace(value)})
+			if err != nil {
+				return nil, true, errors.Wrap(err, "invalid excluded date")
+			}
+			// Cancelled occurrences are identified by their date in the meeting timezone
+			excluded = excluded.In(startTime.Location())
+			schedule.SkipDates = append(schedule.SkipDates, excluded.Format(occurrenceDateFormat))
+		}
+	}
+
+	return schedule, true, nil
+}
+
+// apply updates the schedule, time, timezone and cancelled occurrences of the meeting.
+// Cancelled occurrences before now are dropped.
+func (s *icsSchedule) apply(meeting *Meeting, now time.Time) {
+	meeting.Schedule = s.Schedule
+	meeting.Time = s.Time
+	if s.Timezone != "" {
+		meeting.Timezone = s.Timezone
+	}
+
+	today := now.In(meeting.location()).Format(occurrenceDateFormat)
+	skipDates := map[string]bool{}
+	for _, date := range append(meeting.SkipDates, s.SkipDates...) {
+		if date >= today {
+			skipDates[date] = true
+		}
+	}
+
+	meeting.SkipDates = nil
+	for date := range skipDates {
+		meeting.SkipDates = append(meeting.SkipDates, date)
+	}
+	sort.Strings(meeting.SkipDates)
+}
+
+// describe returns a description of the imported schedule for the user
+func (s *icsSchedule) describe(meeting *Meeting) string {
+	days := make([]string, 0, len(meeting.Schedule))
+	for _, weekday := range meeting.Schedule {
+		days = append(days, weekday.String())
+	}
+
+	description := strings.Join(days, ", ")
+	if meeting.Time != "" {
+		description += fmt.Sprintf(" at %s", meeting.Time)
+		if meeting.Timezone != "" {
+			description += " " + meeting.Timezone
+		}
+	}
+	if len(s.SkipDates) > 0 {
+		description += fmt.Sprintf(", with %d cancelled meetings", len(s.SkipDates))
+	}
+
+	return description
+}
+
+// importICS updates the meeting of the channel with the schedule of the calendar file.
+// It returns the description of the imported schedule.
+func (p *Plugin) importICS(channelID string, data []byte) (*Meeting, string, error) {
+	schedule, err := parseICSSchedule(string(data))
+	if err != nil {
+		return nil, "", err
+	}
+
+	meeting, err := p.GetMeeting(channelID)
+	if err != nil {
+		return nil, "", errors.New("failed to get the meeting information of the channel")
+	}
+
+	schedule.apply(meeting, time.Now())
+	if err = meeting.Validate(p.minimumHashtagLength()); err != nil {
+		return nil, "", err
+	}
+
+	if err = p.SaveMeeting(meeting); err != nil {
+		return nil, "", errors.New("failed to save the meeting settings")
+	}
+
+	return meeting, schedule.describe(meeting), nil
+}
+
+// icsFile returns the content of the calendar file with the given ID, or of the first calendar
+// file attached to the post with the given ID. The file must have been posted in the channel.
+func (p *Plugin) icsFile(channelID, id string) ([]byte, error) {
+	return p.attachedFile(channelID, id, "calendar", []string{"ics"}, maxICSFileSize)
+}
+
+// attachedFile returns the content of the file with the given ID, or of the first file with one of
+// the extensions attached to the post with the given ID. The file must have been posted in the channel.
+func (p *Plugin) attachedFile(channelID, id, kind string, extensions []string, maxSize int64) ([]byte, error) {
+	fileID := id
+	info, appErr := p.API.GetFileInfo(id)
+	if appErr != nil {
+		post, appErr := p.API.GetPost(id)
+		if appErr != nil {
+			return nil, errors.New("the file could not be found")
+		}
+		for _, postFileID := range post.FileIds {
+			if postInfo, appErr := p.API.GetFileInfo(postFileID); appErr == nil && hasExtension(postInfo, extensions) {
+				info = postInfo
+				fileID = postFileID
+				break
+			}
+		}
+		if info == nil {
+			return nil, errors.Errorf("the message has no %s file attached", kind)
+		}
+	}
+
+	post, appErr := p.API.GetPost(info.PostId)
+	if appErr != nil || post.ChannelId != channelID {
+		return nil, errors.New("the file must be attached to a message of this channel")
+	}
+	if info.Size > maxSize {
+		return nil, errors.Errorf("the file is larger than %d KB", maxSize/1024)
+	}
+
+	data, appErr := p.API.GetFile(fileID)
+	if appErr != nil {
+		return nil, errors.New("the file could not be read")
+	}
+
+	return data, nil
+}
+
+// hasExtension returns true if the file has one of the extensions
+func hasExtension(info *model.FileInfo, extensions []string) bool {
+	for _, extension := range extensions {
+		if strings.EqualFold(info.Extension, extension) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Plugin) executeCommandImportICS(args *model.CommandArgs) *model.CommandResponse {
+	split := strings.Fields(args.Command)
+	if len(split) < 3 {
+		return responsef("Missing file. Usage: `/agenda import-ics <fileId or message link>`")
+	}
+
+	if !p.isChannelAdmin(args.ChannelId, args.UserId) {
+		return responsef("Only channel admins can import the meeting schedule of this channel")
+	}
+	if !p.canManageMeeting(args.ChannelId, args.UserId) {
+		return responsef("You do not have permission to change the meeting settings of this channel")
+	}
+
+	id, err := parsePermalink(split[2])
+	if err != nil {
+		return responsef(err.Error())
+	}
+
+	data, err := p.icsFile(args.ChannelId, id)
+	if err != nil {
+		return responsef("Failed to import the calendar file: %s", err.Error())
+	}
+
+	_, description, err := p.importICS(args.ChannelId, data)
+	if err != nil {
+		return responsef("Failed to import the calendar file: %s", err.Error())
+	}
+
+	return responsef("Imported the meeting schedule: %s", description)
+}
+
+// httpImportICS imports the schedule of the calendar file in the body of the request:
+//
+//	POST /api/v1/import-ics?channelId=
+func (p *Plugin) httpImportICS(w http.ResponseWriter, r *http.Request) {
+	mattermostUserID := r.Header.Get("Mattermost-User-Id")
+	if mattermostUserID == "" {
+		http.Error(w, "Not Authorized", http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Request: "+r.Method+" is not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	channelID := r.URL.Query().Get("channelId")
+	if !p.isChannelAdmin(channelID, mattermostUserID) || !p.canManageMeeting(channelID, mattermostUserID) {
+		http.Error(w, "Not Authorized", http.StatusForbidden)
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxICSFileSize+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(data) > maxICSFileSize {
+		http.Error(w, fmt.Sprintf("The file is larger than %d KB", maxICSFileSize/1024), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	meeting, description, err := p.importICS(channelID, data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	p.writeJSON(w, map[string]interface{}{
+		"meeting":     meeting,
+		"description": description,
+	})
+}
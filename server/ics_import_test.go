@@ -0,0 +1,210 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseICSProperties(t *testing.T) {
+	properties := parseICSProperties("BEGIN:VEVENT\r\nSUMMARY:Weekly\r\n  sync\r\nATTENDEE;CN=\"Doe: Jane\":mailto:jane@example.com\r\nnot a property\r\nEND:VEVENT\r\n")
+
+	assert.Equal(t, []icsProperty{
+		{Name: "BEGIN", Params: map[string]string{}, Value: "VEVENT"},
+		{Name: "SUMMARY", Params: map[string]string{}, Value: "Weekly sync"},
+		{Name: "ATTENDEE", Params: map[string]string{"CN": "Doe: Jane"}, Value: "mailto:jane@example.com"},
+		{Name: "END", Params: map[string]string{}, Value: "VEVENT"},
+	}, properties)
+}
+
+func TestParseICSSchedule(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    *icsSchedule
+		wantErr string
+	}{
+		{
+			name: "weekly with timezone and excluded dates",
+			data: "BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART;TZID=Europe/Paris:20220103T153000\n" +
+				"RRULE:FREQ=WEEKLY;BYDAY=TH,MO\nEXDATE;TZID=Europe/Paris:20220110T153000,20220113T153000\n" +
+				"EXDATE:20220117T143000Z\nEND:VEVENT\nEND:VCALENDAR\n",
+			want: &icsSchedule{
+				Schedule:  []time.Weekday{time.Monday, time.Thursday},
+				Time:      "15:30",
+				Timezone:  "Europe/Paris",
+				SkipDates: []string{"2022-01-10", "2022-01-13", "2022-01-17"},
+			},
+		},
+		{
+			name: "all-day without days",
+			data: "BEGIN:VEVENT\nDTSTART;VALUE=DATE:20220106\nRRULE:FREQ=WEEKLY\nEND:VEVENT\n",
+			want: &icsSchedule{Schedule: []time.Weekday{time.Thursday}},
+		},
+		{
+			name: "UTC",
+			data: "BEGIN:VEVENT\nDTSTART:20220106T090000Z\nRRULE:FREQ=WEEKLY;INTERVAL=1;BYDAY=TH\nEND:VEVENT\n",
+			want: &icsSchedule{Schedule: []time.Weekday{time.Thursday}, Time: "09:00", Timezone: "UTC"},
+		},
+		{
+			name: "skips single events",
+			data: "BEGIN:VEVENT\nDTSTART:20220105T090000\nEND:VEVENT\nBEGIN:VEVENT\nDTSTART:20220106T090000\nRRULE:FREQ=WEEKLY\nEND:VEVENT\n",
+			want: &icsSchedule{Schedule: []time.Weekday{time.Thursday}, Time: "09:00"},
+		},
+		{
+			name:    "monthly",
+			data:    "BEGIN:VEVENT\nDTSTART:20220106T090000Z\nRRULE:FREQ=MONTHLY\nEND:VEVENT\n",
+			wantErr: "only weekly meetings can be imported, the meeting repeats monthly",
+		},
+		{
+			name:    "every other week",
+			data:    "BEGIN:VEVENT\nDTSTART:20220106T090000Z\nRRULE:FREQ=WEEKLY;INTERVAL=2\nEND:VEVENT\n",
+			wantErr: "meetings repeating every 2 weeks are not supported",
+		},
+		{
+			name: "Windows timezone",
+			data: "BEGIN:VEVENT\nDTSTART;TZID=Romance Standard Time:20220106T090000\nRRULE:FREQ=WEEKLY\n" +
+				"EXDATE;TZID=Romance Standard Time:20220113T090000\nEND:VEVENT\n",
+			want: &icsSchedule{Schedule: []time.Weekday{time.Thursday}, Time: "09:00", Timezone: "Europe/Paris", SkipDates: []string{"2022-01-13"}},
+		},
+		{
+			name:    "unknown timezone",
+			data:    "BEGIN:VEVENT\nDTSTART;TZID=Customized Time Zone:20220106T090000\nRRULE:FREQ=WEEKLY\nEND:VEVENT\n",
+			wantErr: "invalid start of the recurring event: unknown timezone Customized Time Zone. Only IANA timezones, such as America/New_York, and standard Windows timezones, such as Eastern Standard Time, are supported",
+		},
+		{
+			name:    "not recurring",
+			data:    "BEGIN:VEVENT\nDTSTART:20220106T090000Z\nEND:VEVENT\n",
+			wantErr: "the file has no recurring event",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseICSSchedule(tt.data)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseICSScheduleOfCalendarFeed(t *testing.T) {
+	meeting := &Meeting{
+		ChannelID: "channelID",
+		Schedule:  []time.Weekday{time.Tuesday, time.Friday},
+		Time:      "10:15",
+		Timezone:  "America/New_York",
+		SkipDates: []string{"2022-01-07"},
+	}
+	start := meeting.startTime(time.Date(2022, 1, 4, 0, 0, 0, 0, meeting.location()))
+
+	got, err := parseICSSchedule(buildCalendar(meeting, "Sync", start, []calendarOccurrence{{Start: start, Hashtag: "#sync"}}, start))
+	assert.Nil(t, err)
+	assert.Equal(t, &icsSchedule{
+		Schedule:  meeting.Schedule,
+		Time:      meeting.Time,
+		Timezone:  meeting.Timezone,
+		SkipDates: meeting.SkipDates,
+	}, got)
+}
+
+func TestICSSchedule_apply(t *testing.T) {
+	meeting := &Meeting{
+		Schedule:  []time.Weekday{time.Monday},
+		Time:      "09:00",
+		Timezone:  "Europe/Paris",
+		SkipDates: []string{"2021-12-27", "2022-01-17"},
+	}
+	schedule := &icsSchedule{
+		Schedule:  []time.Weekday{time.Thursday},
+		SkipDates: []string{"2022-01-06", "2022-01-20", "2022-01-17"},
+	}
+
+	schedule.apply(meeting, time.Date(2022, 1, 10, 12, 0, 0, 0, time.UTC))
+	assert.Equal(t, []time.Weekday{time.Thursday}, meeting.Schedule)
+	assert.Equal(t, "", meeting.Time)
+	assert.Equal(t, "Europe/Paris", meeting.Timezone)
+	assert.Equal(t, []string{"2022-01-17", "2022-01-20"}, meeting.SkipDates)
+}
+
+func TestPlugin_icsFile(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("GetFileInfo", "file1").Return(&model.FileInfo{Id: "file1", PostId: "post1", Extension: "ics", Size: 100}, nil)
+	api.On("GetFileInfo", "post1").Return(nil, model.NewAppError("GetFileInfo", "app.file_info.get.app_error", nil, "", 404))
+	api.On("GetPost", "post1").Return(&model.Post{Id: "post1", ChannelId: "channelID", FileIds: []string{"file1"}}, nil)
+	api.On("GetFile", "file1").Return([]byte("BEGIN:VCALENDAR"), nil)
+	p := Plugin{}
+	p.SetAPI(api)
+
+	data, err := p.icsFile("channelID", "file1")
+	assert.Nil(t, err)
+	assert.Equal(t, "BEGIN:VCALENDAR", string(data))
+
+	data, err = p.icsFile("channelID", "post1")
+	assert.Nil(t, err)
+	assert.Equal(t, "BEGIN:VCALENDAR", string(data))
+
+	_, err = p.icsFile("otherChannelID", "file1")
+	assert.EqualError(t, err, "the file must be attached to a message of this channel")
+}
+
+func TestWindowsTimezones(t *testing.T) {
+	for windows, name := range windowsTimezones {
+		_, err := time.LoadLocation(name)
+		assert.Nil(t, err, windows)
+	}
+}
+
+func TestPlugin_importICSPermission(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("GetChannelMember", "channelID", "member").Return(&model.ChannelMember{}, nil)
+	api.On("HasPermissionTo", mock.Anything, model.PermissionManageSystem).Return(false)
+	p := Plugin{}
+	p.SetAPI(api)
+	p.setConfiguration(&configuration{SettingsPermission: settingsPermissionMember})
+
+	response := p.executeCommandImportICS(&model.CommandArgs{Command: "/agenda import-ics file1", ChannelId: "channelID", UserId: "member"})
+	assert.Equal(t, "Only channel admins can import the meeting schedule of this channel", response.Text)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/import-ics?channelId=channelID", strings.NewReader("BEGIN:VCALENDAR"))
+	r.Header.Add("Mattermost-User-Id", "member")
+	w := httptest.NewRecorder()
+	p.httpImportICS(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+
+	api.AssertNotCalled(t, "GetFileInfo", mock.Anything)
+	api.AssertNotCalled(t, "KVSet", mock.Anything, mock.Anything)
+}
+
+func TestPlugin_importICSSettingsPermission(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("GetChannelMember", "channelID", "channelAdmin").Return(&model.ChannelMember{SchemeAdmin: true}, nil)
+	api.On("HasPermissionTo", "channelAdmin", model.PermissionManageSystem).Return(false)
+	api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", TeamId: "teamID"}, nil)
+	api.On("GetTeamMember", "teamID", "channelAdmin").Return(&model.TeamMember{}, nil)
+	p := Plugin{}
+	p.SetAPI(api)
+	p.setConfiguration(&configuration{SettingsPermission: settingsPermissionTeamAdmin})
+
+	response := p.executeCommandImportICS(&model.CommandArgs{Command: "/agenda import-ics file1", ChannelId: "channelID", UserId: "channelAdmin"})
+	assert.Equal(t, "You do not have permission to change the meeting settings of this channel", response.Text)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/import-ics?channelId=channelID", strings.NewReader("BEGIN:VCALENDAR"))
+	r.Header.Add("Mattermost-User-Id", "channelAdmin")
+	w := httptest.NewRecorder()
+	p.httpImportICS(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+
+	api.AssertNotCalled(t, "GetFileInfo", mock.Anything)
+	api.AssertNotCalled(t, "KVSet", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	// maxImportItems is the maximum number of items of an import
+	maxImportItems = 50
+	// maxImportFileSize is the maximum size of the files of items imported
+	maxImportFileSize = 256 * 1024
+)
+
+var (
+	// importBulletRegex matches the items of a Markdown list, i.e. `- item`, `* [ ] item` or `1. item`
+	importBulletRegex = regexp.MustCompile(`^(\s*)(?:[-*+]|[0-9]+[.)])\s+(?:\[[ xX]\]\s+)?(.*)$`)
+
+	// importColumns are the columns of an imported CSV file without a header row
+	importColumns = []string{"title", "owner", "duration"}
+)
+
+// importEntry is an item of an imported list
+type importEntry struct {
+	// Line is the line of the entry in the list
+	Line     int
+	Title    string
+	Details  string
+	Owner    string
+	Duration string
+	Labels   []string
+}
+
+// message returns the message of the item of the entry
+func (e *importEntry) message() string {
+	if e.Details == "" {
+		return e.Title
+	}
+	return e.Title + "\n" + e.Details
+}
+
+// importFailure is an entry of an imported list that could not be queued
+type importFailure struct {
+	Line   int    `json:"line"`
+	Title  string `json:"title"`
+	Reason string `json:"reason"`
+}
+
+// importResult is the outcome of importing a list of items for a meeting occurrence
+type importResult struct {
+	Start   time.Time     `json:"-"`
+	Hashtag string        `json:"hashtag"`
+	Queued  []*AgendaItem `json:"queued"`
+	// Duplicates are the titles of the entries already queued for the occurrence
+	Duplicates []string        `json:"duplicates"`
+	Failed     []importFailure `json:"failed"`
+}
+
+// summary returns the report of the import for the user
+func (r *importResult) summary(meeting *Meeting) string {
+	lines := []string{fmt.Sprintf("Imported %d items for the meeting of %s %s.", len(r.Queued), meeting.formatOccurrence(r.Start), r.Hashtag)}
+	for _, item := range r.Queued {
+		lines = append(lines, fmt.Sprintf("%d. %s", item.Number, item.summary()))
+	}
+	if len(r.Duplicates) > 0 {
+		lines = append(lines, fmt.Sprintf("\nSkipped %d items already queued for this meeting:", len(r.Duplicates)))
+		for _, title := range r.Duplicates {
+			lines = append(lines, "* "+title)
+		}
+	}
+	if len(r.Failed) > 0 {
+		lines = append(lines, fmt.Sprintf("\nFailed to import %d items:", len(r.Failed)))
+		for _, failure := range r.Failed {
+			lines = append(lines, fmt.Sprintf("* Line %d, %s: %s", failure.Line, failure.Title, failure.Reason))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseImportList parses a Markdown list, or a CSV with a row per item
+func parseImportList(text string) ([]*importEntry, error) {
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+	if text == "" {
+		return nil, errors.New("the list is empty")
+	}
+
+	var entries []*importEntry
+	var err error
+	if isMarkdownList(text) {
+		entries = parseMarkdownList(text)
+	} else if entries, err = parseCSVList(text); err != nil {
+		return nil, err
+	}
+
+	if len(entries) == 0 {
+		return nil, errors.New("the list has no items")
+	}
+	if len(entries) > maxImportItems {
+		return nil, errors.Errorf("the list has %d items, more than the maximum of %d", len(entries), maxImportItems)
+	}
+	return entries, nil
+}
+
+// isMarkdownList returns true if a line of the text is the item of a Markdown list
+func isMarkdownList(text string) bool {
+	for _, line := range strings.Split(text, "\n") {
+		if importBulletRegex.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseMarkdownList parses the items of a Markdown list. The indented lines under an item,
+// such as a nested list, are its details.
+func parseMarkdownList(text string) []*importEntry {
+	var entries []*importEntry
+	var details []string
+	indent := -1
+	flush := func() {
+		if len(entries) > 0 {
+			entries[len(entries)-1].Details = strings.Trim(strings.Join(details, "\n"), "\n")
+		}
+		details = nil
+	}
+
+	for i, line := range strings.Split(text, "\n") {
+		matches := importBulletRegex.FindStringSubmatch(line)
+		if matches != nil && (indent == -1 || len(matches[1]) <= indent) {
+			flush()
+			indent = len(matches[1])
+			entries = append(entries, &importEntry{Line: i + 1, Title: strings.TrimSpace(matches[2])})
+			continue
+		}
+
+		if len(entries) > 0 && (strings.TrimSpace(line) == "" || len(line)-len(strings.TrimLeft(line, " \t")) > indent) {
+			details = append(details, strings.TrimRight(dedent(line, indent+2), " \t"))
+		}
+	}
+	flush()
+
+	return entries
+}
+
+// dedent removes up to n leading spaces or tabs from the line
+func dedent(line string, n int) string {
+	for i := 0; i < n && len(line) > 0 && (line[0] == ' ' || line[0] == '\t'); i++ {
+		line = line[1:]
+	}
+	return line
+}
+
+// parseCSVList parses the rows of a CSV list. The columns are named by a header row with a
+// `title` column, otherwise they are the title, owner and duration of the items.
+func parseCSVList(text string) ([]*importEntry, error) {
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, errors.Wrap(err, "the list is neither a Markdown list nor a valid CSV")
+	}
+
+	columns := importColumns
+	first := 0
+	for _, name := range records[0] {
+		if strings.EqualFold(strings.TrimSpace(name), "title") {
+			columns = make([]string, len(records[0]))
+			for i, name := range records[0] {
+				columns[i] = strings.ToLower(strings.TrimSpace(name))
+			}
+			first = 1
+			break
+		}
+	}
+
+	var entries []*importEntry
+	for i, record := range records[first:] {
+		entry := &importEntry{Line: i + first + 1}
+		for j, value := range record {
+			if j >= len(columns) {
+				break
+			}
+			value = strings.TrimSpace(value)
+			switch columns[j] {
+			case "title":
+				entry.Title = value
+			case "details":
+				entry.Details = value
+			case "owner":
+				entry.Owner = value
+			case "duration":
+				entry.Duration = value
+			case "labels":
+				entry.Labels = parseLabels(value)
+			}
+		}
+		if entry.Title == "" && entry.Details == "" && entry.Owner == "" {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// normalizeTitle returns the title compared to find duplicate items
+func normalizeTitle(title string) string {
+	return strings.ToLower(strings.Join(strings.Fields(title), " "))
+}
+
+// importItems queues the entries for the meeting occurrence of the hashtag, after its items, skipping
+// the entries already queued. Users queue items they own, and channel admins items owned by other members.
+func (p *Plugin) importItems(meeting *Meeting, userID, hashtag string, entries []*importEntry) (*importResult, error) {
+	items, _, err := p.scanAgenda(meeting, "", userID, hashtag)
+	if err != nil {
+		return nil, err
+	}
+	if err = p.renumberAgendaItems(hashtag, items); err != nil {
+		return nil, err
+	}
+
+	if len(items) == 0 {
+		if items, err = p.seedStandingItems(meeting, hashtag); err != nil {
+			return nil, err
+		}
+	}
+
+	titles := map[string]bool{}
+	for _, item := range items {
+		titles[normalizeTitle(item.Title)] = true
+	}
+
+	result := &importResult{Hashtag: hashtag, Queued: []*AgendaItem{}, Duplicates: []string{}, Failed: []importFailure{}}
+	fail := func(entry *importEntry, reason string) {
+		result.Failed = append(result.Failed, importFailure{Line: entry.Line, Title: entry.Title, Reason: reason})
+	}
+
+	number := len(items) + 1
+	maxItems := p.getConfiguration().MaxItemsPerOccurrence
+	for _, entry := range entries {
+		if entry.Title == "" {
+			fail(entry, "A title is required.")
+			continue
+		}
+		if titles[normalizeTitle(entry.Title)] {
+			result.Duplicates = append(result.Duplicates, entry.Title)
+			continue
+		}
+
+		duration, err := parseOptionalDuration(entry.Duration)
+		if err != nil {
+			fail(entry, "Invalid duration. Must be a duration such as 15m or 1h.")
+			continue
+		}
+
+		if maxItems > 0 && number > maxItems {
+			fail(entry, fmt.Sprintf("The agenda of this meeting is full. It can have up to %d items.", maxItems))
+			continue
+		}
+
+		ownerID := userID
+		if owner := strings.TrimPrefix(entry.Owner, "@"); owner != "" {
+			user, appErr := p.API.GetUserByUsername(owner)
+			if appErr != nil {
+				fail(entry, fmt.Sprintf("The owner @%s does not exist.", owner))
+				continue
+			}
+			ownerID = user.Id
+		}
+		if ownerID != userID && !p.isChannelAdmin(meeting.ChannelID, userID) {
+			fail(entry, "Only channel admins can queue items owned by other users.")
+			continue
+		} else if ownerID != userID && !p.isChannelMember(meeting.ChannelID, ownerID) {
+			fail(entry, "The owner must be a member of the channel.")
+			continue
+		}
+
+		item, _, err := p.queueItem(meeting, &queueRequest{
+			UserID:   userID,
+			OwnerID:  ownerID,
+			Hashtag:  hashtag,
+			Message:  entry.message(),
+			Duration: duration,
+			Labels:   entry.Labels,
+			Number:   number,
+		})
+		if err != nil {
+			fail(entry, err.Error())
+			continue
+		}
+
+		number++
+		titles[normalizeTitle(entry.Title)] = true
+		result.Queued = append(result.Queued, item)
+	}
+
+	return result, nil
+}
+
+func (p *Plugin) executeCommandImport(args *model.CommandArgs) *model.CommandResponse {
+	split := strings.Fields(args.Command)
+	if len(split) < 3 {
+		return responsef("Missing list. Usage: `/agenda import [next-week|weekday] <list, fileId or message link>`")
+	}
+
+	meeting, err := p.GetMeeting(args.ChannelId)
+	if err != nil {
+		p.API.LogError("failed to get meeting for channel", "err", err.Error(), "channel_id", args.ChannelId)
+		return responsef("Error getting meeting information for this channel")
+	}
+
+	skip := 2
+	nextWeek, weekday := parseQueueDay(split[2])
+	if nextWeek || weekday > -1 {
+		skip++
+	}
+	// Keep the lines of the list
+	text := commandArguments(args.Command, skip)
+	if text == "" {
+		return responsef("Missing list. Usage: `/agenda import [next-week|weekday] <list, fileId or message link>`")
+	}
+
+	if id, err := parsePermalink(text); err == nil {
+		data, err := p.attachedFile(args.ChannelId, id, "Markdown or CSV", []string{"md", "csv", "txt"}, maxImportFileSize)
+		if err != nil {
+			return responsef("Failed to import the items: %s", err.Error())
+		}
+		text = string(data)
+	}
+
+	entries, err := parseImportList(text)
+	if err != nil {
+		return responsef("Failed to import the items: %s", err.Error())
+	}
+
+	start, frozen, err := meeting.queueOccurrence(time.Now(), nextWeek, weekday, false)
+	if err != nil {
+		return responsef("Error calculating hashtags. Check the meeting settings for this channel.")
+	}
+
+	result, err := p.importItems(meeting, args.UserId, meeting.hashtagForDate(start), entries)
+	if err != nil {
+		return responsef("Failed to import the items: %s", err.Error())
+	}
+	result.Start = start
+
+	response := result.summary(meeting)
+	if frozen != nil {
+		response = fmt.Sprintf("The agenda of the meeting of %s is frozen, so the items were imported for the following meeting.\n%s",
+			meeting.formatOccurrence(*frozen), response)
+	}
+	return responsef(response)
+}
+
+// httpImportItems queues the items of the list for the meeting occurrence of the hashtag
+func (p *Plugin) httpImportItems(w http.ResponseWriter, meeting *Meeting, userID, hashtag, list string) {
+	entries, err := parseImportList(list)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := p.importItems(meeting, userID, hashtag, entries)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	p.writeJSON(w, result)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseImportList(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    []*importEntry
+		wantErr string
+	}{
+		{
+			name: "markdown list with details",
+			text: "## Agenda\n- Release status\n  - QA is blocked\n  - Docs are late\n\n* [ ] Hiring\n1. Retro",
+			want: []*importEntry{
+				{Line: 2, Title: "Release status", Details: "- QA is blocked\n- Docs are late"},
+				{Line: 6, Title: "Hiring"},
+				{Line: 7, Title: "Retro"},
+			},
+		},
+		{
+			name: "csv without header",
+			text: "Release status, alice, 15m\nHiring\n\"Retro, part 2\",,1h",
+			want: []*importEntry{
+				{Line: 1, Title: "Release status", Owner: "alice", Duration: "15m"},
+				{Line: 2, Title: "Hiring"},
+				{Line: 3, Title: "Retro, part 2", Duration: "1h"},
+			},
+		},
+		{
+			name: "csv with header",
+			text: "Duration,Title,Labels,Details\r\n15m,Release status,\"release, qa\",QA is blocked\r\n,Hiring,,",
+			want: []*importEntry{
+				{Line: 2, Title: "Release status", Details: "QA is blocked", Duration: "15m", Labels: []string{"release", "qa"}},
+				{Line: 3, Title: "Hiring"},
+			},
+		},
+		{name: "empty", text: " \n ", wantErr: "the list is empty"},
+		{name: "invalid csv", text: "Release \"status\", alice", wantErr: "the list is neither a Markdown list nor a valid CSV"},
+		{name: "too many items", text: strings.Repeat("- item\n", maxImportItems+1), wantErr: fmt.Sprintf("the list has %d items, more than the maximum of %d", maxImportItems+1, maxImportItems)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := parseImportList(tt.text)
+			if tt.wantErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, entries)
+		})
+	}
+}
+
+func TestPlugin_importItems(t *testing.T) {
+	meeting := &Meeting{ChannelID: "channelID", HashtagFormat: "dev-{{Jan02}}"}
+
+	setupAPI := func() *plugintest.API {
+		api := &plugintest.API{}
+		api.On("GetChannelMember", "channelID", "member").Return(&model.ChannelMember{}, nil)
+		api.On("GetChannelMember", "channelID", "admin").Return(&model.ChannelMember{SchemeAdmin: true}, nil)
+		api.On("GetChannelMember", "channelID", "outsider").Return(nil, model.NewAppError("GetChannelMember", "app.channel.get_member.missing.app_error", nil, "", http.StatusNotFound))
+		api.On("HasPermissionTo", mock.Anything, model.PermissionManageSystem).Return(false)
+		api.On("GetUserByUsername", "alice").Return(&model.User{Id: "member", Username: "alice"}, nil)
+		api.On("GetUserByUsername", "carol").Return(&model.User{Id: "outsider", Username: "carol"}, nil)
+		api.On("GetUserByUsername", "nobody").Return(nil, model.NewAppError("GetUserByUsername", "app.user.missing_account.const", nil, "", http.StatusNotFound))
+		api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Name: "dev", TeamId: "teamID"}, nil)
+		api.On("KVGet", webhooksKeyPrefix+"channelID").Return(nil, nil)
+
+		list := model.NewPostList()
+		list.AddPost(&model.Post{Id: "post1", ChannelId: "channelID", UserId: "member", Message: "#### #dev-Jan06 1) Release status"})
+		list.AddOrder("post1")
+		api.On("SearchPostsInTeamForUser", "teamID", mock.Anything, mock.Anything).Return(model.MakePostSearchResults(list, nil), nil)
+		api.On("CreatePost", mock.Anything).Return(func(post *model.Post) *model.Post {
+			created := post.Clone()
+			created.Id = model.NewId()
+			return created
+		}, nil)
+		return api
+	}
+
+	entries := []*importEntry{
+		{Line: 1, Title: "release  STATUS"},
+		{Line: 2, Title: "Hiring", Owner: "@alice", Duration: "15m"},
+		{Line: 3, Title: "Retro", Owner: "carol"},
+		{Line: 4, Title: "Roadmap", Duration: "soon"},
+		{Line: 5, Title: "Budget", Owner: "nobody"},
+		{Line: 6, Title: "hiring"},
+		{Line: 7, Title: "Demo", Details: "- Search"},
+	}
+
+	t.Run("member", func(t *testing.T) {
+		api := setupAPI()
+		p := Plugin{}
+		p.SetAPI(api)
+
+		result, err := p.importItems(meeting, "member", "#dev-Jan06", entries)
+		assert.Nil(t, err)
+
+		assert.Len(t, result.Queued, 2)
+		assert.Equal(t, 2, result.Queued[0].Number)
+		assert.Equal(t, "15m0s", result.Queued[0].Duration)
+		assert.Equal(t, 3, result.Queued[1].Number)
+		assert.Equal(t, []string{"release  STATUS", "hiring"}, result.Duplicates)
+		assert.Equal(t, []importFailure{
+			{Line: 3, Title: "Retro", Reason: "Only channel admins can queue items owned by other users."},
+			{Line: 4, Title: "Roadmap", Reason: "Invalid duration. Must be a duration such as 15m or 1h."},
+			{Line: 5, Title: "Budget", Reason: "The owner @nobody does not exist."},
+		}, result.Failed)
+
+		api.AssertCalled(t, "CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.UserId == "member" && post.Message == "#### #dev-Jan06 3) Demo\n- Search"
+		}))
+		api.AssertNumberOfCalls(t, "CreatePost", 2)
+	})
+
+	t.Run("admin queueing for an outsider", func(t *testing.T) {
+		api := setupAPI()
+		p := Plugin{}
+		p.SetAPI(api)
+
+		result, err := p.importItems(meeting, "admin", "#dev-Jan06", entries[2:3])
+		assert.Nil(t, err)
+		assert.Empty(t, result.Queued)
+		assert.Equal(t, "The owner must be a member of the channel.", result.Failed[0].Reason)
+	})
+}
@@ -0,0 +1,662 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	// itemDurationProp and itemLabelsProp are the post props holding the duration and labels of an item
+	itemDurationProp = "agenda_item_duration"
+	itemLabelsProp   = "agenda_item_labels"
+	// itemSourceProp is the post prop holding the external system that queued an item
+	itemSourceProp = "agenda_item_source"
+	// itemOwnerProp and itemQueuedByProp are the post props holding the owner of an item posted
+	// by the Agenda bot, and the user who queued it
+	itemOwnerProp    = "agenda_item_owner"
+	itemQueuedByProp = "agenda_item_queued_by"
+)
+
+var (
+	errItemNotFound = errors.New("agenda item not found")
+	errNotAllowed   = errors.New("not allowed")
+	errAgendaFull   = errors.New("the agenda of this meeting is full")
+)
+
+// AgendaItem is an item queued for a meeting
+type AgendaItem struct {
+	Number  int    `json:"number"`
+	Message string `json:"message"`
+	// Title is the first line of the message and Details the following lines
+	Title    string   `json:"title"`
+	Details  string   `json:"details,omitempty"`
+	Duration string   `json:"duration,omitempty"`
+	Labels   []string `json:"labels,omitempty"`
+	Source   string   `json:"source,omitempty"`
+	PostID   string   `json:"postId"`
+	UserID   string   `json:"userId"`
+}
+
+// readProps reads the duration, labels and source of the item from the props of its post
+func (i *AgendaItem) readProps(post *model.Post) {
+	i.Duration, _ = post.GetProp(itemDurationProp).(string)
+	i.Source, _ = post.GetProp(itemSourceProp).(string)
+
+	switch labels := post.GetProp(itemLabelsProp).(type) {
+	case []string:
+		i.Labels = labels
+	case []interface{}:
+		for _, label := range labels {
+			if label, ok := label.(string); ok {
+				i.Labels = append(i.Labels, label)
+			}
+		}
+	}
+}
+
+// summary returns the title of the item with its duration and labels
+func (i *AgendaItem) summary() string {
+	summary := i.Title
+	if i.Duration != "" {
+		summary += fmt.Sprintf(" (%s)", i.Duration)
+	}
+	for _, label := range i.Labels {
+		summary += fmt.Sprintf(" `%s`", label)
+	}
+
+	return summary
+}
+
+// newAgendaItem returns the item with the given message, split into its title and details
+func newAgendaItem(number int, message, postID, userID string) *AgendaItem {
+	item := &AgendaItem{
+		Number: number,
+		PostID: postID,
+		UserID: userID,
+	}
+	item.setMessage(message)
+
+	return item
+}
+
+func (i *AgendaItem) setMessage(message string) {
+	i.Message = message
+	i.Title, i.Details = splitItemMessage(message)
+}
+
+// splitItemMessage splits a multi-line item message into its first line and the following lines
+func splitItemMessage(message string) (string, string) {
+	lines := strings.SplitN(message, "\n", 2)
+	if len(lines) == 1 {
+		return strings.TrimSpace(lines[0]), ""
+	}
+
+	return strings.TrimSpace(lines[0]), strings.Trim(lines[1], "\n")
+}
+
+// searchAgendaPosts returns the posts of the channel that contain the given hashtag,
+// sorted by creation time. The search is done on behalf of userID, or without
+// permission checks if userID is empty.
+func (p *Plugin) searchAgendaPosts(channelID, teamID, userID, hashtag string) ([]*model.Post, error) {
+	c, appErr := p.API.GetChannel(channelID)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if teamID == "" {
+		teamID = c.TeamId
+	}
+
+	terms := fmt.Sprintf("in:%s %s", c.Name, hashtag)
+
+	var sortedPosts []*model.Post
+	if userID == "" {
+		posts, appErr := p.API.SearchPostsInTeam(teamID, model.ParseSearchParams(terms, 0))
+		if appErr != nil {
+			return nil, errors.Wrap(appErr, "Error searching posts to find hashtags")
+		}
+		sortedPosts = posts
+	} else {
+		searchResults, appErr := p.API.SearchPostsInTeamForUser(teamID, userID, model.SearchParameter{Terms: &terms})
+		if appErr != nil {
+			return nil, errors.Wrap(appErr, "Error searching posts to find hashtags")
+		}
+
+		// TODO we won't need to do this once we fix https://github.com/mattermost/mattermost-server/issues/11006
+		for _, post := range searchResults.PostList.Posts {
+			sortedPosts = append(sortedPosts, post)
+		}
+	}
+
+	sort.Slice(sortedPosts, func(i, j int) bool {
+		return sortedPosts[i].CreateAt < sortedPosts[j].CreateAt
+	})
+
+	return sortedPosts, nil
+}
+
+// getAgendaItems returns the items queued with the given hashtag, sorted by their number.
+func (p *Plugin) getAgendaItems(meeting *Meeting, teamID, userID, hashtag string) ([]*AgendaItem, error) {
+	items, _, err := p.scanAgenda(meeting, teamID, userID, hashtag)
+	return items, err
+}
+
+// scanAgenda returns the agenda items queued under the hashtag, sorted by number, and the posts
+// that look like agenda items of the hashtag but can't be parsed, i.e. because they were edited by hand.
+// Posts that only mention the hashtag are ignored.
+func (p *Plugin) scanAgenda(meeting *Meeting, teamID, userID, hashtag string) ([]*AgendaItem, []*model.Post, error) {
+	posts, err := p.searchAgendaPosts(meeting.ChannelID, teamID, userID, hashtag)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	brokenItemRegex := brokenItemRegex(hashtag)
+
+	items := make([]*AgendaItem, 0, len(posts))
+	var broken []*model.Post
+	for _, post := range posts {
+		parsedMessage, err := parseMeetingPost(meeting, post)
+		if err != nil {
+			if brokenItemRegex.MatchString(post.Message) {
+				p.API.LogWarn("Failed to parse agenda item", "post_id", post.Id, "hashtag", hashtag)
+				broken = append(broken, post)
+			} else {
+				p.API.LogDebug("Failed to parse meeting post", "post_id", post.Id, "error", err.Error())
+			}
+			continue
+		}
+
+		number, _ := strconv.Atoi(parsedMessage.number)
+		item := newAgendaItem(number, parsedMessage.textMessage, post.Id, p.itemOwner(post))
+		item.readProps(post)
+		items = append(items, item)
+	}
+
+	// Keep the order of items that were reordered, falling back to their creation order
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Number < items[j].Number
+	})
+
+	return items, broken, nil
+}
+
+// itemOwner returns the user the item of the post belongs to: its author, or the user the
+// Agenda bot posted it for
+func (p *Plugin) itemOwner(post *model.Post) string {
+	if ownerID, ok := post.GetProp(itemOwnerProp).(string); ok && ownerID != "" && post.UserId == p.botID {
+		return ownerID
+	}
+	return post.UserId
+}
+
+// itemMessage returns the message of the post of an agenda item
+func itemMessage(hashtag string, number int, message string) string {
+	return fmt.Sprintf("#### %v %v) %v", hashtag, number, message)
+}
+
+// findAgendaItem returns the item with the given number
+func findAgendaItem(items []*AgendaItem, number int) (*AgendaItem, error) {
+	for _, item := range items {
+		if item.Number == number {
+			return item, nil
+		}
+	}
+	return nil, errItemNotFound
+}
+
+// canEditAgendaItem returns true if the user queued the item or is a channel admin
+func (p *Plugin) canEditAgendaItem(item *AgendaItem, channelID, userID string) bool {
+	return item.UserID == userID || p.isChannelAdmin(channelID, userID)
+}
+
+// queueRequest describes an agenda item to queue
+type queueRequest struct {
+	TeamID string
+	// UserID is the user queueing the item and OwnerID the user the item belongs to,
+	// i.e. the author of a message queued by another user
+	UserID   string
+	OwnerID  string
+	RootID   string
+	Hashtag  string
+	Message  string
+	Duration string
+	Labels   []string
+	// Source is the external system the item was queued from
+	Source string
+	// Number is the number of the item, or 0 to queue it after the items of the occurrence
+	Number int
+}
+
+// queueAgendaItem queues the message as the last item of the meeting occurrence of the hashtag.
+// It also returns the posts of the occurrence that could not be parsed as agenda items.
+func (p *Plugin) queueAgendaItem(meeting *Meeting, teamID, userID, rootID, hashtag, message string) (*AgendaItem, []*model.Post, error) {
+	return p.queueItem(meeting, &queueRequest{
+		TeamID:  teamID,
+		UserID:  userID,
+		OwnerID: userID,
+		RootID:  rootID,
+		Hashtag: hashtag,
+		Message: message,
+	})
+}
+
+// queueItem queues the item of the request as the last item of the meeting occurrence of its hashtag
+func (p *Plugin) queueItem(meeting *Meeting, request *queueRequest) (*AgendaItem, []*model.Post, error) {
+	number, broken := request.Number, []*model.Post(nil)
+	if number == 0 {
+		var err error
+		if number, broken, err = p.calculateQueueItemNumberAndUpdateOldItems(meeting, request.TeamID, request.UserID, request.Hashtag); err != nil {
+			return nil, nil, err
+		}
+		if number == 1 && len(broken) == 0 {
+			// The first item of an occurrence comes after its standing items
+			standing, err := p.seedStandingItems(meeting, request.Hashtag)
+			if err != nil {
+				return nil, nil, err
+			}
+			number += len(standing)
+		}
+		if maxItems := p.getConfiguration().MaxItemsPerOccurrence; maxItems > 0 && number > maxItems {
+			return nil, nil, errAgendaFull
+		}
+	}
+
+	post := &model.Post{
+		UserId:    request.UserID,
+		ChannelId: meeting.ChannelID,
+		RootId:    request.RootID,
+		Message:   itemMessage(request.Hashtag, number, request.Message),
+	}
+	if request.Duration != "" {
+		post.AddProp(itemDurationProp, request.Duration)
+	}
+	if len(request.Labels) > 0 {
+		post.AddProp(itemLabelsProp, request.Labels)
+	}
+	if request.Source != "" {
+		post.AddProp(itemSourceProp, request.Source)
+	}
+	if request.OwnerID == p.botID {
+		post.UserId = p.botID
+	} else if p.postsAsBot(meeting) || request.OwnerID != request.UserID {
+		// The Agenda bot posts the item on behalf of its owner, as items are never posted as other users
+		p.attributeBotPost(post, request)
+	}
+
+	created, appErr := p.API.CreatePost(post)
+	if appErr != nil {
+		return nil, nil, errors.Errorf("Error creating post: %s", appErr.Message)
+	}
+
+	item := newAgendaItem(number, request.Message, created.Id, request.OwnerID)
+	item.Duration = request.Duration
+	item.Labels = request.Labels
+	item.Source = request.Source
+
+	p.notifyWebhooks(meeting.ChannelID, WebhookPayload{Event: webhookEventItemQueued, Hashtag: request.Hashtag, UserID: request.UserID, Item: item})
+	return item, broken, nil
+}
+
+// postsAsBot returns true if the Agenda bot posts the items of the meeting instead of the users queueing them
+func (p *Plugin) postsAsBot(meeting *Meeting) bool {
+	if meeting.PostAs != "" {
+		return meeting.PostAs == postAsBot
+	}
+	return p.getConfiguration().PostAs == postAsBot
+}
+
+// attributeBotPost makes the Agenda bot the author of the post of the item of the request, and
+// records its owner and the user who queued it
+func (p *Plugin) attributeBotPost(post *model.Post, request *queueRequest) {
+	queuedBy := request.UserID
+	if queuedBy == "" {
+		queuedBy = request.OwnerID
+	}
+	usernames := p.usernames([]string{queuedBy, request.OwnerID})
+
+	attribution := fmt.Sprintf("Queued by @%s", usernames[queuedBy])
+	if request.OwnerID != queuedBy {
+		attribution += fmt.Sprintf(" for @%s", usernames[request.OwnerID])
+	}
+
+	post.UserId = p.botID
+	post.AddProp(itemOwnerProp, request.OwnerID)
+	post.AddProp(itemQueuedByProp, queuedBy)
+	model.ParseSlackAttachment(post, []*model.SlackAttachment{{Footer: attribution}})
+}
+
+// queueResult is the outcome of queueing an item for a meeting occurrence
+type queueResult struct {
+	Item    *AgendaItem
+	Broken  []*model.Post
+	Start   time.Time
+	Hashtag string
+	// Frozen is the start of the occurrence passed over because its agenda was frozen
+	Frozen *time.Time
+}
+
+// message returns the confirmation of the queued item
+func (r *queueResult) message(meeting *Meeting) string {
+	if r.Frozen != nil {
+		return fmt.Sprintf("The agenda of the meeting of %s is frozen, so item %d was queued for the meeting of %s %s.",
+			meeting.formatOccurrence(*r.Frozen), r.Item.Number, meeting.formatOccurrence(r.Start), r.Hashtag)
+	}
+	return fmt.Sprintf("Queued item %d for the meeting of %s %s.", r.Item.Number, meeting.formatOccurrence(r.Start), r.Hashtag)
+}
+
+// parseQueueDay parses the meeting day of a queued item: `next-week` or a weekday.
+// weekday is -1 if the value is neither.
+func parseQueueDay(value string) (nextWeek bool, weekday int) {
+	if value == "next-week" {
+		return true, -1
+	}
+
+	parsedWeekday, err := parseSchedule(value)
+	if err != nil {
+		return false, -1
+	}
+	return false, int(parsedWeekday)
+}
+
+// queueForOccurrence queues the item of the request for the next meeting, or the meeting of the given
+// weekday or of next week, moving to the following meeting if the agenda is frozen unless force is set.
+func (p *Plugin) queueForOccurrence(meeting *Meeting, request *queueRequest, nextWeek bool, weekday int, force bool) (*queueResult, error) {
+	start, frozen, err := meeting.queueOccurrence(time.Now(), nextWeek, weekday, force)
+	if err != nil {
+		return nil, errors.New("Error calculating hashtags. Check the meeting settings for this channel.")
+	}
+	request.Hashtag = meeting.hashtagForDate(start)
+
+	item, broken, err := p.queueItem(meeting, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return &queueResult{Item: item, Broken: broken, Start: start, Hashtag: request.Hashtag, Frozen: frozen}, nil
+}
+
+// updateAgendaItem replaces the message of an item, keeping its number
+func (p *Plugin) updateAgendaItem(meeting *Meeting, userID, hashtag string, number int, message string) (*AgendaItem, error) {
+	items, err := p.getAgendaItems(meeting, "", userID, hashtag)
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := findAgendaItem(items, number)
+	if err != nil {
+		return nil, err
+	}
+	if !p.canEditAgendaItem(item, meeting.ChannelID, userID) {
+		return nil, errNotAllowed
+	}
+
+	post, appErr := p.API.GetPost(item.PostID)
+	if appErr != nil {
+		return nil, appErr
+	}
+	post.Message = itemMessage(hashtag, item.Number, message)
+	if _, appErr = p.API.UpdatePost(post); appErr != nil {
+		return nil, errors.Wrap(appErr, "Error updating post")
+	}
+
+	item.setMessage(message)
+
+	p.notifyWebhooks(meeting.ChannelID, WebhookPayload{Event: webhookEventItemEdited, Hashtag: hashtag, UserID: userID, Item: item})
+	return item, nil
+}
+
+// deleteAgendaItem deletes the post of an item and renumbers the items after it
+func (p *Plugin) deleteAgendaItem(meeting *Meeting, userID, hashtag string, number int) error {
+	items, err := p.getAgendaItems(meeting, "", userID, hashtag)
+	if err != nil {
+		return err
+	}
+
+	item, err := findAgendaItem(items, number)
+	if err != nil {
+		return err
+	}
+	if !p.canEditAgendaItem(item, meeting.ChannelID, userID) {
+		return errNotAllowed
+	}
+
+	if appErr := p.API.DeletePost(item.PostID); appErr != nil {
+		return errors.Wrap(appErr, "Error deleting post")
+	}
+
+	remaining := make([]*AgendaItem, 0, len(items)-1)
+	for _, other := range items {
+		if other != item {
+			remaining = append(remaining, other)
+		}
+	}
+
+	if err = p.renumberAgendaItems(hashtag, remaining); err != nil {
+		return err
+	}
+
+	p.notifyWebhooks(meeting.ChannelID, WebhookPayload{Event: webhookEventItemRemoved, Hashtag: hashtag, UserID: userID, Item: item, Items: remaining})
+	return nil
+}
+
+// reorderAgendaItems renumbers the items following the given order of their current numbers.
+// Only channel admins can reorder the items, as it changes the items of other users.
+func (p *Plugin) reorderAgendaItems(meeting *Meeting, userID, hashtag string, order []int) ([]*AgendaItem, error) {
+	if !p.isChannelAdmin(meeting.ChannelID, userID) {
+		return nil, errNotAllowed
+	}
+
+	items, err := p.getAgendaItems(meeting, "", userID, hashtag)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(order) != len(items) {
+		return nil, errors.Errorf("the order must contain the %d item numbers", len(items))
+	}
+
+	reordered := make([]*AgendaItem, 0, len(items))
+	seen := map[int]bool{}
+	for _, number := range order {
+		item, err := findAgendaItem(items, number)
+		if err != nil || seen[number] {
+			return nil, errors.Errorf("invalid item number %d in the order", number)
+		}
+		seen[number] = true
+		reordered = append(reordered, item)
+	}
+
+	if err := p.renumberAgendaItems(hashtag, reordered); err != nil {
+		return nil, err
+	}
+
+	p.notifyWebhooks(meeting.ChannelID, WebhookPayload{Event: webhookEventItemsReordered, Hashtag: hashtag, UserID: userID, Items: reordered})
+	return reordered, nil
+}
+
+// renumberAgendaItems numbers the items in the given order, updating the posts whose number changed
+func (p *Plugin) renumberAgendaItems(hashtag string, items []*AgendaItem) error {
+	for i, item := range items {
+		number := i + 1
+		if item.Number == number {
+			continue
+		}
+
+		post, appErr := p.API.GetPost(item.PostID)
+		if appErr != nil {
+			return appErr
+		}
+		post.Message = itemMessage(hashtag, number, item.Message)
+		if _, appErr = p.API.UpdatePost(post); appErr != nil {
+			return errors.Wrap(appErr, "Error updating post")
+		}
+
+		item.Number = number
+	}
+
+	return nil
+}
+
+// httpChannelRoutes serves the routes under /api/v1/channels/{channelId}
+func (p *Plugin) httpChannelRoutes(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/channels/"), "/")
+	channelID := parts[0]
+
+	// Calendar clients authenticate with a calendar token instead of a session
+	if len(parts) == 2 && parts[1] == "meeting.ics" {
+		p.httpMeetingCalendar(w, r, channelID)
+		return
+	}
+
+	mattermostUserID := r.Header.Get("Mattermost-User-Id")
+	if mattermostUserID == "" {
+		http.Error(w, "Not Authorized", http.StatusUnauthorized)
+		return
+	}
+
+	if !p.isChannelMember(channelID, mattermostUserID) {
+		http.Error(w, "Not Authorized", http.StatusForbidden)
+		return
+	}
+
+	switch {
+	case len(parts) >= 4 && parts[1] == "meetings" && parts[3] == "items":
+		p.httpAgendaItems(w, r, mattermostUserID, channelID, parts[2], parts[4:])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// httpAgendaItems serves the items of a meeting occurrence:
+//
+//	GET    /items           lists the items
+//	POST   /items           queues an item
+//	POST   /items/reorder   renumbers the items following {"order": [numbers...]}
+//	POST   /items/import    queues the items of a Markdown or CSV {"list": "..."}
+//	PUT    /items/{number}  updates the message of an item
+//	DELETE /items/{number}  deletes an item
+func (p *Plugin) httpAgendaItems(w http.ResponseWriter, r *http.Request, userID, channelID, date string, rest []string) {
+	meeting, err := p.GetMeeting(channelID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	day, err := time.ParseInLocation(occurrenceDateFormat, date, meeting.location())
+	if err != nil {
+		http.Error(w, "Invalid date. Dates must be formatted as YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	start := meeting.startTime(day)
+	hashtag := meeting.hashtagForDate(start)
+
+	// Items are only queued for the occurrences the dialog accepts
+	if r.Method == http.MethodPost && (len(rest) == 0 || len(rest) == 1 && rest[0] == "import") {
+		if message := meeting.queueDateError(start, time.Now()); message != "" {
+			status := http.StatusConflict
+			if !meeting.isScheduled(start.Weekday()) {
+				status = http.StatusBadRequest
+			}
+			http.Error(w, message, status)
+			return
+		}
+	}
+
+	var body struct {
+		Message string `json:"message"`
+		Order   []int  `json:"order"`
+		List    string `json:"list"`
+	}
+	if r.Method == http.MethodPost || r.Method == http.MethodPut {
+		if err = json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	switch {
+	case len(rest) == 0 && r.Method == http.MethodGet:
+		items, err := p.getAgendaItems(meeting, "", userID, hashtag)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		p.writeJSON(w, items)
+
+	case len(rest) == 0 && r.Method == http.MethodPost:
+		if strings.TrimSpace(body.Message) == "" {
+			http.Error(w, "Missing message", http.StatusBadRequest)
+			return
+		}
+		item, _, err := p.queueAgendaItem(meeting, "", userID, "", hashtag, body.Message)
+		if err != nil {
+			p.writeItemError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		p.writeJSON(w, item)
+
+	case len(rest) == 1 && rest[0] == "reorder" && r.Method == http.MethodPost:
+		items, err := p.reorderAgendaItems(meeting, userID, hashtag, body.Order)
+		if err == errNotAllowed {
+			p.writeItemError(w, err)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		p.writeJSON(w, items)
+
+	case len(rest) == 1 && rest[0] == "import" && r.Method == http.MethodPost:
+		p.httpImportItems(w, meeting, userID, hashtag, body.List)
+
+	case len(rest) == 1 && (r.Method == http.MethodPut || r.Method == http.MethodDelete):
+		number, err := strconv.Atoi(rest[0])
+		if err != nil {
+			http.Error(w, "Invalid item number", http.StatusBadRequest)
+			return
+		}
+
+		if r.Method == http.MethodDelete {
+			err = p.deleteAgendaItem(meeting, userID, hashtag, number)
+			if err == nil {
+				p.writeJSON(w, struct{ Status string }{"OK"})
+				return
+			}
+		} else {
+			if strings.TrimSpace(body.Message) == "" {
+				http.Error(w, "Missing message", http.StatusBadRequest)
+				return
+			}
+			var item *AgendaItem
+			if item, err = p.updateAgendaItem(meeting, userID, hashtag, number, body.Message); err == nil {
+				p.writeJSON(w, item)
+				return
+			}
+		}
+		p.writeItemError(w, err)
+
+	default:
+		http.Error(w, "Request: "+r.Method+" is not allowed.", http.StatusMethodNotAllowed)
+	}
+}
+
+func (p *Plugin) writeItemError(w http.ResponseWriter, err error) {
+	switch err {
+	case errItemNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errNotAllowed:
+		http.Error(w, "Not Authorized", http.StatusForbidden)
+	case errAgendaFull:
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
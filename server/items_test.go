@@ -0,0 +1,339 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func serveAgendaItems(api *plugintest.API, method, path, body string) *http.Response {
+	return serveAgendaItemsAs(api, "author", method, path, body)
+}
+
+func serveAgendaItemsAs(api *plugintest.API, userID, method, path, body string) *http.Response {
+	p := Plugin{}
+	p.SetAPI(api)
+
+	r := httptest.NewRequest(method, "/api/v1/channels/channelID/meetings/2022-01-06/items"+path, strings.NewReader(body))
+	r.Header.Add("Mattermost-User-Id", userID)
+	w := httptest.NewRecorder()
+	p.ServeHTTP(nil, w, r)
+
+	return w.Result()
+}
+
+func TestHTTPAgendaItems(t *testing.T) {
+	// A meeting on 2022-01-06 with three queued items
+	jsonMeeting, err := json.Marshal(&Meeting{
+		ChannelID:     "channelID",
+		Schedule:      []time.Weekday{time.Thursday},
+		HashtagFormat: "dev-{{Jan02}}",
+		Timezone:      "UTC",
+	})
+	assert.Nil(t, err)
+
+	postList := model.NewPostList()
+	for _, post := range []*model.Post{
+		{Id: "post1", ChannelId: "channelID", UserId: "author", Message: "#### #dev-Jan06 1) First", CreateAt: 1},
+		{Id: "post2", ChannelId: "channelID", UserId: "other", Message: "#### #dev-Jan06 2) Second", CreateAt: 2},
+		{Id: "post3", ChannelId: "channelID", UserId: "author", Message: "#### #dev-Jan06 3) Third", CreateAt: 3},
+	} {
+		postList.AddPost(post)
+		postList.AddOrder(post.Id)
+	}
+	terms := "in:dev #dev-Jan06"
+
+	t.Run("list", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("GetChannelMember", "channelID", "author").Return(&model.ChannelMember{}, nil)
+		api.On("KVGet", "channelID").Return(jsonMeeting, nil)
+		api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Name: "dev", TeamId: "teamID"}, nil)
+		api.On("SearchPostsInTeamForUser", "teamID", "author", model.SearchParameter{Terms: &terms}).Return(model.MakePostSearchResults(postList, nil), nil)
+
+		result := serveAgendaItems(api, http.MethodGet, "", "")
+		assert.Equal(t, http.StatusOK, result.StatusCode)
+
+		var items []*AgendaItem
+		assert.Nil(t, json.NewDecoder(result.Body).Decode(&items))
+		assert.Equal(t, []*AgendaItem{
+			{Number: 1, Message: "First", Title: "First", PostID: "post1", UserID: "author"},
+			{Number: 2, Message: "Second", Title: "Second", PostID: "post2", UserID: "other"},
+			{Number: 3, Message: "Third", Title: "Third", PostID: "post3", UserID: "author"},
+		}, items)
+	})
+
+	t.Run("update own item", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("GetChannelMember", "channelID", "author").Return(&model.ChannelMember{}, nil)
+		api.On("KVGet", "channelID").Return(jsonMeeting, nil)
+		api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Name: "dev", TeamId: "teamID"}, nil)
+		api.On("SearchPostsInTeamForUser", "teamID", "author", model.SearchParameter{Terms: &terms}).Return(model.MakePostSearchResults(postList, nil), nil)
+		api.On("KVGet", webhooksKeyPrefix+"channelID").Return(nil, nil)
+		api.On("GetPost", "post3").Return(postList.Posts["post3"].Clone(), nil)
+		api.On("UpdatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.Id == "post3" && post.Message == "#### #dev-Jan06 3) Updated"
+		})).Return(&model.Post{}, nil)
+
+		result := serveAgendaItems(api, http.MethodPut, "/3", `{"message": "Updated"}`)
+		assert.Equal(t, http.StatusOK, result.StatusCode)
+		api.AssertNumberOfCalls(t, "UpdatePost", 1)
+	})
+
+	t.Run("update someone else's item", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("GetChannelMember", "channelID", "author").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionTo", "author", model.PermissionManageSystem).Return(false)
+		api.On("KVGet", "channelID").Return(jsonMeeting, nil)
+		api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Name: "dev", TeamId: "teamID"}, nil)
+		api.On("SearchPostsInTeamForUser", "teamID", "author", model.SearchParameter{Terms: &terms}).Return(model.MakePostSearchResults(postList, nil), nil)
+
+		result := serveAgendaItems(api, http.MethodPut, "/2", `{"message": "Updated"}`)
+		assert.Equal(t, http.StatusForbidden, result.StatusCode)
+		api.AssertNotCalled(t, "UpdatePost", mock.Anything)
+	})
+
+	t.Run("update unknown item", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("GetChannelMember", "channelID", "author").Return(&model.ChannelMember{}, nil)
+		api.On("KVGet", "channelID").Return(jsonMeeting, nil)
+		api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Name: "dev", TeamId: "teamID"}, nil)
+		api.On("SearchPostsInTeamForUser", "teamID", "author", model.SearchParameter{Terms: &terms}).Return(model.MakePostSearchResults(postList, nil), nil)
+
+		result := serveAgendaItems(api, http.MethodPut, "/7", `{"message": "Updated"}`)
+		assert.Equal(t, http.StatusNotFound, result.StatusCode)
+	})
+
+	t.Run("delete renumbers the following items", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("GetChannelMember", "channelID", "author").Return(&model.ChannelMember{}, nil)
+		api.On("KVGet", "channelID").Return(jsonMeeting, nil)
+		api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Name: "dev", TeamId: "teamID"}, nil)
+		api.On("SearchPostsInTeamForUser", "teamID", "author", model.SearchParameter{Terms: &terms}).Return(model.MakePostSearchResults(postList, nil), nil)
+		api.On("KVGet", webhooksKeyPrefix+"channelID").Return(nil, nil)
+		api.On("DeletePost", "post1").Return(nil)
+		api.On("GetPost", "post2").Return(postList.Posts["post2"].Clone(), nil)
+		api.On("GetPost", "post3").Return(postList.Posts["post3"].Clone(), nil)
+		api.On("UpdatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.Id == "post2" && post.Message == "#### #dev-Jan06 1) Second"
+		})).Return(&model.Post{}, nil)
+		api.On("UpdatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.Id == "post3" && post.Message == "#### #dev-Jan06 2) Third"
+		})).Return(&model.Post{}, nil)
+
+		result := serveAgendaItems(api, http.MethodDelete, "/1", "")
+		assert.Equal(t, http.StatusOK, result.StatusCode)
+		api.AssertNumberOfCalls(t, "UpdatePost", 2)
+	})
+
+	t.Run("reorder", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("GetChannelMember", "channelID", "admin").Return(&model.ChannelMember{SchemeAdmin: true}, nil)
+		api.On("HasPermissionTo", "admin", model.PermissionManageSystem).Return(false)
+		api.On("KVGet", "channelID").Return(jsonMeeting, nil)
+		api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Name: "dev", TeamId: "teamID"}, nil)
+		api.On("SearchPostsInTeamForUser", "teamID", "admin", model.SearchParameter{Terms: &terms}).Return(model.MakePostSearchResults(postList, nil), nil)
+		api.On("KVGet", webhooksKeyPrefix+"channelID").Return(nil, nil)
+		for _, post := range postList.Posts {
+			api.On("GetPost", post.Id).Return(post.Clone(), nil)
+		}
+		for _, message := range []string{"#### #dev-Jan06 1) Third", "#### #dev-Jan06 2) First", "#### #dev-Jan06 3) Second"} {
+			message := message
+			api.On("UpdatePost", mock.MatchedBy(func(post *model.Post) bool { return post.Message == message })).Return(&model.Post{}, nil)
+		}
+
+		result := serveAgendaItemsAs(api, "admin", http.MethodPost, "/reorder", `{"order": [3, 1, 2]}`)
+		assert.Equal(t, http.StatusOK, result.StatusCode)
+
+		var items []*AgendaItem
+		assert.Nil(t, json.NewDecoder(result.Body).Decode(&items))
+		assert.Equal(t, []string{"post3", "post1", "post2"}, []string{items[0].PostID, items[1].PostID, items[2].PostID})
+		api.AssertNumberOfCalls(t, "UpdatePost", 3)
+	})
+
+	t.Run("reorder with missing items", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("GetChannelMember", "channelID", "admin").Return(&model.ChannelMember{SchemeAdmin: true}, nil)
+		api.On("HasPermissionTo", "admin", model.PermissionManageSystem).Return(false)
+		api.On("KVGet", "channelID").Return(jsonMeeting, nil)
+		api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Name: "dev", TeamId: "teamID"}, nil)
+		api.On("SearchPostsInTeamForUser", "teamID", "admin", model.SearchParameter{Terms: &terms}).Return(model.MakePostSearchResults(postList, nil), nil)
+
+		result := serveAgendaItemsAs(api, "admin", http.MethodPost, "/reorder", `{"order": [3, 3, 2]}`)
+		assert.Equal(t, http.StatusBadRequest, result.StatusCode)
+		api.AssertNotCalled(t, "UpdatePost", mock.Anything)
+	})
+
+	t.Run("reorder by a member", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("GetChannelMember", "channelID", "author").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionTo", "author", model.PermissionManageSystem).Return(false)
+		api.On("KVGet", "channelID").Return(jsonMeeting, nil)
+
+		result := serveAgendaItems(api, http.MethodPost, "/reorder", `{"order": [3, 1, 2]}`)
+		assert.Equal(t, http.StatusForbidden, result.StatusCode)
+		api.AssertNotCalled(t, "UpdatePost", mock.Anything)
+	})
+
+	t.Run("queue for a past meeting", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("GetChannelMember", "channelID", "author").Return(&model.ChannelMember{}, nil)
+		api.On("KVGet", "channelID").Return(jsonMeeting, nil)
+
+		result := serveAgendaItems(api, http.MethodPost, "", `{"message": "Fourth"}`)
+		assert.Equal(t, http.StatusConflict, result.StatusCode)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+
+	t.Run("queue on a day without meeting", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("GetChannelMember", "channelID", "author").Return(&model.ChannelMember{}, nil)
+		api.On("KVGet", "channelID").Return(jsonMeeting, nil)
+		p := Plugin{}
+		p.SetAPI(api)
+
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/channels/channelID/meetings/2022-01-07/items", strings.NewReader(`{"message": "Fourth"}`))
+		r.Header.Add("Mattermost-User-Id", "author")
+		w := httptest.NewRecorder()
+		p.ServeHTTP(nil, w, r)
+		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+
+	t.Run("invalid date", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("GetChannelMember", "channelID", "author").Return(&model.ChannelMember{}, nil)
+		api.On("KVGet", "channelID").Return(jsonMeeting, nil)
+		p := Plugin{}
+		p.SetAPI(api)
+
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/channels/channelID/meetings/Jan06/items", nil)
+		r.Header.Add("Mattermost-User-Id", "author")
+		w := httptest.NewRecorder()
+		p.ServeHTTP(nil, w, r)
+		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+		api.AssertNotCalled(t, "SearchPostsInTeamForUser", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestPlugin_queueItem(t *testing.T) {
+	meeting := &Meeting{ChannelID: "channelID", Schedule: []time.Weekday{time.Thursday}, HashtagFormat: "dev-{{Jan02}}", Timezone: "UTC"}
+	postList := model.NewPostList()
+	for _, post := range []*model.Post{
+		{Id: "post1", ChannelId: "channelID", UserId: "author", Message: "#### #dev-Jan06 1) First", CreateAt: 1},
+		{Id: "post2", ChannelId: "channelID", UserId: "other", Message: "#### #dev-Jan06 2) Second", CreateAt: 2},
+		{Id: "post3", ChannelId: "channelID", UserId: "author", Message: "#### #dev-Jan06 3) Third", CreateAt: 3},
+	} {
+		postList.AddPost(post)
+		postList.AddOrder(post.Id)
+	}
+	terms := "in:dev #dev-Jan06"
+
+	t.Run("agenda full", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Name: "dev", TeamId: "teamID"}, nil)
+		api.On("SearchPostsInTeamForUser", "teamID", "author", model.SearchParameter{Terms: &terms}).Return(model.MakePostSearchResults(postList, nil), nil)
+		p := Plugin{}
+		p.SetAPI(api)
+		p.setConfiguration(&configuration{MaxItemsPerOccurrence: 3})
+
+		_, _, err := p.queueAgendaItem(meeting, "", "author", "", "#dev-Jan06", "Fourth")
+		assert.Equal(t, errAgendaFull, err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+
+	tests := []struct {
+		name          string
+		configPostAs  string
+		meetingPostAs string
+		wantBot       bool
+	}{
+		{name: "posted by the user", wantBot: false},
+		{name: "posted by the bot", configPostAs: postAsBot, wantBot: true},
+		{name: "posted by the bot for the meeting", configPostAs: postAsUser, meetingPostAs: postAsBot, wantBot: true},
+		{name: "posted by the user for the meeting", configPostAs: postAsBot, meetingPostAs: postAsUser, wantBot: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &plugintest.API{}
+			api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Name: "dev", TeamId: "teamID"}, nil)
+			api.On("SearchPostsInTeamForUser", "teamID", "author", model.SearchParameter{Terms: &terms}).Return(model.MakePostSearchResults(postList, nil), nil)
+			api.On("KVGet", webhooksKeyPrefix+"channelID").Return(nil, nil)
+			api.On("GetUser", "author").Return(&model.User{Id: "author", Username: "alice"}, nil)
+			api.On("CreatePost", mock.Anything).Return(func(post *model.Post) *model.Post {
+				created := post.Clone()
+				created.Id = "post4"
+				return created
+			}, nil)
+			p := Plugin{botID: "botID"}
+			p.SetAPI(api)
+			p.setConfiguration(&configuration{MaxItemsPerOccurrence: 4, PostAs: tt.configPostAs})
+
+			meeting := *meeting
+			meeting.PostAs = tt.meetingPostAs
+			item, _, err := p.queueAgendaItem(&meeting, "", "author", "", "#dev-Jan06", "Fourth")
+			assert.Nil(t, err)
+			assert.Equal(t, 4, item.Number)
+			assert.Equal(t, "author", item.UserID)
+
+			api.AssertCalled(t, "CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+				if post.Message != "#### #dev-Jan06 4) Fourth" {
+					return false
+				}
+				if !tt.wantBot {
+					return post.UserId == "author" && post.GetProp(itemOwnerProp) == nil
+				}
+				attachments := post.Attachments()
+				return post.UserId == "botID" && post.GetProp(itemOwnerProp) == "author" && post.GetProp(itemQueuedByProp) == "author" &&
+					len(attachments) == 1 && attachments[0].Footer == "Queued by @alice"
+			}))
+		})
+	}
+}
+
+func TestPlugin_renumberBotItems(t *testing.T) {
+	botPost := &model.Post{Id: "post2", ChannelId: "channelID", UserId: "botID", Message: "#### #dev-Jan06 3) Second"}
+	botPost.AddProp(itemOwnerProp, "other")
+	model.ParseSlackAttachment(botPost, []*model.SlackAttachment{{Footer: "Queued by @bob"}})
+	userPost := &model.Post{Id: "post1", ChannelId: "channelID", UserId: "author", Message: "#### #dev-Jan06 2) First"}
+
+	api := &plugintest.API{}
+	api.On("GetPost", "post1").Return(userPost.Clone(), nil)
+	api.On("GetPost", "post2").Return(botPost.Clone(), nil)
+	api.On("UpdatePost", mock.Anything).Return(func(post *model.Post) *model.Post { return post }, nil)
+	p := Plugin{botID: "botID"}
+	p.SetAPI(api)
+
+	items := []*AgendaItem{
+		newAgendaItem(2, "First", "post1", p.itemOwner(userPost)),
+		newAgendaItem(3, "Second", "post2", p.itemOwner(botPost)),
+	}
+	assert.Nil(t, p.renumberAgendaItems("#dev-Jan06", items))
+	assert.Equal(t, "other", items[1].UserID)
+
+	api.AssertCalled(t, "UpdatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.Id == "post1" && post.UserId == "author" && post.Message == "#### #dev-Jan06 1) First"
+	}))
+	api.AssertCalled(t, "UpdatePost", mock.MatchedBy(func(post *model.Post) bool {
+		attachments := post.Attachments()
+		return post.Id == "post2" && post.UserId == "botID" && post.Message == "#### #dev-Jan06 2) Second" &&
+			post.GetProp(itemOwnerProp) == "other" && len(attachments) == 1 && attachments[0].Footer == "Queued by @bob"
+	}))
+}
+
+func TestPlugin_itemOwner(t *testing.T) {
+	p := Plugin{botID: "botID"}
+
+	assert.Equal(t, "author", p.itemOwner(&model.Post{UserId: "author"}))
+	assert.Equal(t, "author", p.itemOwner(&model.Post{UserId: "botID", Props: model.StringInterface{itemOwnerProp: "author"}}))
+	assert.Equal(t, "botID", p.itemOwner(&model.Post{UserId: "botID"}))
+	// Only the props of the posts of the bot are trusted
+	assert.Equal(t, "other", p.itemOwner(&model.Post{UserId: "other", Props: model.StringInterface{itemOwnerProp: "author"}}))
+}
@@ -12,6 +12,13 @@ import (
 	"github.com/pkg/errors"
 )
 
+const (
+	// meetingTimeFormat is the format of the meeting start time
+	meetingTimeFormat = "15:04"
+
+	listMeetingsPerPage = 100
+)
+
 var (
 	meetingDateFormatRegex = regexp.MustCompile(`(?m)^(?P<prefix>.*)?(?:{{\s*(?P<dateformat>.*)\s*}})(?P<postfix>.*)?$`)
 )
@@ -21,6 +28,20 @@ type Meeting struct {
 	ChannelID     string         `json:"channelId"`
 	Schedule      []time.Weekday `json:"schedule"`
 	HashtagFormat string         `json:"hashtagFormat"` // Default: {ChannelName}-Jan02
+	Rotation      *Rotation      `json:"rotation,omitempty"`
+	Time          string         `json:"time,omitempty"`          // Start time of the meeting, i.e. 15:04
+	Timezone      string         `json:"timezone,omitempty"`      // IANA timezone of the meeting. Default: server timezone
+	Reminder      string         `json:"reminder,omitempty"`      // Duration before the meeting, "morning", or empty to disable
+	QueueCutoff   string         `json:"queueCutoff,omitempty"`   // Duration before the meeting after which its agenda is frozen
+	EmptyCheck    string         `json:"emptyCheck,omitempty"`    // Duration before the meeting to propose cancelling it if its agenda is empty
+	SkipDates     []string       `json:"skipDates,omitempty"`     // Dates of the cancelled occurrences
+	Name          string         `json:"name,omitempty"`          // Name of the meeting, available to the hashtag format
+	CounterStart  string         `json:"counterStart,omitempty"`  // Date of the first occurrence counted by the hashtag format
+	StandingItems []string       `json:"standingItems,omitempty"` // Items queued first on every occurrence
+	PostAs        string         `json:"postAs,omitempty"`        // Who posts the items, user or bot. Default: plugin setting
+
+	// ChannelName is the name of the meeting channel, loaded when the hashtag format uses it
+	ChannelName string `json:"-"`
 }
 
 // GetMeeting returns a meeting
@@ -41,18 +62,53 @@ func (p *Plugin) GetMeeting(channelID string) (*Meeting, error) {
 		if err != nil {
 			return nil, err
 		}
+		config := p.getConfiguration()
+		schedule, scheduleErr := config.defaultSchedule()
+		if scheduleErr != nil {
+			return nil, scheduleErr
+		}
 		meeting = &Meeting{
-			Schedule:      []time.Weekday{time.Thursday},
-			HashtagFormat: strings.Join([]string{fmt.Sprintf("%.15s", channel.Name), "{{ Jan02 }}"}, "-"),
+			Schedule:      schedule,
+			HashtagFormat: config.DefaultHashtagFormat,
+			Timezone:      config.DefaultTimezone,
 			ChannelID:     channelID,
 		}
+		if meeting.HashtagFormat == "" {
+			meeting.HashtagFormat = strings.Join([]string{fmt.Sprintf("%.15s", channel.Name), "{{ Jan02 }}"}, "-")
+		}
+	}
+
+	if err := p.loadChannelName(meeting); err != nil {
+		return nil, err
 	}
 
 	return meeting, nil
 }
 
+// loadChannelName sets the channel name of the meeting if its hashtag format uses it
+func (p *Plugin) loadChannelName(meeting *Meeting) error {
+	if meeting.ChannelName != "" || !meeting.usesHashtagPlaceholder("ChannelName") {
+		return nil
+	}
+
+	channel, appErr := p.API.GetChannel(meeting.ChannelID)
+	if appErr != nil {
+		return appErr
+	}
+	meeting.ChannelName = channel.Name
+
+	return nil
+}
+
 // SaveMeeting saves a meeting
 func (p *Plugin) SaveMeeting(meeting *Meeting) error {
+	if meeting.CounterStart == "" && meeting.usesHashtagPlaceholder("Occurrence") {
+		// Start counting from the next meeting
+		if start, err := meeting.nextOccurrence(time.Now()); err == nil {
+			meeting.CounterStart = start.Format(occurrenceDateFormat)
+		}
+	}
+
 	jsonMeeting, err := json.Marshal(meeting)
 	if err != nil {
 		return err
@@ -65,51 +121,48 @@ func (p *Plugin) SaveMeeting(meeting *Meeting) error {
 	return nil
 }
 
-func (p *Plugin) calculateQueueItemNumberAndUpdateOldItems(meeting *Meeting, args *model.CommandArgs, hashtag string) (int, error) {
-	c, appErr := p.API.GetChannel(args.ChannelId)
-	if appErr != nil {
-		return 0, appErr
-	}
-	terms := fmt.Sprintf("in:%s %s", c.Name, hashtag)
-	searchResults, appErr := p.API.SearchPostsInTeamForUser(args.TeamId, args.UserId, model.SearchParameter{Terms: &terms})
-	if appErr != nil {
-		return 0, errors.Wrap(appErr, "Error searching posts to find hashtags")
-	}
-
-	counter := 1
-
-	var sortedPosts []*model.Post
-	// TODO we won't need to do this once we fix https://github.com/mattermost/mattermost-server/issues/11006
-	for _, post := range searchResults.PostList.Posts {
-		sortedPosts = append(sortedPosts, post)
-	}
+// listMeetings returns all the meetings saved in the KV store
+func (p *Plugin) listMeetings() ([]*Meeting, error) {
+	var meetings []*Meeting
+	for page := 0; ; page++ {
+		keys, appErr := p.API.KVList(page, listMeetingsPerPage)
+		if appErr != nil {
+			return nil, appErr
+		}
 
-	sort.Slice(sortedPosts, func(i, j int) bool {
-		return sortedPosts[i].CreateAt < sortedPosts[j].CreateAt
-	})
+		for _, key := range keys {
+			// Meetings are stored by channel ID, other records use a prefix
+			if !model.IsValidId(key) {
+				continue
+			}
 
-	for _, post := range sortedPosts {
-		_, parsedMessage, err := parseMeetingPost(meeting, post)
-		if err != nil {
-			p.API.LogDebug(err.Error())
-			return 0, errors.New(err.Error())
+			meeting, err := p.GetMeeting(key)
+			if err != nil {
+				p.API.LogWarn("Failed to get meeting", "error", err.Error(), "channel_id", key)
+				continue
+			}
+			meetings = append(meetings, meeting)
 		}
 
-		_, updateErr := p.API.UpdatePost(&model.Post{
-			Id:        post.Id,
-			UserId:    post.UserId,
-			ChannelId: post.ChannelId,
-			RootId:    post.RootId,
-			Message:   fmt.Sprintf("#### %v %v) %v", hashtag, counter, parsedMessage.textMessage),
-		})
-		if updateErr != nil {
-			return 0, errors.Wrap(updateErr, "Error updating post")
+		if len(keys) < listMeetingsPerPage {
+			return meetings, nil
 		}
+	}
+}
 
-		counter++
+// calculateQueueItemNumberAndUpdateOldItems renumbers the items of the hashtag and returns the
+// number of the next item. Posts that can't be parsed are skipped and returned.
+func (p *Plugin) calculateQueueItemNumberAndUpdateOldItems(meeting *Meeting, teamID, userID, hashtag string) (int, []*model.Post, error) {
+	items, broken, err := p.scanAgenda(meeting, teamID, userID, hashtag)
+	if err != nil {
+		return 0, nil, err
 	}
 
-	return counter, nil
+	if err = p.renumberAgendaItems(hashtag, items); err != nil {
+		return 0, nil, err
+	}
+
+	return len(items) + 1, broken, nil
 }
 
 // GenerateHashtag returns a meeting hashtag
@@ -119,35 +172,231 @@ func (p *Plugin) GenerateHashtag(channelID string, nextWeek bool, weekday int) (
 		return "", err
 	}
 
-	var meetingDate *time.Time
+	start, err := meeting.upcomingOccurrence(time.Now(), nextWeek, weekday)
+	if err != nil {
+		return "", err
+	}
+
+	return meeting.hashtagForDate(start), nil
+}
+
+// nextMeetingDate returns the date of the next meeting, from today in the timezone of the
+// meeting. If weekday is -1 the date is based on the meeting schedule.
+func (m *Meeting) nextMeetingDate(now time.Time, nextWeek bool, weekday int) (*time.Time, error) {
+	today := now.In(m.location())
 	if weekday > -1 {
 		// Get date for given day
-		if meetingDate, err = nextWeekdayDate(time.Weekday(weekday), nextWeek); err != nil {
-			return "", err
+		return nextWeekdayDate(today, time.Weekday(weekday), nextWeek)
+	}
+
+	// Get date for the list of days of the week
+	return nextWeekdayDateInWeek(today, m.Schedule, nextWeek)
+}
+
+// upcomingOccurrence returns the start of the meeting occurrence of the given day, or of the next
+// occurrence of the schedule when weekday is -1 and nextWeek is false. The occurrence following a
+// cancelled one is returned instead.
+func (m *Meeting) upcomingOccurrence(now time.Time, nextWeek bool, weekday int) (time.Time, error) {
+	if !nextWeek && weekday == -1 {
+		return m.nextOccurrence(now)
+	}
+
+	date, err := m.nextMeetingDate(now, nextWeek, weekday)
+	if err != nil {
+		return time.Time{}, err
+	}
+	start := m.startTime(*date)
+	if m.isSkipped(start) {
+		year, month, day := start.Date()
+		return m.nextOccurrence(time.Date(year, month, day+1, 0, 0, 0, 0, start.Location()))
+	}
+	return start, nil
+}
+
+// location returns the timezone of the meeting
+func (m *Meeting) location() *time.Location {
+	if m.Timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(m.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// startTime returns when the meeting occurrence of the given day starts
+func (m *Meeting) startTime(day time.Time) time.Time {
+	loc := m.location()
+	year, month, dayOfMonth := day.In(loc).Date()
+	start := time.Date(year, month, dayOfMonth, 0, 0, 0, 0, loc)
+
+	if clock, err := time.Parse(meetingTimeFormat, m.Time); err == nil {
+		start = start.Add(time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute)
+	}
+
+	return start
+}
+
+// nextOccurrence returns the start time of the first meeting occurrence
+// starting at or after the given time.
+func (m *Meeting) nextOccurrence(after time.Time) (time.Time, error) {
+	if len(m.Schedule) == 0 {
+		return time.Time{}, errors.New("missing weekdays to calculate date")
+	}
+
+	day := after.In(m.location())
+	for i := 0; i <= 7*(len(m.SkipDates)+1); i++ {
+		start := m.startTime(day.AddDate(0, 0, i))
+		if m.isSkipped(start) || m.isPast(start, after) {
+			continue
 		}
-	} else {
-		// Get date for the list of days of the week
-		if meetingDate, err = nextWeekdayDateInWeek(meeting.Schedule, nextWeek); err != nil {
-			return "", err
+		for _, weekday := range m.Schedule {
+			if start.Weekday() == weekday {
+				return start, nil
+			}
 		}
 	}
 
-	var hashtag string
+	return time.Time{}, errors.New("failed to find the next meeting occurrence")
+}
 
-	if matchGroups := meetingDateFormatRegex.FindStringSubmatch(meeting.HashtagFormat); len(matchGroups) == 4 {
-		var (
-			prefix        string
-			hashtagFormat string
-			postfix       string
-		)
-		prefix = matchGroups[1]
-		hashtagFormat = strings.TrimSpace(matchGroups[2])
-		postfix = matchGroups[3]
+// isPast returns true if the meeting occurrence of the given start time is no longer upcoming at
+// the given time. Meetings without a start time are upcoming for the whole day.
+func (m *Meeting) isPast(start, now time.Time) bool {
+	if m.Time == "" {
+		return !start.AddDate(0, 0, 1).After(now)
+	}
+	return start.Before(now)
+}
 
-		hashtag = fmt.Sprintf("#%s%v%s", prefix, meetingDate.Format(hashtagFormat), postfix)
-	} else {
-		hashtag = fmt.Sprintf("#%s", meeting.HashtagFormat)
+// occurrencesBetween returns the start of the scheduled meeting occurrences between two days, inclusive
+func (m *Meeting) occurrencesBetween(from, to time.Time) []time.Time {
+	var occurrences []time.Time
+	loc := m.location()
+	fromYear, fromMonth, fromDay := from.In(loc).Date()
+	for day := time.Date(fromYear, fromMonth, fromDay, 0, 0, 0, 0, loc); !day.After(to); day = day.AddDate(0, 0, 1) {
+		if m.isScheduled(day.Weekday()) {
+			occurrences = append(occurrences, m.startTime(day))
+		}
+	}
+
+	return occurrences
+}
+
+// isScheduled returns true if the meeting is held on the weekday
+func (m *Meeting) isScheduled(weekday time.Weekday) bool {
+	for _, scheduled := range m.Schedule {
+		if scheduled == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// queueOccurrence returns the start of the meeting occurrence new items are queued for.
+// Once the queue cutoff of that occurrence has passed, items go to the following occurrence
+// unless force is set. frozen then holds the start of the occurrence that was passed over.
+func (m *Meeting) queueOccurrence(now time.Time, nextWeek bool, weekday int, force bool) (start time.Time, frozen *time.Time, err error) {
+	if start, err = m.upcomingOccurrence(now, nextWeek, weekday); err != nil {
+		return time.Time{}, nil, err
+	}
+
+	if force || !m.isFrozen(start, now) {
+		return start, nil, nil
+	}
+
+	year, month, day := start.Date()
+	following, err := m.nextOccurrence(time.Date(year, month, day+1, 0, 0, 0, 0, start.Location()))
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+
+	passed := start
+	return following, &passed, nil
+}
+
+// isFrozen returns true if the queue cutoff of the meeting occurrence starting at start has passed
+func (m *Meeting) isFrozen(start, now time.Time) bool {
+	if m.QueueCutoff == "" {
+		return false
+	}
+
+	cutoff, err := time.ParseDuration(m.QueueCutoff)
+	if err != nil {
+		return false
+	}
+
+	return !now.Before(start.Add(-cutoff))
+}
+
+// queueDateError returns why items can't be queued for the meeting occurrence starting at start,
+// or an empty string if they can.
+func (m *Meeting) queueDateError(start, now time.Time) string {
+	switch {
+	case !m.isScheduled(start.Weekday()):
+		return fmt.Sprintf("The meeting of this channel is not held on %s.", start.Weekday())
+	case m.isPast(start, now):
+		return "The meeting of this date has already started."
+	case m.isSkipped(start):
+		return "The meeting of this date is cancelled."
+	case m.isFrozen(start, now):
+		return "The agenda of the meeting of this date is frozen."
+	}
+	return ""
+}
+
+// isSkipped returns true if the meeting occurrence of the given day was cancelled
+func (m *Meeting) isSkipped(day time.Time) bool {
+	date := day.Format(occurrenceDateFormat)
+	for _, skipDate := range m.SkipDates {
+		if skipDate == date {
+			return true
+		}
+	}
+	return false
+}
+
+// heldOccurrences returns the number of meeting occurrences after from until to, inclusive,
+// that were not cancelled
+func (m *Meeting) heldOccurrences(from, to time.Time) int {
+	count := countOccurrences(m.Schedule, from, to)
+	for _, skipDate := range m.SkipDates {
+		skipped, err := time.ParseInLocation(occurrenceDateFormat, skipDate, to.Location())
+		if err == nil && skipped.After(from) && !skipped.After(to) && m.isScheduled(skipped.Weekday()) {
+			count--
+		}
+	}
+	return count
+}
+
+// skipOccurrence cancels the meeting occurrence of the given date. It returns false if it was already cancelled.
+func (m *Meeting) skipOccurrence(date string) bool {
+	for _, skipDate := range m.SkipDates {
+		if skipDate == date {
+			return false
+		}
+	}
+	m.SkipDates = append(m.SkipDates, date)
+	sort.Strings(m.SkipDates)
+	return true
+}
+
+// formatOccurrence returns a human readable date of the meeting occurrence starting at start
+func (m *Meeting) formatOccurrence(start time.Time) string {
+	if m.Time == "" {
+		return start.Format("Monday, January 2")
+	}
+	return start.Format("Monday, January 2 at 15:04 MST")
+}
+
+// hashtagForDate returns the meeting hashtag for the given date
+func (m *Meeting) hashtagForDate(date time.Time) string {
+	hashtag, err := m.renderHashtag(date)
+	if err != nil {
+		// Invalid formats are rejected when the settings are saved
+		return "#" + m.HashtagFormat
 	}
 
-	return hashtag, nil
+	return hashtag
 }
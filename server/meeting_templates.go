@@ -0,0 +1,430 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+)
+
+// meetingTemplatesKeyPrefix prefixes the meeting templates of a team
+const meetingTemplatesKeyPrefix = "meeting_templates_"
+
+// templateNameRegex matches the names of meeting templates, i.e. `retro` or `weekly-1-1`
+var templateNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// templateActionRegex matches the actions of a hashtag format, i.e. {{ .Date "Jan02" }}
+var templateActionRegex = regexp.MustCompile(`\{\{.*?\}\}`)
+
+// MeetingTemplate bundles the settings of a kind of meeting, i.e. a sprint retro, that team admins
+// share with the channels of their team
+type MeetingTemplate struct {
+	Name          string         `json:"name"`
+	Schedule      []time.Weekday `json:"schedule"`
+	HashtagFormat string         `json:"hashtagFormat"`
+	Time          string         `json:"time,omitempty"`
+	Timezone      string         `json:"timezone,omitempty"`
+	Reminder      string         `json:"reminder,omitempty"`
+	QueueCutoff   string         `json:"queueCutoff,omitempty"`
+	EmptyCheck    string         `json:"emptyCheck,omitempty"`
+	StandingItems []string       `json:"standingItems,omitempty"`
+	CreatedBy     string         `json:"createdBy"`
+	UpdateAt      int64          `json:"updateAt"`
+}
+
+// newMeetingTemplate returns a template with the settings of the meeting
+func newMeetingTemplate(name string, meeting *Meeting, channelName string) *MeetingTemplate {
+	return &MeetingTemplate{
+		Name:          name,
+		Schedule:      meeting.Schedule,
+		HashtagFormat: templateHashtagFormat(meeting.HashtagFormat, channelName),
+		Time:          meeting.Time,
+		Timezone:      meeting.Timezone,
+		Reminder:      meeting.Reminder,
+		QueueCutoff:   meeting.QueueCutoff,
+		EmptyCheck:    meeting.EmptyCheck,
+		StandingItems: meeting.StandingItems,
+	}
+}
+
+// templateHashtagFormat returns the hashtag format of a meeting for a template, with the name of
+// its channel replaced by {{ .ChannelName }}, so that each channel set up with the template gets
+// its own hashtags. The default hashtag format starts with the channel name truncated to 15 characters.
+func templateHashtagFormat(format, channelName string) string {
+	if channelName == "" {
+		return format
+	}
+	names := []string{channelName}
+	if truncated := fmt.Sprintf("%.15s", channelName); truncated != channelName {
+		names = append(names, truncated)
+	}
+
+	// Only the text outside of the actions of the template is replaced. Legacy formats are
+	// converted first, as they can only have a single action.
+	converted := hashtagTemplate(format)
+	var sb strings.Builder
+	last := 0
+	for _, action := range templateActionRegex.FindAllStringIndex(converted, -1) {
+		sb.WriteString(replaceChannelName(converted[last:action[0]], names))
+		sb.WriteString(converted[action[0]:action[1]])
+		last = action[1]
+	}
+	sb.WriteString(replaceChannelName(converted[last:], names))
+
+	if sb.String() == converted {
+		return format
+	}
+	return sb.String()
+}
+
+// replaceChannelName replaces the names in the text by {{ .ChannelName }}, when they are not part
+// of a longer word
+func replaceChannelName(text string, names []string) string {
+	for _, name := range names {
+		var sb strings.Builder
+		for {
+			index := strings.Index(text, name)
+			if index == -1 {
+				break
+			}
+			end := index + len(name)
+			if (index > 0 && isNameCharacter(text[index-1])) || (end < len(text) && isNameCharacter(text[end])) {
+				sb.WriteString(text[:end])
+			} else {
+				sb.WriteString(text[:index] + "{{ .ChannelName }}")
+			}
+			text = text[end:]
+		}
+		text = sb.String() + text
+	}
+	return text
+}
+
+func isNameCharacter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9'
+}
+
+// defaultMeetingTemplate returns a template with the default meeting settings of the plugin
+func (p *Plugin) defaultMeetingTemplate(name string) (*MeetingTemplate, error) {
+	config := p.getConfiguration()
+	schedule, err := config.defaultSchedule()
+	if err != nil {
+		return nil, err
+	}
+
+	template := &MeetingTemplate{
+		Name:          name,
+		Schedule:      schedule,
+		HashtagFormat: config.DefaultHashtagFormat,
+		Timezone:      config.DefaultTimezone,
+	}
+	if template.HashtagFormat == "" {
+		template.HashtagFormat = `{{ .ChannelName }}-{{ .Date "Jan02" }}`
+	}
+	return template, nil
+}
+
+// set changes a setting of the template, named like the settings of `/agenda setting`
+func (t *MeetingTemplate) set(field, value string) error {
+	var err error
+	switch field {
+	case "schedule":
+		// Templates can have several meeting days, i.e. mon,thu
+		var schedule []time.Weekday
+		for _, day := range strings.Split(value, ",") {
+			weekday, err := parseSchedule(strings.TrimSpace(day))
+			if err != nil {
+				return err
+			}
+			schedule = append(schedule, weekday)
+		}
+		t.Schedule = schedule
+	case "hashtag":
+		t.HashtagFormat = value
+	case "time":
+		if value == "off" {
+			value = ""
+		} else if _, err = time.Parse(meetingTimeFormat, value); err != nil {
+			return errors.Errorf("Invalid time %s. Must be formatted as HH:MM, i.e. 15:30, or off", value)
+		}
+		t.Time = value
+	case "timezone":
+		if _, err = time.LoadLocation(value); err != nil {
+			return errors.Errorf("Invalid timezone %s. Must be an IANA timezone, i.e. America/New_York", value)
+		}
+		t.Timezone = value
+	case "reminder":
+		t.Reminder, err = parseReminder(value)
+	case "cutoff":
+		t.QueueCutoff, err = parseOptionalDuration(value)
+	case "empty-check":
+		t.EmptyCheck, err = parseOptionalDuration(value)
+	default:
+		return errors.Errorf("Unknown setting %s", field)
+	}
+	return err
+}
+
+// apply sets the settings of the template on the meeting. The rotation, cancelled meetings and
+// name of the meeting are kept.
+func (t *MeetingTemplate) apply(meeting *Meeting) {
+	meeting.Schedule = t.Schedule
+	meeting.HashtagFormat = t.HashtagFormat
+	meeting.Time = t.Time
+	meeting.Timezone = t.Timezone
+	meeting.Reminder = t.Reminder
+	meeting.QueueCutoff = t.QueueCutoff
+	meeting.EmptyCheck = t.EmptyCheck
+	meeting.StandingItems = t.StandingItems
+	meeting.CounterStart = ""
+}
+
+// describe returns a summary of the settings of the template
+func (t *MeetingTemplate) describe() string {
+	days := make([]string, 0, len(t.Schedule))
+	for _, weekday := range t.Schedule {
+		days = append(days, weekday.String())
+	}
+
+	description := fmt.Sprintf("every %s", strings.Join(days, ", "))
+	if t.Time != "" {
+		description += " at " + t.Time
+		if t.Timezone != "" {
+			description += " " + t.Timezone
+		}
+	}
+	description += fmt.Sprintf(", hashtag `%s`", t.HashtagFormat)
+	if len(t.StandingItems) > 0 {
+		description += fmt.Sprintf(", %d standing items", len(t.StandingItems))
+	}
+	return description
+}
+
+// getMeetingTemplates returns the meeting templates of the team, sorted by name
+func (p *Plugin) getMeetingTemplates(teamID string) ([]*MeetingTemplate, error) {
+	templatesBytes, appErr := p.API.KVGet(meetingTemplatesKeyPrefix + teamID)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if templatesBytes == nil {
+		return nil, nil
+	}
+
+	var templates []*MeetingTemplate
+	if err := json.Unmarshal(templatesBytes, &templates); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// saveMeetingTemplates stores the meeting templates of the team
+func (p *Plugin) saveMeetingTemplates(teamID string, templates []*MeetingTemplate) error {
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+
+	templatesBytes, err := json.Marshal(templates)
+	if err != nil {
+		return err
+	}
+	if appErr := p.API.KVSet(meetingTemplatesKeyPrefix+teamID, templatesBytes); appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+// getMeetingTemplate returns the meeting template of the team with the given name
+func (p *Plugin) getMeetingTemplate(teamID, name string) (*MeetingTemplate, error) {
+	templates, err := p.getMeetingTemplates(teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, template := range templates {
+		if template.Name == strings.ToLower(name) {
+			return template, nil
+		}
+	}
+	return nil, errors.Errorf("no meeting template %s exists for this team", name)
+}
+
+// setupMeeting configures the meeting of the channel with the settings of the template
+func (p *Plugin) setupMeeting(channelID string, template *MeetingTemplate) (*Meeting, error) {
+	meeting, err := p.GetMeeting(channelID)
+	if err != nil {
+		return nil, errors.New("failed to get the meeting information of the channel")
+	}
+
+	template.apply(meeting)
+	if err = p.loadChannelName(meeting); err != nil {
+		return nil, errors.New("failed to get the channel of the meeting")
+	}
+	if err = meeting.Validate(p.minimumHashtagLength()); err != nil {
+		return nil, err
+	}
+	if err = p.SaveMeeting(meeting); err != nil {
+		return nil, errors.New("failed to save the meeting settings")
+	}
+
+	return meeting, nil
+}
+
+func (p *Plugin) executeCommandSetup(args *model.CommandArgs) *model.CommandResponse {
+	split := strings.Fields(args.Command)
+
+	name := ""
+	for i := 2; i < len(split); i++ {
+		switch {
+		case split[i] == "--template" && i+1 < len(split):
+			name = split[i+1]
+			i++
+		case strings.HasPrefix(split[i], "--template="):
+			name = strings.TrimPrefix(split[i], "--template=")
+		}
+	}
+
+	if name == "" {
+		templates, err := p.getMeetingTemplates(args.TeamId)
+		if err != nil {
+			return responsef("Error getting the meeting templates of this team")
+		}
+		if len(templates) == 0 {
+			return responsef("No meeting templates exist for this team. Team admins can save the settings of a meeting as a template with `/agenda team-template save <name>`.")
+		}
+		lines := []string{"Missing template. Usage: `/agenda setup --template <name>`", "#### Meeting templates of this team"}
+		for _, template := range templates {
+			lines = append(lines, fmt.Sprintf("* `%s`: %s", template.Name, template.describe()))
+		}
+		return responsef(strings.Join(lines, "\n"))
+	}
+
+	if !p.canManageMeeting(args.ChannelId, args.UserId) {
+		return responsef("You do not have permission to change the meeting settings of this channel")
+	}
+
+	template, err := p.getMeetingTemplate(args.TeamId, name)
+	if err != nil {
+		return responsef(err.Error())
+	}
+
+	if _, err = p.setupMeeting(args.ChannelId, template); err != nil {
+		return responsef("Error setting up the meeting: %s", err.Error())
+	}
+
+	return responsef("Set up the meeting of this channel with the %s template: %s.", template.Name, template.describe())
+}
+
+func (p *Plugin) executeCommandTeamTemplate(args *model.CommandArgs) *model.CommandResponse {
+	split := strings.Fields(args.Command)
+	if len(split) < 3 {
+		return responsef("Missing action. Usage: `/agenda team-template save <name>|set <name> <setting> <value>|remove <name>|list`")
+	}
+
+	templates, err := p.getMeetingTemplates(args.TeamId)
+	if err != nil {
+		return responsef("Error getting the meeting templates of this team")
+	}
+
+	action := split[2]
+	if action == "list" {
+		if len(templates) == 0 {
+			return responsef("No meeting templates exist for this team.")
+		}
+		lines := []string{"#### Meeting templates of this team"}
+		for _, template := range templates {
+			lines = append(lines, fmt.Sprintf("* `%s`: %s", template.Name, template.describe()))
+		}
+		return responsef(strings.Join(lines, "\n"))
+	}
+
+	if action != "save" && action != "set" && action != "remove" {
+		return responsef("Unknown team-template action: %s", action)
+	}
+	if len(split) < 4 {
+		return responsef("Missing template name. Usage: `/agenda team-template %s <name>`", action)
+	}
+	if action == "set" && len(split) < 6 {
+		return responsef("Missing setting. Usage: `/agenda team-template set <name> schedule|hashtag|time|timezone|reminder|cutoff|empty-check <value>`")
+	}
+	if !p.isTeamAdmin(args.ChannelId, args.UserId) {
+		return responsef("Only team admins can manage the meeting templates of this team")
+	}
+
+	name := strings.ToLower(split[3])
+	index := -1
+	for i, template := range templates {
+		if template.Name == name {
+			index = i
+		}
+	}
+
+	if action == "remove" {
+		if index == -1 {
+			return responsef("No meeting template %s exists for this team", name)
+		}
+		if err = p.saveMeetingTemplates(args.TeamId, append(templates[:index], templates[index+1:]...)); err != nil {
+			return responsef("Error removing the meeting template")
+		}
+		return responsef("Removed the meeting template %s.", name)
+	}
+
+	if !templateNameRegex.MatchString(name) {
+		return responsef("Invalid template name %s. Names can have up to 32 lowercase letters, numbers, dashes and underscores.", name)
+	}
+
+	var template *MeetingTemplate
+	var response string
+	if action == "set" {
+		if index == -1 {
+			if template, err = p.defaultMeetingTemplate(name); err != nil {
+				return responsef("Error creating the meeting template")
+			}
+		} else {
+			copied := *templates[index]
+			template = &copied
+		}
+		if err = template.set(split[4], strings.Join(split[5:], " ")); err != nil {
+			return responsef(err.Error())
+		}
+
+		// The template is checked against the meeting of this channel
+		meeting := &Meeting{ChannelID: args.ChannelId}
+		template.apply(meeting)
+		if err = p.loadChannelName(meeting); err != nil {
+			return responsef("Error getting the channel of the meeting")
+		}
+		if err = meeting.Validate(p.minimumHashtagLength()); err != nil {
+			return responsef("Invalid setting: %s", err.Error())
+		}
+		response = fmt.Sprintf("Updated the %s template: %s. Apply it to a channel with `/agenda setup --template %s`.", name, template.describe(), name)
+	} else {
+		meeting, err := p.GetMeeting(args.ChannelId)
+		if err != nil {
+			return responsef("Error getting meeting information for this channel")
+		}
+		channel, appErr := p.API.GetChannel(args.ChannelId)
+		if appErr != nil {
+			return responsef("Error getting the channel of the meeting")
+		}
+
+		template = newMeetingTemplate(name, meeting, channel.Name)
+		response = fmt.Sprintf("Saved the settings of this meeting as the %s template: %s. Apply it to a channel with `/agenda setup --template %s`.", name, template.describe(), name)
+	}
+	template.CreatedBy = args.UserId
+	template.UpdateAt = model.GetMillis()
+	if index == -1 {
+		templates = append(templates, template)
+	} else {
+		templates[index] = template
+	}
+	if err = p.saveMeetingTemplates(args.TeamId, templates); err != nil {
+		return responsef("Error saving the meeting template")
+	}
+
+	return responsef(response)
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+var retroTemplate = &MeetingTemplate{
+	Name:          "retro",
+	Schedule:      []time.Weekday{time.Friday},
+	HashtagFormat: "retro-{{Jan02}}",
+	Time:          "15:00",
+	Timezone:      "Europe/Paris",
+	Reminder:      "1h",
+	StandingItems: []string{"What went well", "What to improve"},
+}
+
+func TestMeetingTemplate_apply(t *testing.T) {
+	meeting := &Meeting{
+		ChannelID:     "channelID",
+		Schedule:      []time.Weekday{time.Thursday},
+		HashtagFormat: "dev-{{Jan02}}",
+		Name:          "Dev sync",
+		SkipDates:     []string{"2022-01-06"},
+		CounterStart:  "2021-12-30",
+		EmptyCheck:    "3h",
+	}
+	retroTemplate.apply(meeting)
+
+	assert.Equal(t, &Meeting{
+		ChannelID:     "channelID",
+		Schedule:      []time.Weekday{time.Friday},
+		HashtagFormat: "retro-{{Jan02}}",
+		Time:          "15:00",
+		Timezone:      "Europe/Paris",
+		Reminder:      "1h",
+		StandingItems: []string{"What went well", "What to improve"},
+		Name:          "Dev sync",
+		SkipDates:     []string{"2022-01-06"},
+	}, meeting)
+	assert.Equal(t, "every Friday at 15:00 Europe/Paris, hashtag `retro-{{Jan02}}`, 2 standing items", retroTemplate.describe())
+}
+
+func TestTemplateHashtagFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		format      string
+		channelName string
+		want        string
+	}{
+		{name: "legacy format", format: "dev-{{Jan02}}", channelName: "dev", want: `{{ .ChannelName }}-{{ .Date "Jan02" }}`},
+		{name: "template", format: `dev-w{{ .ISOWeek }}-dev`, channelName: "dev", want: `{{ .ChannelName }}-w{{ .ISOWeek }}-{{ .ChannelName }}`},
+		{name: "truncated name", format: "platform-engine-{{Jan02}}", channelName: "platform-engineering", want: `{{ .ChannelName }}-{{ .Date "Jan02" }}`},
+		{name: "part of a longer name", format: "devops-{{Jan02}}", channelName: "dev", want: "devops-{{Jan02}}"},
+		{name: "without the channel name", format: "retro-{{Jan02}}", channelName: "dev", want: "retro-{{Jan02}}"},
+		{name: "already a placeholder", format: `{{ .ChannelName }}-{{ .Date "Jan02" }}`, channelName: "dev", want: `{{ .ChannelName }}-{{ .Date "Jan02" }}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, templateHashtagFormat(tt.format, tt.channelName))
+		})
+	}
+}
+
+func TestPlugin_executeCommandTeamTemplate(t *testing.T) {
+	oneOnOne := &MeetingTemplate{Name: "1-1", Schedule: []time.Weekday{time.Monday}, HashtagFormat: "one-{{Jan02}}"}
+	templatesBytes, err := json.Marshal([]*MeetingTemplate{oneOnOne})
+	assert.Nil(t, err)
+	channel := &model.Channel{Id: "channelID", Name: "dev", TeamId: "teamID"}
+
+	t.Run("save", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("KVGet", meetingTemplatesKeyPrefix+"teamID").Return(templatesBytes, nil)
+		api.On("KVGet", "channelID").Return([]byte(`{"channelId":"channelID","schedule":[5],"hashtagFormat":"retro-{{Jan02}}","standingItems":["What went well"]}`), nil)
+		api.On("HasPermissionTo", "teamAdmin", model.PermissionManageSystem).Return(false)
+		api.On("GetChannel", "channelID").Return(channel, nil)
+		api.On("GetTeamMember", "teamID", "teamAdmin").Return(&model.TeamMember{SchemeAdmin: true}, nil)
+		var saved []*MeetingTemplate
+		api.On("KVSet", meetingTemplatesKeyPrefix+"teamID", mock.AnythingOfType("[]uint8")).Run(func(args mock.Arguments) {
+			assert.Nil(t, json.Unmarshal(args.Get(1).([]byte), &saved))
+		}).Return(nil)
+		p := Plugin{}
+		p.SetAPI(api)
+
+		response := p.executeCommandTeamTemplate(&model.CommandArgs{Command: "/agenda team-template save Retro", ChannelId: "channelID", TeamId: "teamID", UserId: "teamAdmin"})
+		assert.Equal(t, "Saved the settings of this meeting as the retro template: every Friday, hashtag `retro-{{Jan02}}`, 1 standing items. Apply it to a channel with `/agenda setup --template retro`.", response.Text)
+
+		assert.Len(t, saved, 2)
+		assert.NotZero(t, saved[1].UpdateAt)
+		saved[1].UpdateAt = 0
+		assert.Equal(t, []*MeetingTemplate{oneOnOne, {
+			Name:          "retro",
+			Schedule:      []time.Weekday{time.Friday},
+			HashtagFormat: "retro-{{Jan02}}",
+			StandingItems: []string{"What went well"},
+			CreatedBy:     "teamAdmin",
+		}}, saved)
+	})
+
+	t.Run("save with the channel name in the hashtag", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("KVGet", meetingTemplatesKeyPrefix+"teamID").Return(nil, nil)
+		api.On("KVGet", "channelID").Return([]byte(`{"channelId":"channelID","schedule":[4],"hashtagFormat":"dev-{{Jan02}}"}`), nil)
+		api.On("HasPermissionTo", "teamAdmin", model.PermissionManageSystem).Return(true)
+		api.On("GetChannel", "channelID").Return(channel, nil)
+		var saved []*MeetingTemplate
+		api.On("KVSet", meetingTemplatesKeyPrefix+"teamID", mock.AnythingOfType("[]uint8")).Run(func(args mock.Arguments) {
+			assert.Nil(t, json.Unmarshal(args.Get(1).([]byte), &saved))
+		}).Return(nil)
+		p := Plugin{}
+		p.SetAPI(api)
+
+		response := p.executeCommandTeamTemplate(&model.CommandArgs{Command: "/agenda team-template save sync", ChannelId: "channelID", TeamId: "teamID", UserId: "teamAdmin"})
+		assert.Equal(t, "Saved the settings of this meeting as the sync template: every Thursday, hashtag `{{ .ChannelName }}-{{ .Date \"Jan02\" }}`. Apply it to a channel with `/agenda setup --template sync`.", response.Text)
+
+		assert.Len(t, saved, 1)
+		assert.Equal(t, `{{ .ChannelName }}-{{ .Date "Jan02" }}`, saved[0].HashtagFormat)
+	})
+
+	t.Run("set a new template", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("KVGet", meetingTemplatesKeyPrefix+"teamID").Return(templatesBytes, nil)
+		api.On("HasPermissionTo", "teamAdmin", model.PermissionManageSystem).Return(false)
+		api.On("GetChannel", "channelID").Return(channel, nil)
+		api.On("GetTeamMember", "teamID", "teamAdmin").Return(&model.TeamMember{SchemeAdmin: true}, nil)
+		api.On("GetConfig").Return(&model.Config{})
+		var saved []*MeetingTemplate
+		api.On("KVSet", meetingTemplatesKeyPrefix+"teamID", mock.AnythingOfType("[]uint8")).Run(func(args mock.Arguments) {
+			assert.Nil(t, json.Unmarshal(args.Get(1).([]byte), &saved))
+		}).Return(nil)
+		p := Plugin{}
+		p.setConfiguration(&configuration{DefaultTimezone: "Europe/Paris"})
+		p.SetAPI(api)
+
+		response := p.executeCommandTeamTemplate(&model.CommandArgs{Command: "/agenda team-template set standup schedule mon, wed", ChannelId: "channelID", TeamId: "teamID", UserId: "teamAdmin"})
+		assert.Equal(t, "Updated the standup template: every Monday, Wednesday, hashtag `{{ .ChannelName }}-{{ .Date \"Jan02\" }}`. Apply it to a channel with `/agenda setup --template standup`.", response.Text)
+
+		assert.Len(t, saved, 2)
+		saved[1].UpdateAt = 0
+		assert.Equal(t, []*MeetingTemplate{oneOnOne, {
+			Name:          "standup",
+			Schedule:      []time.Weekday{time.Monday, time.Wednesday},
+			HashtagFormat: `{{ .ChannelName }}-{{ .Date "Jan02" }}`,
+			Timezone:      "Europe/Paris",
+			CreatedBy:     "teamAdmin",
+		}}, saved)
+	})
+
+	t.Run("set an existing template", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("KVGet", meetingTemplatesKeyPrefix+"teamID").Return(templatesBytes, nil)
+		api.On("HasPermissionTo", "teamAdmin", model.PermissionManageSystem).Return(false)
+		api.On("GetChannel", "channelID").Return(channel, nil)
+		api.On("GetTeamMember", "teamID", "teamAdmin").Return(&model.TeamMember{SchemeAdmin: true}, nil)
+		api.On("GetConfig").Return(&model.Config{})
+		var saved []*MeetingTemplate
+		api.On("KVSet", meetingTemplatesKeyPrefix+"teamID", mock.AnythingOfType("[]uint8")).Run(func(args mock.Arguments) {
+			assert.Nil(t, json.Unmarshal(args.Get(1).([]byte), &saved))
+		}).Return(nil)
+		p := Plugin{}
+		p.setConfiguration(&configuration{})
+		p.SetAPI(api)
+
+		response := p.executeCommandTeamTemplate(&model.CommandArgs{Command: "/agenda team-template set 1-1 time 10:30", ChannelId: "channelID", TeamId: "teamID", UserId: "teamAdmin"})
+		assert.Equal(t, "Updated the 1-1 template: every Monday at 10:30, hashtag `one-{{Jan02}}`. Apply it to a channel with `/agenda setup --template 1-1`.", response.Text)
+
+		assert.Len(t, saved, 1)
+		saved[0].UpdateAt = 0
+		assert.Equal(t, []*MeetingTemplate{{
+			Name:          "1-1",
+			Schedule:      []time.Weekday{time.Monday},
+			HashtagFormat: "one-{{Jan02}}",
+			Time:          "10:30",
+			CreatedBy:     "teamAdmin",
+		}}, saved)
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("KVGet", meetingTemplatesKeyPrefix+"teamID").Return(templatesBytes, nil)
+		api.On("HasPermissionTo", "teamAdmin", model.PermissionManageSystem).Return(false)
+		api.On("GetChannel", "channelID").Return(channel, nil)
+		api.On("GetTeamMember", "teamID", "teamAdmin").Return(&model.TeamMember{SchemeAdmin: true}, nil)
+		api.On("KVSet", meetingTemplatesKeyPrefix+"teamID", []byte(`[]`)).Return(nil)
+		p := Plugin{}
+		p.SetAPI(api)
+
+		response := p.executeCommandTeamTemplate(&model.CommandArgs{Command: "/agenda team-template remove 1-1", ChannelId: "channelID", TeamId: "teamID", UserId: "teamAdmin"})
+		assert.Equal(t, "Removed the meeting template 1-1.", response.Text)
+		api.AssertCalled(t, "KVSet", meetingTemplatesKeyPrefix+"teamID", []byte(`[]`))
+	})
+
+	tests := []struct {
+		name    string
+		command string
+		userID  string
+		want    string
+	}{
+		{name: "list", command: "/agenda team-template list", userID: "member", want: "#### Meeting templates of this team\n* `1-1`: every Monday, hashtag `one-{{Jan02}}`"},
+		{name: "not a team admin", command: "/agenda team-template save retro", userID: "member", want: "Only team admins can manage the meeting templates of this team"},
+		{name: "invalid name", command: "/agenda team-template save sprint/retro", userID: "teamAdmin", want: "Invalid template name sprint/retro. Names can have up to 32 lowercase letters, numbers, dashes and underscores."},
+		{name: "remove an unknown template", command: "/agenda team-template remove retro", userID: "teamAdmin", want: "No meeting template retro exists for this team"},
+		{name: "set without a value", command: "/agenda team-template set retro time", userID: "teamAdmin", want: "Missing setting. Usage: `/agenda team-template set <name> schedule|hashtag|time|timezone|reminder|cutoff|empty-check <value>`"},
+		{name: "set an invalid time", command: "/agenda team-template set 1-1 time 25:00", userID: "teamAdmin", want: "Invalid time 25:00. Must be formatted as HH:MM, i.e. 15:30, or off"},
+		{name: "set an unknown setting", command: "/agenda team-template set 1-1 location Room 1", userID: "teamAdmin", want: "Unknown setting location"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &plugintest.API{}
+			api.On("KVGet", meetingTemplatesKeyPrefix+"teamID").Return(templatesBytes, nil)
+			api.On("HasPermissionTo", tt.userID, model.PermissionManageSystem).Return(false)
+			api.On("GetChannel", "channelID").Return(channel, nil)
+			api.On("GetTeamMember", "teamID", "teamAdmin").Return(&model.TeamMember{SchemeAdmin: true}, nil)
+			api.On("GetTeamMember", "teamID", "member").Return(&model.TeamMember{}, nil)
+			p := Plugin{}
+			p.SetAPI(api)
+
+			response := p.executeCommandTeamTemplate(&model.CommandArgs{Command: tt.command, ChannelId: "channelID", TeamId: "teamID", UserId: tt.userID})
+			assert.Equal(t, tt.want, response.Text)
+			api.AssertNotCalled(t, "KVSet", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestPlugin_executeCommandSetup(t *testing.T) {
+	templatesBytes, err := json.Marshal([]*MeetingTemplate{retroTemplate})
+	assert.Nil(t, err)
+	// The settings of the template replace those of the meeting, which keeps its name
+	savedMeeting, err := json.Marshal(&Meeting{
+		ChannelID:     "channelID",
+		Schedule:      []time.Weekday{time.Friday},
+		HashtagFormat: "retro-{{Jan02}}",
+		Time:          "15:00",
+		Timezone:      "Europe/Paris",
+		Reminder:      "1h",
+		Name:          "Dev retro",
+		StandingItems: []string{"What went well", "What to improve"},
+	})
+	assert.Nil(t, err)
+
+	api := &plugintest.API{}
+	api.On("KVGet", meetingTemplatesKeyPrefix+"teamID").Return(templatesBytes, nil)
+	api.On("KVGet", meetingTemplatesKeyPrefix+"otherTeamID").Return(nil, nil)
+	api.On("KVGet", "channelID").Return([]byte(`{"channelId":"channelID","schedule":[4],"hashtagFormat":"dev-{{Jan02}}","name":"Dev retro"}`), nil)
+	api.On("GetChannelMember", "channelID", "member").Return(&model.ChannelMember{}, nil)
+	api.On("HasPermissionTo", "member", model.PermissionManageSystem).Return(false)
+	api.On("KVSet", "channelID", savedMeeting).Return(nil)
+	api.On("GetConfig").Return(&model.Config{})
+
+	p := Plugin{}
+	p.setConfiguration(&configuration{})
+	p.SetAPI(api)
+
+	response := p.executeCommandSetup(&model.CommandArgs{Command: "/agenda setup --template Retro", ChannelId: "channelID", TeamId: "teamID", UserId: "member"})
+	assert.Equal(t, "Set up the meeting of this channel with the retro template: every Friday at 15:00 Europe/Paris, hashtag `retro-{{Jan02}}`, 2 standing items.", response.Text)
+	api.AssertCalled(t, "KVSet", "channelID", savedMeeting)
+
+	response = p.executeCommandSetup(&model.CommandArgs{Command: "/agenda setup --template=incident", ChannelId: "channelID", TeamId: "teamID", UserId: "member"})
+	assert.Equal(t, "no meeting template incident exists for this team", response.Text)
+
+	response = p.executeCommandSetup(&model.CommandArgs{Command: "/agenda setup", ChannelId: "channelID", TeamId: "teamID", UserId: "member"})
+	assert.Equal(t, "Missing template. Usage: `/agenda setup --template <name>`\n#### Meeting templates of this team\n* `retro`: every Friday at 15:00 Europe/Paris, hashtag `retro-{{Jan02}}`, 2 standing items", response.Text)
+
+	response = p.executeCommandSetup(&model.CommandArgs{Command: "/agenda setup", ChannelId: "channelID", TeamId: "otherTeamID", UserId: "member"})
+	assert.Contains(t, response.Text, "No meeting templates exist for this team.")
+}
@@ -13,7 +13,7 @@ import (
 )
 
 func assertNextWeekdayDate(meetingDay time.Weekday, nextWeek bool) *time.Time {
-	weekDay, err := nextWeekdayDate(meetingDay, nextWeek)
+	weekDay, err := nextWeekdayDate(time.Now(), meetingDay, nextWeek)
 	if err != nil {
 		panic(err)
 	}
@@ -227,3 +227,220 @@ func GenerateFakeChannel(channelID, name string) (channel *model.Channel, appErr
 	}
 	return
 }
+
+func TestMeeting_nextOccurrence(t *testing.T) {
+	meeting := &Meeting{
+		Schedule: []time.Weekday{time.Monday, time.Thursday},
+		Time:     "15:00",
+		Timezone: "America/New_York",
+	}
+	loc, err := time.LoadLocation("America/New_York")
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name  string
+		after time.Time
+		want  time.Time
+	}{
+		{
+			name:  "later the same day",
+			after: time.Date(2022, 1, 6, 10, 0, 0, 0, loc),
+			want:  time.Date(2022, 1, 6, 15, 0, 0, 0, loc),
+		},
+		{
+			name:  "after the meeting started",
+			after: time.Date(2022, 1, 6, 15, 1, 0, 0, loc),
+			want:  time.Date(2022, 1, 10, 15, 0, 0, 0, loc),
+		},
+		{
+			name:  "from another timezone",
+			after: time.Date(2022, 1, 7, 1, 0, 0, 0, time.UTC),
+			want:  time.Date(2022, 1, 10, 15, 0, 0, 0, loc),
+		},
+		{
+			name:  "a week ahead",
+			after: time.Date(2022, 1, 10, 16, 0, 0, 0, loc),
+			want:  time.Date(2022, 1, 13, 15, 0, 0, 0, loc),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := meeting.nextOccurrence(tt.after)
+			assert.Nil(t, err)
+			assert.True(t, tt.want.Equal(got), "got %v, want %v", got, tt.want)
+		})
+	}
+
+	_, err = (&Meeting{}).nextOccurrence(time.Now())
+	assert.NotNil(t, err)
+}
+
+func TestMeeting_upcomingOccurrence(t *testing.T) {
+	meeting := &Meeting{
+		Schedule: []time.Weekday{time.Monday, time.Thursday},
+		Time:     "07:30",
+		Timezone: "Asia/Tokyo",
+	}
+	loc, err := time.LoadLocation("Asia/Tokyo")
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		nextWeek bool
+		weekday  int
+		skipped  []string
+		want     time.Time
+	}{
+		{
+			// Wednesday in UTC, but already Thursday after the meeting in Tokyo
+			name:    "next meeting in the timezone of the meeting",
+			now:     time.Date(2022, 1, 5, 23, 0, 0, 0, time.UTC),
+			weekday: -1,
+			want:    time.Date(2022, 1, 10, 7, 30, 0, 0, loc),
+		},
+		{
+			name:    "given weekday",
+			now:     time.Date(2022, 1, 5, 23, 0, 0, 0, time.UTC),
+			weekday: int(time.Monday),
+			want:    time.Date(2022, 1, 10, 7, 30, 0, 0, loc),
+		},
+		{
+			name:     "next week",
+			now:      time.Date(2022, 1, 4, 12, 0, 0, 0, loc),
+			nextWeek: true,
+			weekday:  -1,
+			want:     time.Date(2022, 1, 13, 7, 30, 0, 0, loc),
+		},
+		{
+			name:    "cancelled meeting",
+			now:     time.Date(2022, 1, 11, 12, 0, 0, 0, loc),
+			weekday: -1,
+			skipped: []string{"2022-01-13"},
+			want:    time.Date(2022, 1, 17, 7, 30, 0, 0, loc),
+		},
+		{
+			name:    "given weekday of a cancelled meeting",
+			now:     time.Date(2022, 1, 11, 12, 0, 0, 0, loc),
+			weekday: int(time.Thursday),
+			skipped: []string{"2022-01-13"},
+			want:    time.Date(2022, 1, 17, 7, 30, 0, 0, loc),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meeting.SkipDates = tt.skipped
+			got, err := meeting.upcomingOccurrence(tt.now, tt.nextWeek, tt.weekday)
+			assert.Nil(t, err)
+			assert.True(t, tt.want.Equal(got), "got %v, want %v", got, tt.want)
+		})
+	}
+}
+
+func TestMeeting_isPast(t *testing.T) {
+	thursday := time.Date(2022, 1, 6, 0, 0, 0, 0, time.UTC)
+	atThree := thursday.Add(15 * time.Hour)
+
+	allDay := &Meeting{Schedule: []time.Weekday{time.Thursday}, Timezone: "UTC"}
+	assert.False(t, allDay.isPast(thursday, thursday.Add(23*time.Hour)))
+	assert.True(t, allDay.isPast(thursday, thursday.AddDate(0, 0, 1)))
+
+	timed := &Meeting{Schedule: []time.Weekday{time.Thursday}, Time: "15:00", Timezone: "UTC"}
+	assert.False(t, timed.isPast(atThree, atThree))
+	assert.True(t, timed.isPast(atThree, atThree.Add(time.Minute)))
+}
+
+func TestMeeting_queueDateError(t *testing.T) {
+	meeting := &Meeting{
+		Schedule:    []time.Weekday{time.Thursday},
+		Time:        "15:00",
+		Timezone:    "UTC",
+		QueueCutoff: "2h0m0s",
+		SkipDates:   []string{"2022-01-13"},
+	}
+	now := time.Date(2022, 1, 6, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		start time.Time
+		want  string
+	}{
+		{name: "upcoming", start: time.Date(2022, 1, 6, 15, 0, 0, 0, time.UTC), want: ""},
+		{name: "not scheduled", start: time.Date(2022, 1, 7, 15, 0, 0, 0, time.UTC), want: "The meeting of this channel is not held on Friday."},
+		{name: "past", start: time.Date(2021, 12, 30, 15, 0, 0, 0, time.UTC), want: "The meeting of this date has already started."},
+		{name: "cancelled", start: time.Date(2022, 1, 13, 15, 0, 0, 0, time.UTC), want: "The meeting of this date is cancelled."},
+		{name: "frozen", start: time.Date(2022, 1, 6, 11, 0, 0, 0, time.UTC), want: "The agenda of the meeting of this date is frozen."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, meeting.queueDateError(tt.start, now))
+		})
+	}
+}
+
+func TestMeeting_queueOccurrence(t *testing.T) {
+	meeting := &Meeting{
+		Schedule:    []time.Weekday{time.Monday, time.Thursday},
+		Time:        "15:00",
+		Timezone:    "UTC",
+		QueueCutoff: "2h0m0s",
+	}
+	thursday := time.Date(2022, 1, 6, 15, 0, 0, 0, time.UTC)
+	monday := time.Date(2022, 1, 10, 15, 0, 0, 0, time.UTC)
+
+	t.Run("before the cutoff", func(t *testing.T) {
+		start, frozen, err := meeting.queueOccurrence(time.Date(2022, 1, 6, 12, 0, 0, 0, time.UTC), false, -1, false)
+		assert.Nil(t, err)
+		assert.Nil(t, frozen)
+		assert.True(t, thursday.Equal(start))
+	})
+
+	t.Run("after the cutoff", func(t *testing.T) {
+		start, frozen, err := meeting.queueOccurrence(time.Date(2022, 1, 6, 13, 30, 0, 0, time.UTC), false, -1, false)
+		assert.Nil(t, err)
+		assert.NotNil(t, frozen)
+		assert.True(t, thursday.Equal(*frozen))
+		assert.True(t, monday.Equal(start))
+	})
+
+	t.Run("forced after the cutoff", func(t *testing.T) {
+		start, frozen, err := meeting.queueOccurrence(time.Date(2022, 1, 6, 13, 30, 0, 0, time.UTC), false, -1, true)
+		assert.Nil(t, err)
+		assert.Nil(t, frozen)
+		assert.True(t, thursday.Equal(start))
+	})
+
+	t.Run("without cutoff", func(t *testing.T) {
+		noCutoff := *meeting
+		noCutoff.QueueCutoff = ""
+		start, frozen, err := noCutoff.queueOccurrence(time.Date(2022, 1, 6, 14, 59, 0, 0, time.UTC), false, -1, false)
+		assert.Nil(t, err)
+		assert.Nil(t, frozen)
+		assert.True(t, thursday.Equal(start))
+	})
+
+	t.Run("meeting without time is upcoming all day", func(t *testing.T) {
+		allDay := &Meeting{Schedule: []time.Weekday{time.Thursday}, Timezone: "UTC"}
+		start, frozen, err := allDay.queueOccurrence(time.Date(2022, 1, 6, 22, 0, 0, 0, time.UTC), false, -1, false)
+		assert.Nil(t, err)
+		assert.Nil(t, frozen)
+		assert.True(t, time.Date(2022, 1, 6, 0, 0, 0, 0, time.UTC).Equal(start))
+	})
+}
+
+func TestMeeting_skipOccurrence(t *testing.T) {
+	meeting := &Meeting{
+		Schedule: []time.Weekday{time.Thursday},
+		Time:     "15:00",
+		Timezone: "UTC",
+	}
+
+	assert.True(t, meeting.skipOccurrence("2022-01-13"))
+	assert.True(t, meeting.skipOccurrence("2022-01-06"))
+	assert.False(t, meeting.skipOccurrence("2022-01-06"))
+	assert.Equal(t, []string{"2022-01-06", "2022-01-13"}, meeting.SkipDates)
+
+	start, err := meeting.nextOccurrence(time.Date(2022, 1, 4, 0, 0, 0, 0, time.UTC))
+	assert.Nil(t, err)
+	assert.True(t, time.Date(2022, 1, 20, 15, 0, 0, 0, time.UTC).Equal(start))
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+const (
+	settingsPermissionMember       = "member"
+	settingsPermissionChannelAdmin = "channel_admin"
+	settingsPermissionTeamAdmin    = "team_admin"
+)
+
+// isChannelMember returns true if the user is a member of the channel
+func (p *Plugin) isChannelMember(channelID, userID string) bool {
+	if channelID == "" || userID == "" {
+		return false
+	}
+	_, appErr := p.API.GetChannelMember(channelID, userID)
+	return appErr == nil
+}
+
+// isChannelAdmin returns true if the user is an admin of the channel or a system admin
+func (p *Plugin) isChannelAdmin(channelID, userID string) bool {
+	if p.API.HasPermissionTo(userID, model.PermissionManageSystem) {
+		return true
+	}
+	member, appErr := p.API.GetChannelMember(channelID, userID)
+	if appErr != nil {
+		return false
+	}
+	return member.SchemeAdmin
+}
+
+// isTeamAdmin returns true if the user is an admin of the team of the channel or a system admin
+func (p *Plugin) isTeamAdmin(channelID, userID string) bool {
+	if p.API.HasPermissionTo(userID, model.PermissionManageSystem) {
+		return true
+	}
+	channel, appErr := p.API.GetChannel(channelID)
+	if appErr != nil {
+		return false
+	}
+	member, appErr := p.API.GetTeamMember(channel.TeamId, userID)
+	if appErr != nil {
+		return false
+	}
+	return member.SchemeAdmin
+}
+
+// canManageMeeting returns true if the user can change the meeting settings of the channel,
+// based on the role required by the plugin configuration. System admins can change them without
+// being members of the channel.
+func (p *Plugin) canManageMeeting(channelID, userID string) bool {
+	if channelID == "" || userID == "" {
+		return false
+	}
+	if p.API.HasPermissionTo(userID, model.PermissionManageSystem) {
+		return true
+	}
+	if !p.isChannelMember(channelID, userID) {
+		return false
+	}
+
+	switch p.getConfiguration().SettingsPermission {
+	case settingsPermissionTeamAdmin:
+		return p.isTeamAdmin(channelID, userID)
+	case settingsPermissionChannelAdmin:
+		return p.isChannelAdmin(channelID, userID) || p.isTeamAdmin(channelID, userID)
+	default:
+		return true
+	}
+}
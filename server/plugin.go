@@ -5,11 +5,14 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 
 	pluginapi "github.com/mattermost/mattermost-plugin-api"
+	"github.com/mattermost/mattermost-plugin-api/cluster"
 	"github.com/mattermost/mattermost-server/v6/model"
 	"github.com/mattermost/mattermost-server/v6/plugin"
 
@@ -29,6 +32,12 @@ type Plugin struct {
 
 	// BotId of the created bot account.
 	botID string
+
+	// reminderJob posts the pre-meeting reminders and empty agenda checks.
+	reminderJob *cluster.Job
+
+	// stopWebhooks is closed when the plugin is deactivated, to stop retrying webhook deliveries.
+	stopWebhooks chan struct{}
 }
 
 var (
@@ -46,7 +55,35 @@ func (p *Plugin) ServeHTTP(c *plugin.Context, w http.ResponseWriter, r *http.Req
 		p.httpMeetingDaysAutocomplete(w, r, false)
 	case "/api/v1/list-meeting-days-autocomplete":
 		p.httpMeetingDaysAutocomplete(w, r, true)
+	case "/api/v1/attendance/join":
+		p.httpAttendanceJoin(w, r)
+	case "/api/v1/attendance":
+		p.httpAttendanceReport(w, r)
+	case "/api/v1/agenda/open":
+		p.httpOpenAgenda(w, r)
+	case "/api/v1/empty-agenda":
+		p.httpEmptyAgendaAction(w, r)
+	case "/api/v1/hashtag-preview":
+		p.httpHashtagPreview(w, r)
+	case "/api/v1/doctor/repair":
+		p.httpDoctorRepair(w, r)
+	case "/api/v1/queue-post":
+		p.httpQueuePost(w, r)
+	case "/api/v1/queue-dialog":
+		p.httpQueueDialog(w, r)
+	case "/api/v1/import-ics":
+		p.httpImportICS(w, r)
+	case "/api/v1/export":
+		p.httpExport(w, r)
 	default:
+		if strings.HasPrefix(path, "/api/v1/channels/") {
+			p.httpChannelRoutes(w, r)
+			return
+		}
+		if strings.HasPrefix(path, "/api/v1/hooks/") {
+			p.httpIncomingHook(w, r)
+			return
+		}
 		http.NotFound(w, r)
 	}
 }
@@ -68,6 +105,27 @@ func (p *Plugin) OnActivate() error {
 	}
 	p.botID = botID
 
+	job, err := cluster.Schedule(p.API, reminderJobKey, cluster.MakeWaitForRoundedInterval(time.Minute), p.runReminderJob)
+	if err != nil {
+		return errors.Wrap(err, "failed to schedule reminder job")
+	}
+	p.reminderJob = job
+	p.stopWebhooks = make(chan struct{})
+
+	return nil
+}
+
+// OnDeactivate is invoked when the plugin is deactivated
+func (p *Plugin) OnDeactivate() error {
+	if p.reminderJob != nil {
+		if err := p.reminderJob.Close(); err != nil {
+			p.API.LogWarn("Failed to close reminder job", "error", err.Error())
+		}
+	}
+	if p.stopWebhooks != nil {
+		close(p.stopWebhooks)
+	}
+
 	return nil
 }
 
@@ -75,6 +133,7 @@ func (p *Plugin) httpMeetingSettings(w http.ResponseWriter, r *http.Request) {
 	mattermostUserID := r.Header.Get("Mattermost-User-Id")
 	if mattermostUserID == "" {
 		http.Error(w, "Not Authorized", http.StatusUnauthorized)
+		return
 	}
 
 	switch r.Method {
@@ -94,12 +153,45 @@ func (p *Plugin) httpMeetingSaveSettings(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
-	var meeting *Meeting
-	if err = json.Unmarshal(body, &meeting); err != nil {
+	var settings *Meeting
+	if err = json.Unmarshal(body, &settings); err != nil || settings == nil {
+		http.Error(w, "Invalid meeting settings", http.StatusBadRequest)
+		return
+	}
+
+	if !p.canManageMeeting(settings.ChannelID, mmUserID) {
+		http.Error(w, "Not Authorized", http.StatusForbidden)
+		return
+	}
+
+	// Only the settings of the dialog are taken from the request. The cancelled meetings, rotation,
+	// counter and standing items are changed by their own commands.
+	meeting, err := p.GetMeeting(settings.ChannelID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	meeting.Schedule = settings.Schedule
+	meeting.HashtagFormat = settings.HashtagFormat
+	meeting.Name = settings.Name
+	meeting.Time = settings.Time
+	meeting.Timezone = settings.Timezone
+	meeting.Reminder = settings.Reminder
+	meeting.QueueCutoff = settings.QueueCutoff
+	meeting.EmptyCheck = settings.EmptyCheck
+	meeting.PostAs = settings.PostAs
+
+	if err = p.loadChannelName(meeting); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	if err = meeting.Validate(p.minimumHashtagLength()); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		p.writeJSON(w, err)
+		return
+	}
+
 	if err = p.SaveMeeting(meeting); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -120,6 +212,11 @@ func (p *Plugin) httpMeetingGetSettings(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
+	if !p.isChannelMember(channelID[0], mmUserID) {
+		http.Error(w, "Not Authorized", http.StatusForbidden)
+		return
+	}
+
 	meeting, err := p.GetMeeting(channelID[0])
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
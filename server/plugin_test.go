@@ -9,7 +9,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/mattermost/mattermost-server/v6/model"
 	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -19,6 +21,10 @@ func TestServeHTTP(t *testing.T) {
 	api := &plugintest.API{}
 	plugin.SetAPI(api)
 
+	api.On("GetChannelMember", "myChannelId", "theuserid").Return(&model.ChannelMember{}, nil)
+	api.On("HasPermissionTo", "theuserid", model.PermissionManageSystem).Return(false)
+	api.On("GetConfig").Return(&model.Config{})
+
 	t.Run("get default meeting settings", func(t *testing.T) {
 		// Mock get default meeting
 		defaultMeeting := &Meeting{
@@ -69,4 +75,259 @@ func TestServeHTTP(t *testing.T) {
 		assert.NotNil(result)
 		assert.Equal(http.StatusOK, result.StatusCode)
 	})
+
+	t.Run("post invalid meeting settings", func(t *testing.T) {
+		meeting := &Meeting{
+			ChannelID:     "myChannelId",
+			Schedule:      []time.Weekday{},
+			HashtagFormat: "My Meeting {{Jan 02}}",
+		}
+
+		jsonMeeting, err := json.Marshal(meeting)
+		assert.Nil(err)
+
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/settings", strings.NewReader(string(jsonMeeting)))
+		r.Header.Add("Mattermost-User-Id", "theuserid")
+
+		w := httptest.NewRecorder()
+		plugin.ServeHTTP(nil, w, r)
+
+		result := w.Result()
+		assert.Equal(http.StatusBadRequest, result.StatusCode)
+
+		var validationErr *ValidationError
+		assert.Nil(json.NewDecoder(result.Body).Decode(&validationErr))
+		assert.Contains(validationErr.Fields, "schedule")
+		assert.Contains(validationErr.Fields, "hashtagFormat")
+		api.AssertNotCalled(t, "KVSet", "myChannelId", jsonMeeting)
+	})
+}
+
+func TestServeHTTPSettingsUnauthorized(t *testing.T) {
+	assert := assert.New(t)
+
+	meeting := &Meeting{
+		ChannelID:     "myChannelId",
+		Schedule:      []time.Weekday{time.Tuesday},
+		HashtagFormat: "MyMeeting-{{Jan02}}",
+	}
+	jsonMeeting, err := json.Marshal(meeting)
+	assert.Nil(err)
+
+	storedMeeting := &Meeting{
+		ChannelID:     "myChannelId",
+		Schedule:      []time.Weekday{time.Thursday},
+		HashtagFormat: "MyMeeting-{{Jan02}}",
+		Rotation:      &Rotation{Users: []string{"member", "channeladmin"}, Index: 1, Occurrence: "2022-01-06"},
+		SkipDates:     []string{"2022-01-13"},
+		CounterStart:  "2021-12-30",
+		StandingItems: []string{"Incidents"},
+	}
+	jsonStoredMeeting, err := json.Marshal(storedMeeting)
+	assert.Nil(err)
+
+	notFound := model.NewAppError("GetChannelMember", "app.channel.get_member.missing.app_error", nil, "", http.StatusNotFound)
+
+	setupPlugin := func(settingsPermission string) (*Plugin, *plugintest.API) {
+		api := &plugintest.API{}
+		api.On("GetChannelMember", "myChannelId", "member").Return(&model.ChannelMember{}, nil)
+		api.On("GetChannelMember", "myChannelId", "channeladmin").Return(&model.ChannelMember{SchemeAdmin: true}, nil)
+		api.On("GetChannelMember", "myChannelId", "outsider").Return(nil, notFound)
+		api.On("GetChannelMember", "myChannelId", "sysadmin").Return(nil, notFound)
+		api.On("HasPermissionTo", "sysadmin", model.PermissionManageSystem).Return(true)
+		api.On("HasPermissionTo", mock.Anything, model.PermissionManageSystem).Return(false)
+		api.On("KVGet", "myChannelId").Return(jsonStoredMeeting, nil)
+		api.On("GetChannel", "myChannelId").Return(&model.Channel{Id: "myChannelId", TeamId: "myTeamId"}, nil)
+		api.On("GetTeamMember", "myTeamId", mock.Anything).Return(&model.TeamMember{}, nil)
+		api.On("GetConfig").Return(&model.Config{})
+
+		plugin := &Plugin{}
+		plugin.SetAPI(api)
+		plugin.setConfiguration(&configuration{SettingsPermission: settingsPermission})
+		return plugin, api
+	}
+
+	serve := func(plugin *Plugin, method, userID string) *http.Response {
+		var r *http.Request
+		if method == http.MethodGet {
+			r = httptest.NewRequest(http.MethodGet, "/api/v1/settings?channelId=myChannelId", nil)
+		} else {
+			r = httptest.NewRequest(http.MethodPost, "/api/v1/settings", strings.NewReader(string(jsonMeeting)))
+		}
+		if userID != "" {
+			r.Header.Add("Mattermost-User-Id", userID)
+		}
+
+		w := httptest.NewRecorder()
+		plugin.ServeHTTP(nil, w, r)
+		return w.Result()
+	}
+
+	t.Run("missing user stops at 401", func(t *testing.T) {
+		plugin, api := setupPlugin(settingsPermissionMember)
+
+		assert.Equal(http.StatusUnauthorized, serve(plugin, http.MethodGet, "").StatusCode)
+		assert.Equal(http.StatusUnauthorized, serve(plugin, http.MethodPost, "").StatusCode)
+		api.AssertNotCalled(t, "KVGet", mock.Anything)
+		api.AssertNotCalled(t, "KVSet", mock.Anything, mock.Anything)
+	})
+
+	t.Run("non member cannot read settings", func(t *testing.T) {
+		plugin, api := setupPlugin(settingsPermissionMember)
+
+		assert.Equal(http.StatusForbidden, serve(plugin, http.MethodGet, "outsider").StatusCode)
+		api.AssertNotCalled(t, "KVGet", mock.Anything)
+	})
+
+	t.Run("non member cannot save settings", func(t *testing.T) {
+		plugin, api := setupPlugin(settingsPermissionMember)
+
+		assert.Equal(http.StatusForbidden, serve(plugin, http.MethodPost, "outsider").StatusCode)
+		api.AssertNotCalled(t, "KVSet", mock.Anything, mock.Anything)
+	})
+
+	t.Run("member cannot save settings restricted to channel admins", func(t *testing.T) {
+		plugin, api := setupPlugin(settingsPermissionChannelAdmin)
+
+		assert.Equal(http.StatusForbidden, serve(plugin, http.MethodPost, "member").StatusCode)
+		api.AssertNotCalled(t, "KVSet", mock.Anything, mock.Anything)
+	})
+
+	// The settings of the request are saved on the stored meeting, keeping its rotation,
+	// cancelled meetings, counter and standing items
+	savedMeeting := *storedMeeting
+	savedMeeting.Schedule = meeting.Schedule
+	savedMeeting.HashtagFormat = meeting.HashtagFormat
+	jsonSavedMeeting, err := json.Marshal(&savedMeeting)
+	assert.Nil(err)
+
+	t.Run("channel admin can save settings restricted to channel admins", func(t *testing.T) {
+		plugin, api := setupPlugin(settingsPermissionChannelAdmin)
+		api.On("KVSet", "myChannelId", jsonSavedMeeting).Return(nil)
+
+		assert.Equal(http.StatusOK, serve(plugin, http.MethodPost, "channeladmin").StatusCode)
+		api.AssertCalled(t, "KVSet", "myChannelId", jsonSavedMeeting)
+	})
+
+	t.Run("system admin can save settings without being a member", func(t *testing.T) {
+		plugin, api := setupPlugin(settingsPermissionTeamAdmin)
+		api.On("KVSet", "myChannelId", jsonSavedMeeting).Return(nil)
+
+		assert.Equal(http.StatusOK, serve(plugin, http.MethodPost, "sysadmin").StatusCode)
+		api.AssertCalled(t, "KVSet", "myChannelId", jsonSavedMeeting)
+	})
+
+	t.Run("fields outside of the settings are not taken from the request", func(t *testing.T) {
+		plugin, api := setupPlugin(settingsPermissionMember)
+		api.On("KVSet", "myChannelId", jsonSavedMeeting).Return(nil)
+
+		request := *meeting
+		request.Rotation = &Rotation{Users: []string{"member"}}
+		request.SkipDates = []string{}
+		request.CounterStart = "2022-01-06"
+		request.StandingItems = []string{"Something else"}
+		jsonRequest, err := json.Marshal(&request)
+		assert.Nil(err)
+
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/settings", strings.NewReader(string(jsonRequest)))
+		r.Header.Add("Mattermost-User-Id", "member")
+		w := httptest.NewRecorder()
+		plugin.ServeHTTP(nil, w, r)
+
+		assert.Equal(http.StatusOK, w.Result().StatusCode)
+		api.AssertCalled(t, "KVSet", "myChannelId", jsonSavedMeeting)
+	})
+
+	t.Run("channel admin cannot save settings restricted to team admins", func(t *testing.T) {
+		plugin, api := setupPlugin(settingsPermissionTeamAdmin)
+
+		assert.Equal(http.StatusForbidden, serve(plugin, http.MethodPost, "channeladmin").StatusCode)
+		api.AssertNotCalled(t, "KVSet", mock.Anything, mock.Anything)
+	})
+}
+
+func TestExecuteCommandSettingUnauthorized(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("GetChannelMember", "myChannelId", "member").Return(&model.ChannelMember{}, nil)
+	api.On("HasPermissionTo", mock.Anything, model.PermissionManageSystem).Return(false)
+	api.On("GetChannel", "myChannelId").Return(&model.Channel{Id: "myChannelId", TeamId: "myTeamId"}, nil)
+	api.On("GetTeamMember", "myTeamId", "member").Return(&model.TeamMember{}, nil)
+
+	plugin := &Plugin{}
+	plugin.SetAPI(api)
+	plugin.setConfiguration(&configuration{SettingsPermission: settingsPermissionChannelAdmin})
+
+	response, appErr := plugin.ExecuteCommand(nil, &model.CommandArgs{
+		Command:   "/agenda setting schedule Monday",
+		ChannelId: "myChannelId",
+		UserId:    "member",
+	})
+	assert.Nil(t, appErr)
+	assert.Equal(t, "You do not have permission to change the meeting settings of this channel", response.Text)
+	api.AssertNotCalled(t, "KVSet", mock.Anything, mock.Anything)
+}
+
+func TestExecuteCommandSettingPostAs(t *testing.T) {
+	tests := []struct {
+		value      string
+		wantText   string
+		wantPostAs string
+	}{
+		{value: "bot", wantText: "Updated setting post-as to bot", wantPostAs: `"postAs":"bot"`},
+		{value: "default", wantText: "Updated setting post-as to default", wantPostAs: `"schedule"`},
+		{value: "admin", wantText: "Invalid value admin. Must be user, bot or default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			api := &plugintest.API{}
+			api.On("GetChannelMember", "myChannelId", "member").Return(&model.ChannelMember{}, nil)
+			api.On("HasPermissionTo", "member", model.PermissionManageSystem).Return(false)
+			api.On("KVGet", "myChannelId").Return([]byte(`{"channelId":"myChannelId","schedule":[4],"hashtagFormat":"dev-{{Jan02}}","postAs":"user"}`), nil)
+			api.On("KVSet", "myChannelId", mock.Anything).Return(nil)
+			api.On("GetConfig").Return(&model.Config{})
+
+			plugin := &Plugin{}
+			plugin.SetAPI(api)
+			plugin.setConfiguration(&configuration{})
+
+			response, appErr := plugin.ExecuteCommand(nil, &model.CommandArgs{
+				Command:   "/agenda setting post-as " + tt.value,
+				ChannelId: "myChannelId",
+				UserId:    "member",
+			})
+			assert.Nil(t, appErr)
+			assert.Contains(t, response.Text, tt.wantText)
+			if tt.wantPostAs == "" {
+				api.AssertNotCalled(t, "KVSet", mock.Anything, mock.Anything)
+				return
+			}
+			api.AssertCalled(t, "KVSet", "myChannelId", mock.MatchedBy(func(value []byte) bool {
+				return strings.Contains(string(value), tt.wantPostAs) && (tt.value != "default" || !strings.Contains(string(value), "postAs"))
+			}))
+		})
+	}
+}
+
+func TestExecuteCommandQueueKeepsFormatting(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("KVGet", "myChannelId").Return([]byte(`{"channelId":"myChannelId","schedule":[1,2,3,4,5],"hashtagFormat":"dev-{{Jan02}}"}`), nil)
+	api.On("KVGet", webhooksKeyPrefix+"myChannelId").Return(nil, nil)
+	api.On("GetChannel", "myChannelId").Return(&model.Channel{Id: "myChannelId", Name: "dev", TeamId: "myTeamId"}, nil)
+	api.On("SearchPostsInTeamForUser", "myTeamId", "theuserid", mock.Anything).Return(model.MakePostSearchResults(model.NewPostList(), nil), nil)
+	api.On("CreatePost", mock.Anything).Return(&model.Post{Id: "newPost"}, nil)
+
+	plugin := &Plugin{}
+	plugin.SetAPI(api)
+
+	response, appErr := plugin.ExecuteCommand(nil, &model.CommandArgs{
+		Command:   "/agenda queue Mon Release status\n- backend is done\n- ```go\n  webapp\n  ```",
+		ChannelId: "myChannelId",
+		TeamId:    "myTeamId",
+		UserId:    "theuserid",
+	})
+	assert.Nil(t, appErr)
+	assert.Contains(t, response.Text, "Queued item 1")
+	api.AssertCalled(t, "CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return strings.HasSuffix(post.Message, " 1) Release status\n- backend is done\n- ```go\n  webapp\n  ```")
+	}))
 }
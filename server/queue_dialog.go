@@ -0,0 +1,292 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+const (
+	// queueDialogTitleLength is the maximum length of the title of an item queued with the dialog
+	queueDialogTitleLength = 200
+	// queueDialogDetailsLength is the maximum length of the details of an item queued with the dialog
+	queueDialogDetailsLength = 3000
+)
+
+// queueDates returns the start of the meeting occurrences items can be queued for: the next meeting
+// of each day of the schedule and the first meeting of next week, as offered by the meeting days
+// autocomplete, sorted by date.
+func (m *Meeting) queueDates(now time.Time) []time.Time {
+	var dates []time.Time
+	seen := map[string]bool{}
+	add := func(nextWeek bool, weekday int) {
+		start, _, err := m.queueOccurrence(now, nextWeek, weekday, false)
+		if err != nil || seen[start.Format(occurrenceDateFormat)] {
+			return
+		}
+		seen[start.Format(occurrenceDateFormat)] = true
+		dates = append(dates, start)
+	}
+
+	add(false, -1)
+	for _, weekday := range m.Schedule {
+		add(false, int(weekday))
+	}
+	add(true, -1)
+
+	sort.Slice(dates, func(i, j int) bool {
+		return dates[i].Before(dates[j])
+	})
+
+	return dates
+}
+
+// queueDialog returns the dialog to queue an item for the meeting
+func (p *Plugin) queueDialog(meeting *Meeting, userID string) *model.Dialog {
+	now := time.Now()
+
+	var defaultDate string
+	if start, _, err := meeting.queueOccurrence(now, false, -1, false); err == nil {
+		defaultDate = start.Format(occurrenceDateFormat)
+	}
+
+	dates := meeting.queueDates(now)
+	options := make([]*model.PostActionOptions, 0, len(dates))
+	for _, start := range dates {
+		options = append(options, &model.PostActionOptions{
+			Text:  fmt.Sprintf("%s (%s)", meeting.formatOccurrence(start), meeting.hashtagForDate(start)),
+			Value: start.Format(occurrenceDateFormat),
+		})
+	}
+
+	return &model.Dialog{
+		CallbackId:  meeting.ChannelID,
+		Title:       "Queue an agenda item",
+		SubmitLabel: "Queue",
+		Elements: []model.DialogElement{
+			{
+				DisplayName: "Meeting",
+				Name:        "channel_id",
+				Type:        "select",
+				DataSource:  "channels",
+				Default:     meeting.ChannelID,
+				HelpText:    "The channel of the meeting.",
+			},
+			{
+				DisplayName: "Date",
+				Name:        "date",
+				Type:        "select",
+				Options:     options,
+				Default:     defaultDate,
+				HelpText:    "Items queued for the meeting of another channel must use a date of its schedule.",
+			},
+			{
+				DisplayName: "Title",
+				Name:        "title",
+				Type:        "text",
+				MaxLength:   queueDialogTitleLength,
+			},
+			{
+				DisplayName: "Details",
+				Name:        "details",
+				Type:        "textarea",
+				Optional:    true,
+				MaxLength:   queueDialogDetailsLength,
+				HelpText:    "Markdown is supported.",
+			},
+			{
+				DisplayName: "Owner",
+				Name:        "owner",
+				Type:        "select",
+				DataSource:  "users",
+				Default:     userID,
+				Optional:    true,
+				HelpText:    "Only channel admins can queue items owned by other users.",
+			},
+			{
+				DisplayName: "Duration",
+				Name:        "duration",
+				Type:        "text",
+				Optional:    true,
+				Placeholder: "15m",
+			},
+			{
+				DisplayName: "Labels",
+				Name:        "labels",
+				Type:        "text",
+				Optional:    true,
+				Placeholder: "release, hiring",
+				HelpText:    "Comma-separated labels.",
+			},
+		},
+	}
+}
+
+// queueDialogRequest returns the request opening the queue dialog
+func (p *Plugin) queueDialogRequest(channelID, userID string) (*model.OpenDialogRequest, error) {
+	meeting, err := p.GetMeeting(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.OpenDialogRequest{
+		URL:    fmt.Sprintf("/plugins/%s/api/v1/queue-dialog", Manifest.Id),
+		Dialog: *p.queueDialog(meeting, userID),
+	}, nil
+}
+
+func (p *Plugin) openQueueDialog(args *model.CommandArgs) *model.CommandResponse {
+	request, err := p.queueDialogRequest(args.ChannelId, args.UserId)
+	if err != nil {
+		return responsef("Error getting meeting information for this channel")
+	}
+
+	request.TriggerId = args.TriggerId
+	if appErr := p.API.OpenInteractiveDialog(*request); appErr != nil {
+		return responsef("Error opening the dialog to queue an item: %s", appErr.Message)
+	}
+
+	return &model.CommandResponse{}
+}
+
+// parseLabels splits comma-separated labels, dropping the empty ones
+func parseLabels(value string) []string {
+	var labels []string
+	for _, label := range strings.Split(value, ",") {
+		if label = strings.TrimSpace(label); label != "" {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+// submitQueueDialog queues the item of the dialog submission. It returns the errors of the
+// submission fields, or the confirmation for the user.
+func (p *Plugin) submitQueueDialog(userID string, submission map[string]interface{}) (map[string]string, string) {
+	value := func(name string) string {
+		s, _ := submission[name].(string)
+		return strings.TrimSpace(s)
+	}
+
+	channelID := value("channel_id")
+	if !p.isChannelMember(channelID, userID) {
+		return map[string]string{"channel_id": "You can only queue items for the meetings of the channels you are a member of."}, ""
+	}
+
+	meeting, err := p.GetMeeting(channelID)
+	if err != nil {
+		return map[string]string{"channel_id": "Error getting meeting information for this channel."}, ""
+	}
+
+	errs := map[string]string{}
+	now := time.Now()
+
+	day, err := time.ParseInLocation(occurrenceDateFormat, value("date"), meeting.location())
+	start := meeting.startTime(day)
+	if err != nil {
+		errs["date"] = "Invalid date."
+	} else if message := meeting.queueDateError(start, now); message != "" {
+		errs["date"] = message
+	}
+
+	title := value("title")
+	if title == "" {
+		errs["title"] = "A title is required."
+	} else if strings.Contains(title, "\n") {
+		errs["title"] = "The title must fit on one line. Use the details for the rest."
+	}
+
+	ownerID := value("owner")
+	if ownerID == "" {
+		ownerID = userID
+	}
+	if ownerID != userID && !p.isChannelAdmin(channelID, userID) {
+		errs["owner"] = "Only channel admins can queue items owned by other users."
+	} else if ownerID != userID && !p.isChannelMember(channelID, ownerID) {
+		errs["owner"] = "The owner must be a member of the channel."
+	}
+
+	duration, err := parseOptionalDuration(value("duration"))
+	if err != nil {
+		errs["duration"] = "Invalid duration. Must be a duration such as 15m or 1h."
+	}
+
+	if len(errs) > 0 {
+		return errs, ""
+	}
+
+	message := title
+	if details := value("details"); details != "" {
+		message += "\n" + details
+	}
+
+	hashtag := meeting.hashtagForDate(start)
+	item, _, err := p.queueItem(meeting, &queueRequest{
+		UserID:   userID,
+		OwnerID:  ownerID,
+		Hashtag:  hashtag,
+		Message:  message,
+		Duration: duration,
+		Labels:   parseLabels(value("labels")),
+	})
+	if err != nil {
+		return map[string]string{"title": fmt.Sprintf("Error queueing the item: %s", err.Error())}, ""
+	}
+
+	return nil, fmt.Sprintf("Queued item %d for the meeting of %s %s.", item.Number, meeting.formatOccurrence(start), hashtag)
+}
+
+func (p *Plugin) httpQueueDialog(w http.ResponseWriter, r *http.Request) {
+	mattermostUserID := r.Header.Get("Mattermost-User-Id")
+	if mattermostUserID == "" {
+		http.Error(w, "Not Authorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		channelID := r.URL.Query().Get("channelId")
+		if !p.isChannelMember(channelID, mattermostUserID) {
+			http.Error(w, "Not Authorized", http.StatusForbidden)
+			return
+		}
+
+		request, err := p.queueDialogRequest(channelID, mattermostUserID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		p.writeJSON(w, request)
+
+	case http.MethodPost:
+		var request *model.SubmitDialogRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request == nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		if request.Cancelled {
+			return
+		}
+
+		errs, confirmation := p.submitQueueDialog(mattermostUserID, request.Submission)
+		if len(errs) > 0 {
+			p.writeJSON(w, &model.SubmitDialogResponse{Errors: errs})
+			return
+		}
+
+		p.API.SendEphemeralPost(mattermostUserID, &model.Post{
+			UserId:    p.botID,
+			ChannelId: request.ChannelId,
+			Message:   confirmation,
+		})
+		p.writeJSON(w, &model.SubmitDialogResponse{})
+
+	default:
+		http.Error(w, "Request: "+r.Method+" is not allowed.", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMeeting_queueDates(t *testing.T) {
+	meeting := &Meeting{
+		ChannelID:     "channelID",
+		Schedule:      []time.Weekday{time.Tuesday, time.Thursday},
+		HashtagFormat: "dev-{{Jan02}}",
+	}
+
+	dates := meeting.queueDates(time.Now())
+	assert.NotEmpty(t, dates)
+	assert.True(t, sort.SliceIsSorted(dates, func(i, j int) bool { return dates[i].Before(dates[j]) }))
+
+	seen := map[string]bool{}
+	for _, date := range dates {
+		assert.True(t, meeting.isScheduled(date.Weekday()), date)
+		assert.False(t, seen[date.Format(occurrenceDateFormat)], date)
+		seen[date.Format(occurrenceDateFormat)] = true
+	}
+}
+
+func TestParseLabels(t *testing.T) {
+	assert.Nil(t, parseLabels(""))
+	assert.Equal(t, []string{"release", "hiring"}, parseLabels(" release, ,hiring ,"))
+}
+
+func TestPlugin_submitQueueDialog(t *testing.T) {
+	meeting := &Meeting{ChannelID: "channelID", Schedule: []time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday}}
+	date := time.Now().In(meeting.location()).AddDate(0, 0, 2)
+
+	setupAPI := func() *plugintest.API {
+		api := &plugintest.API{}
+		api.On("GetChannelMember", "channelID", "member").Return(&model.ChannelMember{}, nil)
+		api.On("GetChannelMember", "channelID", "admin").Return(&model.ChannelMember{SchemeAdmin: true}, nil)
+		api.On("GetChannelMember", "channelID", "outsider").Return(nil, model.NewAppError("GetChannelMember", "app.channel.get_member.missing.app_error", nil, "", http.StatusNotFound))
+		api.On("HasPermissionTo", mock.Anything, model.PermissionManageSystem).Return(false)
+		api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Name: "dev", TeamId: "teamID"}, nil)
+		api.On("KVGet", "channelID").Return([]byte(`{"channelId":"channelID","schedule":[0,1,2,3,4,5,6],"hashtagFormat":"dev-{{Jan02}}"}`), nil)
+		api.On("KVGet", webhooksKeyPrefix+"channelID").Return(nil, nil)
+		api.On("GetConfig").Return(&model.Config{})
+		api.On("SearchPostsInTeamForUser", "teamID", mock.Anything, mock.Anything).Return(model.MakePostSearchResults(model.NewPostList(), nil), nil)
+		api.On("GetUser", "admin").Return(&model.User{Id: "admin", Username: "alice"}, nil)
+		api.On("GetUser", "member").Return(&model.User{Id: "member", Username: "bob"}, nil)
+		api.On("CreatePost", mock.Anything).Return(&model.Post{Id: "item1"}, nil)
+		return api
+	}
+
+	submission := func(values map[string]interface{}) map[string]interface{} {
+		s := map[string]interface{}{
+			"channel_id": "channelID",
+			"date":       date.Format(occurrenceDateFormat),
+			"title":      "Release status",
+		}
+		for name, value := range values {
+			s[name] = value
+		}
+		return s
+	}
+
+	t.Run("queued with details, duration and labels", func(t *testing.T) {
+		api := setupAPI()
+		p := Plugin{}
+		p.SetAPI(api)
+
+		errs, confirmation := p.submitQueueDialog("member", submission(map[string]interface{}{
+			"details":  "- QA is blocked",
+			"duration": "15m",
+			"labels":   "release, qa",
+		}))
+		assert.Empty(t, errs)
+		assert.Contains(t, confirmation, "Queued item 1 for the meeting of")
+
+		hashtag := "#dev-" + date.Format("Jan02")
+		api.AssertCalled(t, "CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.UserId == "member" &&
+				post.Message == "#### "+hashtag+" 1) Release status\n- QA is blocked" &&
+				post.GetProp(itemDurationProp) == "15m0s"
+		}))
+	})
+
+	t.Run("today, for a meeting without a start time", func(t *testing.T) {
+		api := setupAPI()
+		p := Plugin{}
+		p.SetAPI(api)
+
+		errs, _ := p.submitQueueDialog("member", submission(map[string]interface{}{"date": time.Now().In(meeting.location()).Format(occurrenceDateFormat)}))
+		assert.Empty(t, errs)
+		api.AssertNumberOfCalls(t, "CreatePost", 1)
+	})
+
+	t.Run("owned by another member", func(t *testing.T) {
+		api := setupAPI()
+		p := Plugin{botID: "botID"}
+		p.SetAPI(api)
+
+		errs, _ := p.submitQueueDialog("admin", submission(map[string]interface{}{"owner": "member"}))
+		assert.Empty(t, errs)
+		api.AssertCalled(t, "CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.UserId == "botID" && post.GetProp(itemOwnerProp) == "member" &&
+				post.GetProp(itemQueuedByProp) == "admin"
+		}))
+	})
+
+	tests := []struct {
+		name   string
+		userID string
+		values map[string]interface{}
+		field  string
+	}{
+		{name: "not a channel member", userID: "outsider", field: "channel_id"},
+		{name: "invalid date", userID: "member", values: map[string]interface{}{"date": "tomorrow"}, field: "date"},
+		{name: "past date", userID: "member", values: map[string]interface{}{"date": time.Now().AddDate(0, 0, -2).Format(occurrenceDateFormat)}, field: "date"},
+		{name: "missing title", userID: "member", values: map[string]interface{}{"title": " "}, field: "title"},
+		{name: "invalid duration", userID: "member", values: map[string]interface{}{"duration": "soon"}, field: "duration"},
+		{name: "owner set by a member", userID: "member", values: map[string]interface{}{"owner": "admin"}, field: "owner"},
+		{name: "owner outside the channel", userID: "admin", values: map[string]interface{}{"owner": "outsider"}, field: "owner"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := setupAPI()
+			p := Plugin{}
+			p.SetAPI(api)
+
+			errs, _ := p.submitQueueDialog(tt.userID, submission(tt.values))
+			assert.Contains(t, errs, tt.field)
+			api.AssertNotCalled(t, "CreatePost", mock.Anything)
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	// quotedTitleLength is the maximum length of the title of an item quoting a post
+	quotedTitleLength = 80
+)
+
+// permalinkRegex matches the ID of the post of a permalink, i.e. https://example.com/team/pl/<postID>
+var permalinkRegex = regexp.MustCompile(`/pl/([a-z0-9]{26})/?$`)
+
+// parsePermalink returns the ID of the post of a permalink, or the value itself if it is a post ID
+func parsePermalink(value string) (string, error) {
+	value = strings.Trim(strings.TrimSpace(value), "<>")
+	if model.IsValidId(value) {
+		return value, nil
+	}
+
+	if matchGroups := permalinkRegex.FindStringSubmatch(value); len(matchGroups) == 2 {
+		return matchGroups[1], nil
+	}
+
+	return "", errors.Errorf("%s is not a link to a message", value)
+}
+
+// quotedItemMessage returns the message of an item quoting the post, titled after its first line
+func quotedItemMessage(post *model.Post, username, permalink string) string {
+	message := strings.TrimSpace(post.Message)
+	title := strings.TrimSpace(strings.SplitN(message, "\n", 2)[0])
+	if runes := []rune(title); len(runes) > quotedTitleLength {
+		title = string(runes[:quotedTitleLength-1]) + "…"
+	}
+	if title == "" {
+		title = "Message from @" + username
+	}
+
+	quoted := strings.Split(message, "\n")
+	for i, line := range quoted {
+		quoted[i] = "> " + line
+	}
+
+	return fmt.Sprintf("%s\n%s\n\n[Original message](%s) by @%s", title, strings.Join(quoted, "\n"), permalink, username)
+}
+
+// queuePost queues an item quoting the post for the next meeting of its channel, owned by the
+// author of the post. The items of posts by other users are posted by the Agenda bot. It returns
+// the message for the user who queued it.
+func (p *Plugin) queuePost(userID, postID string) (string, error) {
+	post, appErr := p.API.GetPost(postID)
+	if appErr != nil {
+		return "", errors.New("the message could not be found")
+	}
+
+	if !p.isChannelMember(post.ChannelId, userID) {
+		return "", errors.New("you can only add messages of the channels you are a member of")
+	}
+
+	channel, appErr := p.API.GetChannel(post.ChannelId)
+	if appErr != nil {
+		return "", errors.New("the channel of the message could not be found")
+	}
+
+	author, appErr := p.API.GetUser(post.UserId)
+	if appErr != nil {
+		return "", errors.New("the author of the message could not be found")
+	}
+
+	meeting, err := p.GetMeeting(post.ChannelId)
+	if err != nil {
+		return "", errors.New("failed to get the meeting information of the channel")
+	}
+
+	start, frozen, err := meeting.queueOccurrence(time.Now(), false, -1, false)
+	if err != nil {
+		return "", errors.New("failed to calculate the hashtag. Check the meeting settings of the channel")
+	}
+	hashtag := meeting.hashtagForDate(start)
+
+	message := quotedItemMessage(post, author.Username, p.permalink(channel.TeamId, post.Id))
+	item, _, err := p.queueItem(meeting, &queueRequest{
+		TeamID:  channel.TeamId,
+		UserID:  userID,
+		OwnerID: post.UserId,
+		Hashtag: hashtag,
+		Message: message,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if frozen != nil {
+		return fmt.Sprintf("The agenda of the meeting of %s is frozen, so the message was queued as item %d for the meeting of %s %s.",
+			meeting.formatOccurrence(*frozen), item.Number, meeting.formatOccurrence(start), hashtag), nil
+	}
+
+	return fmt.Sprintf("Queued the message as item %d for the meeting of %s %s.", item.Number, meeting.formatOccurrence(start), hashtag), nil
+}
+
+func (p *Plugin) executeCommandQueuePost(args *model.CommandArgs) *model.CommandResponse {
+	split := strings.Fields(args.Command)
+	if len(split) < 3 {
+		return responsef("Missing message link. Usage: `/agenda queue-post <permalink>`")
+	}
+
+	postID, err := parsePermalink(split[2])
+	if err != nil {
+		return responsef(err.Error())
+	}
+
+	message, err := p.queuePost(args.UserId, postID)
+	if err != nil {
+		return responsef("Failed to add the message to the agenda: %s", err.Error())
+	}
+
+	return responsef(message)
+}
+
+func (p *Plugin) httpQueuePost(w http.ResponseWriter, r *http.Request) {
+	mattermostUserID := r.Header.Get("Mattermost-User-Id")
+	if mattermostUserID == "" {
+		http.Error(w, "Not Authorized", http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Request: "+r.Method+" is not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		PostID string `json:"postId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || !model.IsValidId(body.PostID) {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	message, err := p.queuePost(mattermostUserID, body.PostID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	post, _ := p.API.GetPost(body.PostID)
+	if post != nil {
+		p.API.SendEphemeralPost(mattermostUserID, &model.Post{
+			UserId:    p.botID,
+			ChannelId: post.ChannelId,
+			Message:   message,
+		})
+	}
+
+	p.writeJSON(w, map[string]string{"message": message})
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePermalink(t *testing.T) {
+	postID := model.NewId()
+
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: postID, want: postID},
+		{value: "https://chat.example.com/dev-team/pl/" + postID, want: postID},
+		{value: "<https://chat.example.com/dev-team/pl/" + postID + ">", want: postID},
+		{value: "https://chat.example.com/dev-team/channels/town-square", wantErr: true},
+		{value: "not a link", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parsePermalink(tt.value)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestQuotedItemMessage(t *testing.T) {
+	post := &model.Post{Message: "We should talk about the release\n- it is late\n- QA is blocked"}
+
+	assert.Equal(t, "We should talk about the release\n"+
+		"> We should talk about the release\n"+
+		"> - it is late\n"+
+		"> - QA is blocked\n"+
+		"\n[Original message](https://chat.example.com/dev/pl/post1) by @alice",
+		quotedItemMessage(post, "alice", "https://chat.example.com/dev/pl/post1"))
+
+	long := &model.Post{Message: strings.Repeat("a", 100)}
+	title, _ := splitItemMessage(quotedItemMessage(long, "alice", "post1"))
+	assert.Equal(t, quotedTitleLength, len([]rune(title)))
+}
+
+func TestPlugin_queuePost(t *testing.T) {
+	setupAPI := func() *plugintest.API {
+		api := &plugintest.API{}
+		api.On("GetPost", "post1").Return(&model.Post{Id: "post1", ChannelId: "channelID", UserId: "author", Message: "Release status"}, nil)
+		api.On("GetChannelMember", "channelID", "member").Return(&model.ChannelMember{}, nil)
+		api.On("GetChannelMember", "channelID", "author").Return(&model.ChannelMember{}, nil)
+		api.On("GetChannelMember", "channelID", "outsider").Return(nil, model.NewAppError("GetChannelMember", "app.channel.get_member.missing.app_error", nil, "", http.StatusNotFound))
+		api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Name: "dev", TeamId: "teamID"}, nil)
+		api.On("GetUser", "author").Return(&model.User{Id: "author", Username: "alice"}, nil)
+		api.On("GetUser", "member").Return(&model.User{Id: "member", Username: "bob"}, nil)
+		api.On("KVGet", "channelID").Return([]byte(`{"channelId":"channelID","schedule":[0,1,2,3,4,5,6],"hashtagFormat":"dev-{{Jan02}}"}`), nil)
+		api.On("KVGet", webhooksKeyPrefix+"channelID").Return(nil, nil)
+		api.On("GetConfig").Return(&model.Config{})
+		api.On("SearchPostsInTeamForUser", "teamID", mock.Anything, mock.Anything).Return(model.MakePostSearchResults(model.NewPostList(), nil), nil)
+		api.On("CreatePost", mock.Anything).Return(&model.Post{Id: "item1"}, nil)
+		return api
+	}
+
+	t.Run("queued by the bot for the author", func(t *testing.T) {
+		api := setupAPI()
+		p := Plugin{botID: "botID"}
+		p.SetAPI(api)
+
+		message, err := p.queuePost("member", "post1")
+		assert.Nil(t, err)
+		assert.Contains(t, message, "Queued the message as item 1")
+		api.AssertCalled(t, "CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.UserId == "botID" && post.ChannelId == "channelID" &&
+				post.GetProp(itemOwnerProp) == "author" && post.GetProp(itemQueuedByProp) == "member" &&
+				post.Attachments()[0].Footer == "Queued by @bob for @alice" &&
+				strings.Contains(post.Message, " 1) Release status\n> Release status\n\n[Original message](post1) by @alice")
+		}))
+	})
+
+	t.Run("own message", func(t *testing.T) {
+		api := setupAPI()
+		p := Plugin{botID: "botID"}
+		p.SetAPI(api)
+
+		_, err := p.queuePost("author", "post1")
+		assert.Nil(t, err)
+		api.AssertCalled(t, "CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.UserId == "author" && post.GetProp(itemOwnerProp) == nil
+		}))
+	})
+
+	t.Run("not a channel member", func(t *testing.T) {
+		api := setupAPI()
+		p := Plugin{}
+		p.SetAPI(api)
+
+		_, err := p.queuePost("outsider", "post1")
+		assert.NotNil(t, err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+}
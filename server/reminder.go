@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	reminderJobKey        = "agenda_reminder_job"
+	reminderSentKeyPrefix = "reminder_sent_"
+
+	// reminderMorning posts the reminder on the morning of the meeting day
+	reminderMorning = "morning"
+	// reminderMorningHour is the hour of the day morning reminders are posted at
+	reminderMorningHour = 8
+	// reminderSentExpiry is how long to remember that a reminder was posted, in seconds
+	reminderSentExpiry = 8 * 24 * 60 * 60
+)
+
+// parseReminder validates the reminder setting value, returning the value to store
+func parseReminder(value string) (string, error) {
+	if value == reminderMorning {
+		return reminderMorning, nil
+	}
+
+	reminder, err := parseOptionalDuration(value)
+	if err != nil {
+		return "", errors.New("invalid reminder. Must be a duration such as 30m or 1h, `morning` or `off`")
+	}
+
+	return reminder, nil
+}
+
+// reminderTime returns when the reminder for the occurrence starting at start must be posted.
+// It returns false if reminders are disabled.
+func (m *Meeting) reminderTime(start time.Time) (time.Time, bool) {
+	switch m.Reminder {
+	case "":
+		return time.Time{}, false
+	case reminderMorning:
+		morning := time.Date(start.Year(), start.Month(), start.Day(), reminderMorningHour, 0, 0, 0, start.Location())
+		if morning.After(start) {
+			// Early meetings get their reminder at the start of the day
+			morning = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+		}
+		return morning, true
+	}
+
+	before, err := time.ParseDuration(m.Reminder)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return start.Add(-before), true
+}
+
+// runReminderJob seeds the standing items of the next occurrence of every meeting, and posts the
+// reminders and empty agenda checks that are due
+func (p *Plugin) runReminderJob() {
+	meetings, err := p.listMeetings()
+	if err != nil {
+		p.API.LogError("Failed to list meetings for reminders", "error", err.Error())
+		return
+	}
+
+	now := time.Now()
+	for _, meeting := range meetings {
+		if meeting.Reminder == "" && meeting.EmptyCheck == "" && len(meeting.StandingItems) == 0 {
+			continue
+		}
+
+		start, err := meeting.nextOccurrence(now)
+		if err != nil {
+			continue
+		}
+
+		if len(meeting.StandingItems) > 0 {
+			if err := p.seedOccurrence(meeting, start); err != nil {
+				p.API.LogError("Failed to seed the standing items", "error", err.Error(), "channel_id", meeting.ChannelID)
+			}
+		}
+
+		if checkAt, ok := meeting.emptyCheckTime(start); ok && !now.Before(checkAt) {
+			if err := p.checkEmptyAgenda(meeting, start); err != nil {
+				p.API.LogError("Failed to check for an empty agenda", "error", err.Error(), "channel_id", meeting.ChannelID)
+			}
+		}
+
+		if remindAt, ok := meeting.reminderTime(start); ok && !now.Before(remindAt) {
+			if err := p.postReminder(meeting, start); err != nil {
+				p.API.LogError("Failed to post meeting reminder", "error", err.Error(), "channel_id", meeting.ChannelID)
+			}
+		}
+	}
+}
+
+// postReminder posts the agenda of the meeting occurrence starting at start, once. If the reminder
+// can't be posted, it is retried on the next run of the reminder job.
+func (p *Plugin) postReminder(meeting *Meeting, start time.Time) error {
+	sentKey := reminderSentKeyPrefix + meeting.ChannelID + "_" + start.Format(occurrenceDateFormat)
+	firstTime, appErr := p.API.KVSetWithOptions(sentKey, []byte(start.Format(time.RFC3339)), model.PluginKVSetOptions{
+		Atomic:          true,
+		OldValue:        nil,
+		ExpireInSeconds: reminderSentExpiry,
+	})
+	if appErr != nil {
+		return appErr
+	}
+	if !firstTime {
+		return nil
+	}
+
+	if err := p.createReminderPost(meeting, start); err != nil {
+		if appErr := p.API.KVDelete(sentKey); appErr != nil {
+			p.API.LogWarn("Failed to release the meeting reminder", "error", appErr.Error(), "channel_id", meeting.ChannelID)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// createReminderPost posts the agenda of the meeting occurrence starting at start
+func (p *Plugin) createReminderPost(meeting *Meeting, start time.Time) error {
+	hashtag := meeting.hashtagForDate(start)
+	items, err := p.getAgendaItems(meeting, "", "", hashtag)
+	if err != nil {
+		return errors.Wrap(err, "failed to get agenda items")
+	}
+
+	roles, err := p.getRotationRoles(meeting, start)
+	if err != nil {
+		p.API.LogWarn("Failed to get the meeting roles", "error", err.Error(), "channel_id", meeting.ChannelID)
+	}
+
+	post := &model.Post{
+		UserId:    p.botID,
+		ChannelId: meeting.ChannelID,
+		Message:   reminderMessage(meeting, hashtag, start, p.rotationRolesMessage(roles), items),
+	}
+	model.ParseSlackAttachment(post, []*model.SlackAttachment{{
+		Actions: []*model.PostAction{{
+			Name: "View agenda",
+			Integration: &model.PostActionIntegration{
+				URL: fmt.Sprintf("/plugins/%s/api/v1/agenda/open", Manifest.Id),
+				Context: map[string]interface{}{
+					"channel_id": meeting.ChannelID,
+					"hashtag":    hashtag,
+				},
+			},
+		}},
+	}})
+
+	if _, appErr := p.API.CreatePost(post); appErr != nil {
+		return appErr
+	}
+
+	return nil
+}
+
+func reminderMessage(meeting *Meeting, hashtag string, start time.Time, roles string, items []*AgendaItem) string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "#### Upcoming meeting %s on %s\n", hashtag, meeting.formatOccurrence(start))
+	if roles != "" {
+		sb.WriteString(roles + "\n")
+	}
+
+	if len(items) == 0 {
+		sb.WriteString("\nNo items are queued yet. Use `/agenda queue` to add one.\n")
+	} else {
+		sb.WriteString("\n")
+	}
+	for _, item := range items {
+		fmt.Fprintf(&sb, "%d. %s\n", item.Number, item.summary())
+	}
+
+	return sb.String()
+}
+
+// openAgendaSearch opens the RHS of the user with the search of the given hashtag
+func (p *Plugin) openAgendaSearch(userID, hashtag string) {
+	// Send a websocket event to the web app that will open the RHS
+	p.API.PublishWebSocketEvent(
+		wsEventList,
+		map[string]interface{}{
+			"hashtag": hashtag,
+		},
+		&model.WebsocketBroadcast{UserId: userID},
+	)
+}
+
+func (p *Plugin) httpOpenAgenda(w http.ResponseWriter, r *http.Request) {
+	mattermostUserID := r.Header.Get("Mattermost-User-Id")
+	if mattermostUserID == "" {
+		http.Error(w, "Not Authorized", http.StatusUnauthorized)
+		return
+	}
+
+	var request *model.PostActionIntegrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request == nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	channelID, _ := request.Context["channel_id"].(string)
+	hashtag, _ := request.Context["hashtag"].(string)
+	if !p.isChannelMember(channelID, mattermostUserID) {
+		http.Error(w, "Not Authorized", http.StatusForbidden)
+		return
+	}
+
+	p.openAgendaSearch(mattermostUserID, hashtag)
+
+	p.writeJSON(w, &model.PostActionIntegrationResponse{})
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseReminder(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "off", want: ""},
+		{value: "", want: ""},
+		{value: "morning", want: "morning"},
+		{value: "1h", want: "1h0m0s"},
+		{value: "90m", want: "1h30m0s"},
+		{value: "-1h", wantErr: true},
+		{value: "tomorrow", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseReminder(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMeeting_reminderTime(t *testing.T) {
+	start := time.Date(2022, 1, 6, 15, 0, 0, 0, time.UTC)
+
+	t.Run("disabled", func(t *testing.T) {
+		_, ok := (&Meeting{}).reminderTime(start)
+		assert.False(t, ok)
+	})
+
+	t.Run("duration", func(t *testing.T) {
+		remindAt, ok := (&Meeting{Reminder: "1h0m0s"}).reminderTime(start)
+		assert.True(t, ok)
+		assert.Equal(t, time.Date(2022, 1, 6, 14, 0, 0, 0, time.UTC), remindAt)
+	})
+
+	t.Run("morning", func(t *testing.T) {
+		remindAt, ok := (&Meeting{Reminder: reminderMorning}).reminderTime(start)
+		assert.True(t, ok)
+		assert.Equal(t, time.Date(2022, 1, 6, reminderMorningHour, 0, 0, 0, time.UTC), remindAt)
+	})
+
+	t.Run("morning of an early meeting", func(t *testing.T) {
+		early := time.Date(2022, 1, 6, 7, 30, 0, 0, time.UTC)
+		remindAt, ok := (&Meeting{Reminder: reminderMorning}).reminderTime(early)
+		assert.True(t, ok)
+		assert.Equal(t, time.Date(2022, 1, 6, 0, 0, 0, 0, time.UTC), remindAt)
+	})
+}
+
+func Test_reminderMessage(t *testing.T) {
+	start := time.Date(2022, 1, 6, 15, 0, 0, 0, time.UTC)
+
+	message := reminderMessage(&Meeting{Time: "15:00"}, "#dev-Jan06", start, "**Facilitator:** @alice", []*AgendaItem{
+		newAgendaItem(1, "Release status\nWe are late", "", ""),
+		newAgendaItem(2, "Hiring", "", ""),
+	})
+	assert.Equal(t, "#### Upcoming meeting #dev-Jan06 on Thursday, January 6 at 15:00 UTC\n"+
+		"**Facilitator:** @alice\n"+
+		"\n1. Release status\n"+
+		"2. Hiring\n", message)
+
+	empty := reminderMessage(&Meeting{}, "#dev-Jan06", start, "", nil)
+	assert.Equal(t, "#### Upcoming meeting #dev-Jan06 on Thursday, January 6\n"+
+		"\nNo items are queued yet. Use `/agenda queue` to add one.\n", empty)
+}
+
+func TestPlugin_postReminder(t *testing.T) {
+	meeting := &Meeting{ChannelID: "channelID", Schedule: []time.Weekday{time.Thursday}, HashtagFormat: "dev-{{Jan02}}", Time: "15:00", Timezone: "UTC"}
+	start := time.Date(2022, 1, 6, 15, 0, 0, 0, time.UTC)
+	sentKey := reminderSentKeyPrefix + "channelID_2022-01-06"
+
+	setupAPI := func(createErr *model.AppError) *plugintest.API {
+		api := &plugintest.API{}
+		api.On("KVSetWithOptions", sentKey, mock.Anything, mock.Anything).Return(true, nil)
+		api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Name: "dev", TeamId: "teamID"}, nil)
+		api.On("SearchPostsInTeam", "teamID", mock.Anything).Return([]*model.Post{}, nil)
+		api.On("CreatePost", mock.Anything).Return(nil, createErr)
+		api.On("KVDelete", sentKey).Return(nil)
+		return api
+	}
+
+	t.Run("posted", func(t *testing.T) {
+		api := setupAPI(nil)
+		p := Plugin{botID: "botID"}
+		p.SetAPI(api)
+
+		assert.Nil(t, p.postReminder(meeting, start))
+		api.AssertNumberOfCalls(t, "CreatePost", 1)
+		api.AssertNotCalled(t, "KVDelete", sentKey)
+	})
+
+	t.Run("released when the post fails", func(t *testing.T) {
+		api := setupAPI(model.NewAppError("CreatePost", "app.post.save.app_error", nil, "", http.StatusInternalServerError))
+		p := Plugin{botID: "botID"}
+		p.SetAPI(api)
+
+		assert.NotNil(t, p.postReminder(meeting, start))
+		api.AssertCalled(t, "KVDelete", sentKey)
+	})
+}
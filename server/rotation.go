@@ -0,0 +1,266 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+)
+
+// Rotation is the ordered roster of users taking turns as facilitator and note-taker
+type Rotation struct {
+	Users []string `json:"users"`
+	// Index is the position in Users of the facilitator of Occurrence
+	Index int `json:"index"`
+	// Occurrence is the date of the meeting Index applies to
+	Occurrence string `json:"occurrence"`
+}
+
+// RotationRoles are the users holding the meeting roles of an occurrence
+type RotationRoles struct {
+	Facilitator string
+	NoteTaker   string
+}
+
+// advanceTo moves the rotation forward one position per meeting occurrence held since the last
+// known occurrence, skipping the cancelled ones. It returns true if the rotation changed.
+func (r *Rotation) advanceTo(meeting *Meeting, date time.Time) bool {
+	dateStr := date.Format(occurrenceDateFormat)
+	if r.Occurrence == dateStr {
+		return false
+	}
+
+	last, err := time.ParseInLocation(occurrenceDateFormat, r.Occurrence, date.Location())
+	if err == nil && date.Before(last) {
+		return false
+	}
+	if err == nil {
+		r.Index += meeting.heldOccurrences(last, date)
+	}
+
+	r.Occurrence = dateStr
+	if len(r.Users) > 0 {
+		r.Index %= len(r.Users)
+	}
+	return true
+}
+
+// skip hands the current facilitator role to the next user in the roster
+func (r *Rotation) skip() {
+	if len(r.Users) == 0 {
+		return
+	}
+	r.Index = (r.Index + 1) % len(r.Users)
+}
+
+// roles returns the facilitator and note-taker starting at the current index,
+// skipping the users that are unavailable.
+func (r *Rotation) roles(unavailable func(userID string) bool) RotationRoles {
+	var available []string
+	for i := 0; i < len(r.Users); i++ {
+		userID := r.Users[(r.Index+i)%len(r.Users)]
+		if !unavailable(userID) {
+			available = append(available, userID)
+		}
+	}
+
+	var roles RotationRoles
+	if len(available) > 0 {
+		roles.Facilitator = available[0]
+	}
+	if len(available) > 1 {
+		roles.NoteTaker = available[1]
+	}
+	return roles
+}
+
+// isOutOfOffice returns true if the user has set their status to out of office
+func (p *Plugin) isOutOfOffice(userID string) bool {
+	status, appErr := p.API.GetUserStatus(userID)
+	if appErr != nil {
+		return false
+	}
+	return status.Status == model.StatusOutOfOffice
+}
+
+// getRotationRoles returns the meeting roles for the occurrence on the given date,
+// advancing and saving the meeting rotation when a new occurrence is reached.
+func (p *Plugin) getRotationRoles(meeting *Meeting, date time.Time) (*RotationRoles, error) {
+	if meeting.Rotation == nil || len(meeting.Rotation.Users) == 0 {
+		return nil, nil
+	}
+
+	if err := p.advanceRotation(meeting, date); err != nil {
+		return nil, err
+	}
+	if meeting.Rotation == nil || len(meeting.Rotation.Users) == 0 {
+		return nil, nil
+	}
+
+	roles := meeting.Rotation.roles(p.isOutOfOffice)
+	return &roles, nil
+}
+
+// advanceRotation advances the stored rotation of the meeting to the occurrence on the given date.
+// Only the rotation is saved, with compare and set, so that the settings saved since the meeting
+// was read, i.e. while the reminder job posts a reminder, are kept.
+func (p *Plugin) advanceRotation(meeting *Meeting, date time.Time) error {
+	for i := 0; i < 5; i++ {
+		oldBytes, appErr := p.API.KVGet(meeting.ChannelID)
+		if appErr != nil {
+			return appErr
+		}
+		if oldBytes == nil {
+			return nil
+		}
+
+		var stored map[string]json.RawMessage
+		if err := json.Unmarshal(oldBytes, &stored); err != nil {
+			return err
+		}
+		var rotation *Rotation
+		if rawRotation, ok := stored["rotation"]; ok {
+			if err := json.Unmarshal(rawRotation, &rotation); err != nil {
+				return err
+			}
+		}
+		if rotation == nil || !rotation.advanceTo(meeting, date) {
+			meeting.Rotation = rotation
+			return nil
+		}
+
+		rawRotation, err := json.Marshal(rotation)
+		if err != nil {
+			return err
+		}
+		stored["rotation"] = rawRotation
+		newBytes, err := json.Marshal(stored)
+		if err != nil {
+			return err
+		}
+
+		saved, appErr := p.API.KVSetWithOptions(meeting.ChannelID, newBytes, model.PluginKVSetOptions{
+			Atomic:   true,
+			OldValue: oldBytes,
+		})
+		if appErr != nil {
+			return appErr
+		}
+		if saved {
+			meeting.Rotation = rotation
+			return nil
+		}
+	}
+
+	return errors.New("the meeting was updated concurrently too many times")
+}
+
+// rotationRolesMessage returns the announcement of the meeting roles
+func (p *Plugin) rotationRolesMessage(roles *RotationRoles) string {
+	if roles == nil {
+		return ""
+	}
+
+	usernames := p.usernames([]string{roles.Facilitator, roles.NoteTaker})
+	facilitator, noteTaker := "_nobody available_", "_nobody available_"
+	if roles.Facilitator != "" {
+		facilitator = "@" + usernames[roles.Facilitator]
+	}
+	if roles.NoteTaker != "" {
+		noteTaker = "@" + usernames[roles.NoteTaker]
+	}
+
+	return fmt.Sprintf("**Facilitator:** %s | **Note-taker:** %s", facilitator, noteTaker)
+}
+
+func (p *Plugin) executeCommandRotation(args *model.CommandArgs) *model.CommandResponse {
+	split := strings.Fields(args.Command)
+
+	if len(split) < 3 {
+		return responsef("Missing parameters for rotation command. You can try show, set, skip")
+	}
+
+	meeting, err := p.GetMeeting(args.ChannelId)
+	if err != nil {
+		return responsef("Error getting meeting information for this channel")
+	}
+
+	// The roles are those of the occurrence the reminder announces
+	meetingDate, err := meeting.upcomingOccurrence(time.Now(), false, -1)
+	if err != nil {
+		return responsef("Error calculating the meeting date. Check the meeting settings for this channel.")
+	}
+
+	if split[2] != "show" && !p.canManageMeeting(args.ChannelId, args.UserId) {
+		return responsef("You do not have permission to change the meeting settings of this channel")
+	}
+
+	switch split[2] {
+	case "show":
+	case "set":
+		if len(split) < 4 {
+			return responsef("Missing users for the rotation. Usage: `/agenda rotation set @user1 @user2 ...`")
+		}
+
+		var userIDs []string
+		for _, username := range split[3:] {
+			user, appErr := p.API.GetUserByUsername(strings.TrimPrefix(username, "@"))
+			if appErr != nil {
+				return responsef("Unknown user %s", username)
+			}
+			userIDs = append(userIDs, user.Id)
+		}
+
+		meeting.Rotation = &Rotation{
+			Users:      userIDs,
+			Occurrence: meetingDate.Format(occurrenceDateFormat),
+		}
+		if err = p.SaveMeeting(meeting); err != nil {
+			return responsef("Error saving rotation")
+		}
+	case "skip":
+		if meeting.Rotation == nil || len(meeting.Rotation.Users) == 0 {
+			return responsef("There is no rotation for this channel. Use `/agenda rotation set` to create one.")
+		}
+
+		// Bring the rotation up to date before skipping the current facilitator
+		meeting.Rotation.advanceTo(meeting, meetingDate)
+		meeting.Rotation.skip()
+		if err = p.SaveMeeting(meeting); err != nil {
+			return responsef("Error saving rotation")
+		}
+	default:
+		return responsef("Unknown rotation action %s. You can try show, set, skip", split[2])
+	}
+
+	roles, err := p.getRotationRoles(meeting, meetingDate)
+	if err != nil {
+		return responsef("Error getting the meeting roles")
+	}
+	if roles == nil {
+		return responsef("There is no rotation for this channel. Use `/agenda rotation set` to create one.")
+	}
+
+	return responsef("Roles for the meeting of %s:\n%s\n**Rotation:** %s",
+		meetingDate.Format(occurrenceDateFormat), p.rotationRolesMessage(roles), p.rotationRosterMessage(meeting.Rotation))
+}
+
+// rotationRosterMessage lists the users of the rotation in order, starting with the current facilitator
+func (p *Plugin) rotationRosterMessage(rotation *Rotation) string {
+	usernames := p.usernames(rotation.Users)
+
+	roster := make([]string, 0, len(rotation.Users))
+	for i := 0; i < len(rotation.Users); i++ {
+		userID := rotation.Users[(rotation.Index+i)%len(rotation.Users)]
+		entry := "@" + usernames[userID]
+		if p.isOutOfOffice(userID) {
+			entry += " (out of office)"
+		}
+		roster = append(roster, entry)
+	}
+
+	return strings.Join(roster, ", ")
+}
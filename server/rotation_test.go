@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotation_advanceTo(t *testing.T) {
+	meeting := &Meeting{Schedule: []time.Weekday{time.Monday, time.Thursday}}
+	// 2022-01-03 is a Monday
+	monday := time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC)
+
+	t.Run("first occurrence", func(t *testing.T) {
+		rotation := &Rotation{Users: []string{"a", "b", "c"}}
+		assert.True(t, rotation.advanceTo(meeting, monday))
+		assert.Equal(t, 0, rotation.Index)
+		assert.Equal(t, "2022-01-03", rotation.Occurrence)
+	})
+
+	t.Run("same occurrence", func(t *testing.T) {
+		rotation := &Rotation{Users: []string{"a", "b", "c"}, Index: 1, Occurrence: "2022-01-03"}
+		assert.False(t, rotation.advanceTo(meeting, monday))
+		assert.Equal(t, 1, rotation.Index)
+	})
+
+	t.Run("one occurrence per meeting day", func(t *testing.T) {
+		rotation := &Rotation{Users: []string{"a", "b", "c"}, Occurrence: "2022-01-03"}
+		assert.True(t, rotation.advanceTo(meeting, monday.AddDate(0, 0, 3)))
+		assert.Equal(t, 1, rotation.Index)
+
+		// Two weeks later: four meetings were held, wrapping around the roster
+		assert.True(t, rotation.advanceTo(meeting, monday.AddDate(0, 0, 17)))
+		assert.Equal(t, 2, rotation.Index)
+		assert.Equal(t, "2022-01-20", rotation.Occurrence)
+	})
+
+	t.Run("cancelled occurrences", func(t *testing.T) {
+		cancelled := &Meeting{
+			Schedule: []time.Weekday{time.Monday, time.Thursday},
+			// The Thursday is cancelled, the Friday is not a meeting day
+			SkipDates: []string{"2022-01-06", "2022-01-07"},
+		}
+		rotation := &Rotation{Users: []string{"a", "b", "c"}, Occurrence: "2022-01-03"}
+		assert.True(t, rotation.advanceTo(cancelled, monday.AddDate(0, 0, 7)))
+		assert.Equal(t, 1, rotation.Index)
+	})
+
+	t.Run("past occurrence", func(t *testing.T) {
+		rotation := &Rotation{Users: []string{"a", "b", "c"}, Index: 2, Occurrence: "2022-01-03"}
+		assert.False(t, rotation.advanceTo(meeting, monday.AddDate(0, 0, -4)))
+		assert.Equal(t, 2, rotation.Index)
+	})
+}
+
+func TestPlugin_getRotationRoles(t *testing.T) {
+	// The meeting was read before a cancellation was saved by a slash command
+	meeting := &Meeting{
+		ChannelID: "channelID",
+		Schedule:  []time.Weekday{time.Monday, time.Thursday},
+		Rotation:  &Rotation{Users: []string{"a", "b", "c"}, Occurrence: "2022-01-03"},
+	}
+	stored := []byte(`{"channelId":"channelID","schedule":[1,4],"skipDates":["2022-01-20"],` +
+		`"rotation":{"users":["a","b","c"],"index":0,"occurrence":"2022-01-03"}}`)
+
+	api := &plugintest.API{}
+	api.On("KVGet", "channelID").Return(stored, nil)
+	api.On("KVSetWithOptions", "channelID", mock.MatchedBy(func(value []byte) bool {
+		var saved *Meeting
+		return json.Unmarshal(value, &saved) == nil &&
+			len(saved.SkipDates) == 1 && saved.SkipDates[0] == "2022-01-20" &&
+			saved.Rotation.Index == 1 && saved.Rotation.Occurrence == "2022-01-06"
+	}), model.PluginKVSetOptions{Atomic: true, OldValue: stored}).Return(true, nil)
+	api.On("GetUserStatus", mock.Anything).Return(&model.Status{Status: model.StatusOnline}, nil)
+	p := Plugin{}
+	p.SetAPI(api)
+
+	roles, err := p.getRotationRoles(meeting, time.Date(2022, 1, 6, 0, 0, 0, 0, time.UTC))
+	assert.Nil(t, err)
+	assert.Equal(t, &RotationRoles{Facilitator: "b", NoteTaker: "c"}, roles)
+	api.AssertNotCalled(t, "KVSet", mock.Anything, mock.Anything)
+	api.AssertNumberOfCalls(t, "KVSetWithOptions", 1)
+}
+
+func TestRotation_roles(t *testing.T) {
+	noneUnavailable := func(string) bool { return false }
+
+	t.Run("consecutive users", func(t *testing.T) {
+		rotation := &Rotation{Users: []string{"a", "b", "c"}, Index: 2}
+		assert.Equal(t, RotationRoles{Facilitator: "c", NoteTaker: "a"}, rotation.roles(noneUnavailable))
+	})
+
+	t.Run("skip out of office", func(t *testing.T) {
+		rotation := &Rotation{Users: []string{"a", "b", "c"}}
+		roles := rotation.roles(func(userID string) bool { return userID == "a" })
+		assert.Equal(t, RotationRoles{Facilitator: "b", NoteTaker: "c"}, roles)
+	})
+
+	t.Run("not enough users", func(t *testing.T) {
+		rotation := &Rotation{Users: []string{"a", "b"}}
+		roles := rotation.roles(func(userID string) bool { return userID == "b" })
+		assert.Equal(t, RotationRoles{Facilitator: "a"}, roles)
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		rotation := &Rotation{Users: []string{"a", "b", "c"}, Index: 2}
+		rotation.skip()
+		assert.Equal(t, RotationRoles{Facilitator: "a", NoteTaker: "b"}, rotation.roles(noneUnavailable))
+	})
+}
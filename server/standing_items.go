@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	// standingItemsKeyPrefix prefixes the occurrences seeded with the standing items, by channel and hashtag
+	standingItemsKeyPrefix = "standing_items_"
+	// standingItemsSeededExpiry is how long to remember that an occurrence was seeded, in seconds.
+	// It is shorter than a year so hashtags repeating yearly are seeded again.
+	standingItemsSeededExpiry = 300 * 24 * 60 * 60
+
+	// maxStandingItems is the maximum number of standing items of a meeting
+	maxStandingItems = 20
+)
+
+// validateStandingItems returns why the standing items are invalid, or an empty string if they are valid
+func validateStandingItems(items []string) string {
+	if len(items) > maxStandingItems {
+		return fmt.Sprintf("A meeting can have at most %d standing items", maxStandingItems)
+	}
+
+	seen := map[string]bool{}
+	for _, item := range items {
+		switch {
+		case strings.TrimSpace(item) == "":
+			return "Standing items cannot be empty"
+		case strings.Contains(item, "\n"):
+			return fmt.Sprintf("The standing item %s must fit on one line", item)
+		case seen[normalizeTitle(item)]:
+			return fmt.Sprintf("The standing item %s is repeated", item)
+		}
+		seen[normalizeTitle(item)] = true
+	}
+	return ""
+}
+
+func standingItemsKey(channelID, hashtag string) string {
+	return standingItemsKeyPrefix + channelID + "_" + hashtag
+}
+
+// seedOccurrence queues the standing items of the meeting occurrence starting at start when it
+// becomes the next occurrence, so that they are listed, reminded and exported even if nothing else
+// is queued. Occurrences that already have items are left as they are.
+func (p *Plugin) seedOccurrence(meeting *Meeting, start time.Time) error {
+	hashtag := meeting.hashtagForDate(start)
+	seeded, appErr := p.API.KVGet(standingItemsKey(meeting.ChannelID, hashtag))
+	if appErr != nil {
+		return appErr
+	}
+	if seeded != nil {
+		return nil
+	}
+
+	items, err := p.getAgendaItems(meeting, "", "", hashtag)
+	if err != nil {
+		return errors.Wrap(err, "failed to get agenda items")
+	}
+	if len(items) > 0 {
+		// Standing items only come before the first item of an occurrence
+		_, appErr = p.API.KVSetWithOptions(standingItemsKey(meeting.ChannelID, hashtag), []byte(hashtag), model.PluginKVSetOptions{
+			ExpireInSeconds: standingItemsSeededExpiry,
+		})
+		if appErr != nil {
+			return appErr
+		}
+		return nil
+	}
+
+	_, err = p.seedStandingItems(meeting, hashtag)
+	return err
+}
+
+// seedStandingItems queues the standing items of the meeting as the first items of the occurrence
+// of the hashtag, posted by the Agenda bot. Each occurrence is seeded once, so the standing items
+// removed from an occurrence are not queued again.
+func (p *Plugin) seedStandingItems(meeting *Meeting, hashtag string) ([]*AgendaItem, error) {
+	if len(meeting.StandingItems) == 0 {
+		return nil, nil
+	}
+
+	firstTime, appErr := p.API.KVSetWithOptions(standingItemsKey(meeting.ChannelID, hashtag), []byte(hashtag), model.PluginKVSetOptions{
+		Atomic:          true,
+		OldValue:        nil,
+		ExpireInSeconds: standingItemsSeededExpiry,
+	})
+	if appErr != nil {
+		return nil, appErr
+	}
+	if !firstTime {
+		return nil, nil
+	}
+
+	items := make([]*AgendaItem, 0, len(meeting.StandingItems))
+	for _, title := range meeting.StandingItems {
+		item, _, err := p.queueItem(meeting, &queueRequest{
+			OwnerID: p.botID,
+			Hashtag: hashtag,
+			Message: title,
+			Number:  len(items) + 1,
+		})
+		if err != nil {
+			p.API.LogWarn("Failed to queue standing item", "error", err.Error(), "channel_id", meeting.ChannelID, "hashtag", hashtag)
+			continue
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+func (p *Plugin) executeCommandTemplate(args *model.CommandArgs) *model.CommandResponse {
+	split := strings.Fields(args.Command)
+	if len(split) < 3 {
+		return responsef("Missing action. Usage: `/agenda template add <item>|remove <number>|list`")
+	}
+
+	meeting, err := p.GetMeeting(args.ChannelId)
+	if err != nil {
+		return responsef("Error getting meeting information for this channel")
+	}
+
+	action := split[2]
+	if action == "list" {
+		if len(meeting.StandingItems) == 0 {
+			return responsef("This meeting has no standing items. Add one with `/agenda template add <item>`.")
+		}
+		lines := []string{"#### Standing items of this meeting"}
+		for i, item := range meeting.StandingItems {
+			lines = append(lines, fmt.Sprintf("%d. %s", i+1, item))
+		}
+		return responsef(strings.Join(lines, "\n"))
+	}
+
+	if action != "add" && action != "remove" {
+		return responsef("Unknown template action: %s", action)
+	}
+
+	if !p.canManageMeeting(args.ChannelId, args.UserId) {
+		return responsef("You do not have permission to change the meeting settings of this channel")
+	}
+
+	var response string
+	if action == "add" {
+		item := strings.Join(split[3:], " ")
+		if item == "" {
+			return responsef("Missing item. Usage: `/agenda template add <item>`")
+		}
+		meeting.StandingItems = append(meeting.StandingItems, item)
+		response = fmt.Sprintf("Added standing item %d: %s. It will be queued first on the next meetings that have no items yet.", len(meeting.StandingItems), item)
+	} else {
+		if len(split) < 4 {
+			return responsef("Missing item number. Usage: `/agenda template remove <number>`")
+		}
+		number, err := strconv.Atoi(split[3])
+		if err != nil || number < 1 || number > len(meeting.StandingItems) {
+			return responsef("Invalid item number %s. Use `/agenda template list` to see the standing items.", split[3])
+		}
+		item := meeting.StandingItems[number-1]
+		meeting.StandingItems = append(meeting.StandingItems[:number-1], meeting.StandingItems[number:]...)
+		response = fmt.Sprintf("Removed standing item %d: %s.", number, item)
+	}
+
+	if problem := validateStandingItems(meeting.StandingItems); problem != "" {
+		return responsef(problem)
+	}
+	if err := p.SaveMeeting(meeting); err != nil {
+		return responsef("Error saving the standing items")
+	}
+
+	return responsef(response)
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateStandingItems(t *testing.T) {
+	tests := []struct {
+		name  string
+		items []string
+		want  string
+	}{
+		{name: "none", items: nil, want: ""},
+		{name: "valid", items: []string{"Metrics review", "Incidents", "Open floor"}, want: ""},
+		{name: "empty", items: []string{"Incidents", " "}, want: "Standing items cannot be empty"},
+		{name: "several lines", items: []string{"Incidents\nP1 only"}, want: "The standing item Incidents\nP1 only must fit on one line"},
+		{name: "repeated", items: []string{"Incidents", "incidents"}, want: "The standing item incidents is repeated"},
+		{name: "too many", items: strings.Split(strings.Repeat("item,", maxStandingItems+1), ","), want: "A meeting can have at most 20 standing items"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, validateStandingItems(tt.items))
+		})
+	}
+}
+
+func TestPlugin_queueItemWithStandingItems(t *testing.T) {
+	meeting := &Meeting{ChannelID: "channelID", HashtagFormat: "dev-{{Jan02}}", StandingItems: []string{"Metrics review", "Open floor"}}
+	seededKey := standingItemsKeyPrefix + "channelID_#dev-Jan06"
+
+	terms := "in:dev #dev-Jan06"
+
+	t.Run("first item of the occurrence", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Name: "dev", TeamId: "teamID"}, nil)
+		api.On("KVGet", webhooksKeyPrefix+"channelID").Return(nil, nil)
+		api.On("SearchPostsInTeamForUser", "teamID", "member", model.SearchParameter{Terms: &terms}).Return(model.MakePostSearchResults(model.NewPostList(), nil), nil)
+		api.On("KVSetWithOptions", seededKey, []byte("#dev-Jan06"), model.PluginKVSetOptions{Atomic: true, ExpireInSeconds: standingItemsSeededExpiry}).Return(true, nil)
+		api.On("CreatePost", mock.Anything).Return(func(post *model.Post) *model.Post {
+			created := post.Clone()
+			created.Id = model.NewId()
+			return created
+		}, nil)
+		p := Plugin{botID: "botID"}
+		p.SetAPI(api)
+
+		item, _, err := p.queueAgendaItem(meeting, "teamID", "member", "", "#dev-Jan06", "Release status")
+		assert.Nil(t, err)
+		assert.Equal(t, 3, item.Number)
+
+		api.AssertNumberOfCalls(t, "CreatePost", 3)
+		api.AssertCalled(t, "CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.UserId == "botID" && post.Message == "#### #dev-Jan06 1) Metrics review"
+		}))
+		api.AssertCalled(t, "CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.UserId == "botID" && post.Message == "#### #dev-Jan06 2) Open floor"
+		}))
+		api.AssertCalled(t, "CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.UserId == "member" && post.Message == "#### #dev-Jan06 3) Release status"
+		}))
+	})
+
+	t.Run("occurrence already seeded", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Name: "dev", TeamId: "teamID"}, nil)
+		api.On("KVGet", webhooksKeyPrefix+"channelID").Return(nil, nil)
+		api.On("SearchPostsInTeamForUser", "teamID", "member", model.SearchParameter{Terms: &terms}).Return(model.MakePostSearchResults(model.NewPostList(), nil), nil)
+		api.On("KVSetWithOptions", seededKey, []byte("#dev-Jan06"), model.PluginKVSetOptions{Atomic: true, ExpireInSeconds: standingItemsSeededExpiry}).Return(false, nil)
+		api.On("CreatePost", mock.Anything).Return(func(post *model.Post) *model.Post {
+			created := post.Clone()
+			created.Id = model.NewId()
+			return created
+		}, nil)
+		p := Plugin{botID: "botID"}
+		p.SetAPI(api)
+
+		item, _, err := p.queueAgendaItem(meeting, "teamID", "member", "", "#dev-Jan06", "Release status")
+		assert.Nil(t, err)
+		assert.Equal(t, 1, item.Number)
+		api.AssertNumberOfCalls(t, "CreatePost", 1)
+	})
+}
+
+func TestPlugin_seedOccurrence(t *testing.T) {
+	meeting := &Meeting{ChannelID: "channelID", HashtagFormat: "dev-{{Jan02}}", StandingItems: []string{"Metrics review", "Open floor"}}
+	start := time.Date(2022, 1, 6, 15, 0, 0, 0, time.UTC)
+	seededKey := standingItemsKeyPrefix + "channelID_#dev-Jan06"
+
+	t.Run("occurrence without items", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("KVGet", seededKey).Return(nil, nil)
+		api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Name: "dev", TeamId: "teamID"}, nil)
+		api.On("SearchPostsInTeam", "teamID", mock.Anything).Return([]*model.Post{}, nil)
+		api.On("KVSetWithOptions", seededKey, []byte("#dev-Jan06"), model.PluginKVSetOptions{Atomic: true, ExpireInSeconds: standingItemsSeededExpiry}).Return(true, nil)
+		api.On("KVGet", webhooksKeyPrefix+"channelID").Return(nil, nil)
+		api.On("CreatePost", mock.Anything).Return(func(post *model.Post) *model.Post { return post }, nil)
+		p := Plugin{botID: "botID"}
+		p.SetAPI(api)
+
+		assert.Nil(t, p.seedOccurrence(meeting, start))
+		api.AssertNumberOfCalls(t, "CreatePost", 2)
+		api.AssertCalled(t, "CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.UserId == "botID" && post.Message == "#### #dev-Jan06 1) Metrics review"
+		}))
+		api.AssertCalled(t, "CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.UserId == "botID" && post.Message == "#### #dev-Jan06 2) Open floor"
+		}))
+	})
+
+	t.Run("occurrence already seeded", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("KVGet", seededKey).Return([]byte("#dev-Jan06"), nil)
+		p := Plugin{botID: "botID"}
+		p.SetAPI(api)
+
+		assert.Nil(t, p.seedOccurrence(meeting, start))
+		api.AssertNotCalled(t, "SearchPostsInTeam", mock.Anything, mock.Anything)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+
+	t.Run("occurrence with items", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("KVGet", seededKey).Return(nil, nil)
+		api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Name: "dev", TeamId: "teamID"}, nil)
+		api.On("SearchPostsInTeam", "teamID", mock.Anything).Return([]*model.Post{
+			{Id: "post1", ChannelId: "channelID", UserId: "member", Message: "#### #dev-Jan06 1) Release status"},
+		}, nil)
+		api.On("KVSetWithOptions", seededKey, []byte("#dev-Jan06"), model.PluginKVSetOptions{ExpireInSeconds: standingItemsSeededExpiry}).Return(true, nil)
+		p := Plugin{botID: "botID"}
+		p.SetAPI(api)
+
+		assert.Nil(t, p.seedOccurrence(meeting, start))
+		api.AssertCalled(t, "KVSetWithOptions", seededKey, []byte("#dev-Jan06"), model.PluginKVSetOptions{ExpireInSeconds: standingItemsSeededExpiry})
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+}
+
+func TestPlugin_executeCommandTemplate(t *testing.T) {
+	p := Plugin{}
+	p.setConfiguration(&configuration{})
+
+	tests := []struct {
+		name         string
+		command      string
+		meeting      string
+		wantText     string
+		wantStanding []string
+	}{
+		{
+			name:     "list without items",
+			command:  "/agenda template list",
+			meeting:  `{"channelId":"channelID","schedule":[4],"hashtagFormat":"dev-{{Jan02}}"}`,
+			wantText: "This meeting has no standing items. Add one with `/agenda template add <item>`.",
+		},
+		{
+			name:     "list",
+			command:  "/agenda template list",
+			meeting:  `{"channelId":"channelID","schedule":[4],"hashtagFormat":"dev-{{Jan02}}","standingItems":["Metrics review","Incidents"]}`,
+			wantText: "#### Standing items of this meeting\n1. Metrics review\n2. Incidents",
+		},
+		{
+			name:         "add",
+			command:      "/agenda template add Open  floor",
+			meeting:      `{"channelId":"channelID","schedule":[4],"hashtagFormat":"dev-{{Jan02}}","standingItems":["Metrics review"]}`,
+			wantText:     "Added standing item 2: Open floor. It will be queued first on the next meetings that have no items yet.",
+			wantStanding: []string{"Metrics review", "Open floor"},
+		},
+		{
+			name:     "add a repeated item",
+			command:  "/agenda template add metrics review",
+			meeting:  `{"channelId":"channelID","schedule":[4],"hashtagFormat":"dev-{{Jan02}}","standingItems":["Metrics review"]}`,
+			wantText: "The standing item metrics review is repeated",
+		},
+		{
+			name:         "remove",
+			command:      "/agenda template remove 1",
+			meeting:      `{"channelId":"channelID","schedule":[4],"hashtagFormat":"dev-{{Jan02}}","standingItems":["Metrics review","Incidents"]}`,
+			wantText:     "Removed standing item 1: Metrics review.",
+			wantStanding: []string{"Incidents"},
+		},
+		{
+			name:     "remove an unknown item",
+			command:  "/agenda template remove 3",
+			meeting:  `{"channelId":"channelID","schedule":[4],"hashtagFormat":"dev-{{Jan02}}","standingItems":["Metrics review"]}`,
+			wantText: "Invalid item number 3. Use `/agenda template list` to see the standing items.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The meeting is saved with only its standing items changed
+			var meeting *Meeting
+			assert.Nil(t, json.Unmarshal([]byte(tt.meeting), &meeting))
+			meeting.StandingItems = tt.wantStanding
+			saved, err := json.Marshal(meeting)
+			assert.Nil(t, err)
+
+			api := &plugintest.API{}
+			api.On("KVGet", "channelID").Return([]byte(tt.meeting), nil)
+			api.On("GetChannelMember", "channelID", "member").Return(&model.ChannelMember{}, nil)
+			api.On("HasPermissionTo", "member", model.PermissionManageSystem).Return(false)
+			api.On("KVSet", "channelID", saved).Return(nil)
+			p.SetAPI(api)
+
+			response := p.executeCommandTemplate(&model.CommandArgs{Command: tt.command, ChannelId: "channelID", UserId: "member"})
+			assert.Equal(t, tt.wantText, response.Text)
+
+			if tt.wantStanding == nil {
+				api.AssertNotCalled(t, "KVSet", mock.Anything, mock.Anything)
+				return
+			}
+			api.AssertCalled(t, "KVSet", "channelID", saved)
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/pkg/errors"
 )
@@ -53,12 +54,12 @@ func parseScheduleNumber(val string) (time.Weekday, error) {
 // nextWeekdayDate calculates the date of the next weekday from the given
 // list of days from today's date.
 // If nextWeek is true, it will be based on the next calendar week.
-func nextWeekdayDateInWeek(meetingDays []time.Weekday, nextWeek bool) (*time.Time, error) {
+func nextWeekdayDateInWeek(today time.Time, meetingDays []time.Weekday, nextWeek bool) (*time.Time, error) {
 	if len(meetingDays) == 0 {
 		return nil, errors.New("missing weekdays to calculate date")
 	}
 
-	todayWeekday := time.Now().Weekday()
+	todayWeekday := today.Weekday()
 
 	// Find which meeting weekday to calculate the date for
 	meetingDay := meetingDays[0]
@@ -69,15 +70,15 @@ func nextWeekdayDateInWeek(meetingDays []time.Weekday, nextWeek bool) (*time.Tim
 		}
 	}
 
-	return nextWeekdayDate(meetingDay, nextWeek)
+	return nextWeekdayDate(today, meetingDay, nextWeek)
 }
 
 // nextWeekdayDate calculates the date of the next given weekday
 // from today's date.
 // If nextWeek is true, it will be based on the next calendar week.
-func nextWeekdayDate(meetingDay time.Weekday, nextWeek bool) (*time.Time, error) {
-	daysTill := daysTillNextWeekday(time.Now().Weekday(), meetingDay, nextWeek)
-	nextDate := time.Now().AddDate(0, 0, daysTill)
+func nextWeekdayDate(today time.Time, meetingDay time.Weekday, nextWeek bool) (*time.Time, error) {
+	daysTill := daysTillNextWeekday(today.Weekday(), meetingDay, nextWeek)
+	nextDate := today.AddDate(0, 0, daysTill)
 
 	return &nextDate, nil
 }
@@ -97,3 +98,48 @@ func daysTillNextWeekday(today time.Weekday, nextDay time.Weekday, nextWeek bool
 
 	return daysTillNextWeekday
 }
+
+// countOccurrences counts the meeting days of the schedule after from, up to and including to.
+func countOccurrences(meetingDays []time.Weekday, from, to time.Time) int {
+	count := 0
+	for day := from.AddDate(0, 0, 1); !day.After(to); day = day.AddDate(0, 0, 1) {
+		for _, meetingDay := range meetingDays {
+			if day.Weekday() == meetingDay {
+				count++
+				break
+			}
+		}
+	}
+
+	return count
+}
+
+// parseOptionalDuration parses a positive duration setting. `off` or an empty value disable the setting.
+func parseOptionalDuration(value string) (string, error) {
+	if value == "" || value == "off" {
+		return "", nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return "", errors.New("invalid duration. Must be a duration such as 30m or 2h, or `off`")
+	}
+
+	return duration.String(), nil
+}
+
+// commandArguments returns the text of the command after its first skip words, keeping
+// the newlines and spacing of the text.
+func commandArguments(command string, skip int) string {
+	rest := command
+	for i := 0; i < skip; i++ {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		end := strings.IndexFunc(rest, unicode.IsSpace)
+		if end < 0 {
+			return ""
+		}
+		rest = rest[end:]
+	}
+
+	return strings.TrimSpace(rest)
+}
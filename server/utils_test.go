@@ -41,3 +41,45 @@ func Test_parseSchedule(t *testing.T) {
 		})
 	}
 }
+
+func Test_commandArguments(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		skip    int
+		want    string
+	}{
+		{name: "single line", command: "/agenda queue my  item", skip: 2, want: "my  item"},
+		{name: "multi line", command: "/agenda queue Mon Title\n- first\n- second", skip: 3, want: "Title\n- first\n- second"},
+		{name: "code block", command: "/agenda queue Title\n```\nfunc main() {\n\tpanic()\n}\n```", skip: 2, want: "Title\n```\nfunc main() {\n\tpanic()\n}\n```"},
+		{name: "words on several lines", command: "/agenda\nqueue\nTitle", skip: 2, want: "Title"},
+		{name: "nothing left", command: "/agenda queue", skip: 2, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := commandArguments(tt.command, tt.skip); got != tt.want {
+				t.Errorf("commandArguments() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_splitItemMessage(t *testing.T) {
+	tests := []struct {
+		message string
+		title   string
+		details string
+	}{
+		{message: "Title", title: "Title", details: ""},
+		{message: "Title\n- first\n- second", title: "Title", details: "- first\n- second"},
+		{message: "Title\n\n```\ncode\n```\n", title: "Title", details: "```\ncode\n```"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			title, details := splitItemMessage(tt.message)
+			if title != tt.title || details != tt.details {
+				t.Errorf("splitItemMessage() = %q, %q, want %q, %q", title, details, tt.title, tt.details)
+			}
+		})
+	}
+}
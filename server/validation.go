@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// minimumHashtagLength is the default minimum length of a Mattermost hashtag, without the #
+	minimumHashtagLength = 3
+)
+
+// validHashtagRegex matches the hashtags Mattermost makes searchable
+var validHashtagRegex = regexp.MustCompile(`^#\pL[\pL\d\-_.]*[\pL\d]$`)
+
+// ValidationError lists the invalid fields of the meeting settings, keyed by their JSON name
+type ValidationError struct {
+	Message string            `json:"error"`
+	Fields  map[string]string `json:"fields"`
+}
+
+func (e *ValidationError) Error() string {
+	fields := make([]string, 0, len(e.Fields))
+	for field := range e.Fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	messages := make([]string, 0, len(fields))
+	for _, field := range fields {
+		messages = append(messages, fmt.Sprintf("%s: %s", field, e.Fields[field]))
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// Validate checks that the meeting settings can be used to queue and list items
+func (m *Meeting) Validate(minHashtagLength int) error {
+	fields := map[string]string{}
+
+	if m.ChannelID == "" {
+		fields["channelId"] = "A channel is required"
+	}
+
+	if len(m.Schedule) == 0 {
+		fields["schedule"] = "At least one meeting day is required"
+	}
+	for _, weekday := range m.Schedule {
+		if weekday < time.Sunday || weekday > time.Saturday {
+			fields["schedule"] = fmt.Sprintf("Invalid weekday %d. Must be between 0 (Sunday) and 6 (Saturday)", weekday)
+			break
+		}
+	}
+
+	if problem := m.validateHashtagFormat(minHashtagLength); problem != "" {
+		fields["hashtagFormat"] = problem
+	}
+
+	if m.Time != "" {
+		if _, err := time.Parse(meetingTimeFormat, m.Time); err != nil {
+			fields["time"] = "Invalid time. Must be formatted as HH:MM, i.e. 15:30"
+		}
+	}
+
+	if m.Timezone != "" {
+		if _, err := time.LoadLocation(m.Timezone); err != nil {
+			fields["timezone"] = "Invalid timezone. Must be an IANA timezone, i.e. America/New_York"
+		}
+	}
+
+	if _, err := parseReminder(m.Reminder); err != nil {
+		fields["reminder"] = err.Error()
+	}
+	if _, err := parseOptionalDuration(m.QueueCutoff); err != nil {
+		fields["queueCutoff"] = err.Error()
+	}
+	if _, err := parseOptionalDuration(m.EmptyCheck); err != nil {
+		fields["emptyCheck"] = err.Error()
+	}
+	switch m.PostAs {
+	case "", postAsUser, postAsBot:
+	default:
+		fields["postAs"] = "Invalid value. Must be user or bot"
+	}
+	if problem := validateStandingItems(m.StandingItems); problem != "" {
+		fields["standingItems"] = problem
+	}
+
+	if len(fields) > 0 {
+		return &ValidationError{
+			Message: "Invalid meeting settings",
+			Fields:  fields,
+		}
+	}
+
+	return nil
+}
+
+// validateHashtagFormat renders the hashtag of every day of a year and returns a description
+// of the first problem found, or an empty string if all the hashtags are valid.
+func (m *Meeting) validateHashtagFormat(minLength int) string {
+	if strings.TrimSpace(m.HashtagFormat) == "" {
+		return "A hashtag format is required"
+	}
+	// The agenda items are found with the regular expression of the format, which only
+	// supports text and placeholders
+	if _, err := m.hashtagRegex(); err != nil {
+		return err.Error()
+	}
+
+	start := time.Date(time.Now().Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	for day := start; day.Year() == start.Year(); day = day.AddDate(0, 0, 1) {
+		hashtag, err := m.renderHashtag(day)
+		if err != nil {
+			return err.Error()
+		}
+		if problem := hashtagProblem(hashtag, minLength); problem != "" {
+			return problem
+		}
+	}
+
+	return ""
+}
+
+// hashtagProblem returns why the given hashtag would not be searchable in Mattermost,
+// or an empty string if it is valid.
+func hashtagProblem(hashtag string, minLength int) string {
+	if len([]rune(strings.TrimPrefix(hashtag, "#"))) < minLength {
+		return fmt.Sprintf("The hashtag %s is shorter than the minimum of %d characters", hashtag, minLength)
+	}
+	if !validHashtagRegex.MatchString(hashtag) {
+		return fmt.Sprintf("The hashtag %s is invalid. Hashtags must start with a letter and can only contain letters, numbers, dashes, underscores and periods", hashtag)
+	}
+	return ""
+}
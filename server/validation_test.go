@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMeeting_Validate(t *testing.T) {
+	valid := func() *Meeting {
+		return &Meeting{
+			ChannelID:     "myChannelId",
+			Schedule:      []time.Weekday{time.Monday, time.Thursday},
+			HashtagFormat: "dev-{{ Jan02 }}",
+			Time:          "15:30",
+			Timezone:      "America/New_York",
+			Reminder:      "1h0m0s",
+			QueueCutoff:   "2h0m0s",
+		}
+	}
+
+	assert.Nil(t, valid().Validate(minimumHashtagLength))
+	// The minimum hashtag length configured on the server applies
+	assert.NotNil(t, valid().Validate(12))
+
+	tests := []struct {
+		name   string
+		modify func(m *Meeting)
+		field  string
+	}{
+		{name: "missing channel", modify: func(m *Meeting) { m.ChannelID = "" }, field: "channelId"},
+		{name: "empty schedule", modify: func(m *Meeting) { m.Schedule = nil }, field: "schedule"},
+		{name: "weekday out of range", modify: func(m *Meeting) { m.Schedule = []time.Weekday{7} }, field: "schedule"},
+		{name: "negative weekday", modify: func(m *Meeting) { m.Schedule = []time.Weekday{-1} }, field: "schedule"},
+		{name: "empty hashtag", modify: func(m *Meeting) { m.HashtagFormat = " " }, field: "hashtagFormat"},
+		{name: "hashtag with spaces", modify: func(m *Meeting) { m.HashtagFormat = "React {{January 02 2006}} Born" }, field: "hashtagFormat"},
+		{name: "hashtag with illegal characters", modify: func(m *Meeting) { m.HashtagFormat = "dev/{{Jan02}}" }, field: "hashtagFormat"},
+		{name: "hashtag starting with a number", modify: func(m *Meeting) { m.HashtagFormat = "{{0102}}" }, field: "hashtagFormat"},
+		{name: "hashtag padded with spaces on some days", modify: func(m *Meeting) { m.HashtagFormat = "dev{{Jan_2}}" }, field: "hashtagFormat"},
+		{name: "hashtag with unknown placeholder", modify: func(m *Meeting) { m.HashtagFormat = "dev-{{ .Unknown }}" }, field: "hashtagFormat"},
+		{name: "hashtag with a condition", modify: func(m *Meeting) { m.HashtagFormat = `dev-{{ if .Year }}{{ .Date "Jan02" }}{{ end }}` }, field: "hashtagFormat"},
+		{name: "hashtag with a loop", modify: func(m *Meeting) { m.HashtagFormat = `dev-{{ .Year }}{{ range .MeetingName }}x{{ end }}` }, field: "hashtagFormat"},
+		{name: "hashtag too short", modify: func(m *Meeting) { m.HashtagFormat = "a{{1}}" }, field: "hashtagFormat"},
+		{name: "invalid time", modify: func(m *Meeting) { m.Time = "3pm" }, field: "time"},
+		{name: "invalid timezone", modify: func(m *Meeting) { m.Timezone = "Mars/Olympus" }, field: "timezone"},
+		{name: "invalid reminder", modify: func(m *Meeting) { m.Reminder = "soon" }, field: "reminder"},
+		{name: "invalid cutoff", modify: func(m *Meeting) { m.QueueCutoff = "-2h" }, field: "queueCutoff"},
+		{name: "invalid empty check", modify: func(m *Meeting) { m.EmptyCheck = "later" }, field: "emptyCheck"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meeting := valid()
+			tt.modify(meeting)
+
+			err := meeting.Validate(minimumHashtagLength)
+			assert.NotNil(t, err)
+			validationErr, ok := err.(*ValidationError)
+			assert.True(t, ok)
+			assert.Len(t, validationErr.Fields, 1)
+			assert.Contains(t, validationErr.Fields, tt.field)
+		})
+	}
+}
+
+func Test_hashtagProblem(t *testing.T) {
+	assert.Empty(t, hashtagProblem("#dev-Jan02", 3))
+	assert.Empty(t, hashtagProblem("#réunion_2022.01", 3))
+	assert.NotEmpty(t, hashtagProblem("#ab", 3))
+	assert.NotEmpty(t, hashtagProblem("#dev-", 3))
+	assert.NotEmpty(t, hashtagProblem("#dev Jan02", 3))
+}
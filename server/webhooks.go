@@ -0,0 +1,419 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	webhooksKeyPrefix   = "webhooks_"
+	webhookLogKeyPrefix = "webhook_log_"
+
+	// webhookLogSize is the number of deliveries kept in the delivery log of a channel
+	webhookLogSize = 50
+
+	// webhookSignatureHeader holds the hex HMAC-SHA256 of the payload, keyed with the secret of the webhook
+	webhookSignatureHeader = "X-Agenda-Signature"
+	webhookEventHeader     = "X-Agenda-Event"
+	webhookDeliveryHeader  = "X-Agenda-Delivery"
+
+	webhookEventItemQueued     = "item_queued"
+	webhookEventItemEdited     = "item_edited"
+	webhookEventItemRemoved    = "item_removed"
+	webhookEventItemsReordered = "items_reordered"
+	webhookEventMeetingStarted = "meeting_started"
+	webhookEventMeetingEnded   = "meeting_ended"
+)
+
+var (
+	// webhookRetryDelays are the delays before retrying a failed delivery
+	webhookRetryDelays = []time.Duration{5 * time.Second, 30 * time.Second, 2 * time.Minute, 10 * time.Minute}
+
+	// webhookClient only connects to public addresses, so webhooks can't reach the internal
+	// services of the server network. The addresses are checked once resolved, when dialing.
+	webhookClient = &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext: (&net.Dialer{
+				Timeout: 5 * time.Second,
+				Control: func(network, address string, _ syscall.RawConn) error {
+					host, _, err := net.SplitHostPort(address)
+					if err != nil {
+						return err
+					}
+					if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+						return errors.Errorf("the webhook address %s is not public", host)
+					}
+					return nil
+				},
+			}).DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+		},
+	}
+
+	// sharedAddressSpace is the carrier-grade NAT range, which also holds cloud metadata services
+	sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+)
+
+// Webhook is a URL notified of the agenda events of a channel
+type Webhook struct {
+	ID        string `json:"id"`
+	URL       string `json:"url"`
+	Secret    string `json:"secret"`
+	CreatedBy string `json:"createdBy"`
+	CreateAt  int64  `json:"createAt"`
+}
+
+// WebhookPayload is the JSON body posted to the webhooks
+type WebhookPayload struct {
+	Event      string        `json:"event"`
+	DeliveryID string        `json:"deliveryId"`
+	Timestamp  int64         `json:"timestamp"`
+	ChannelID  string        `json:"channelId"`
+	Hashtag    string        `json:"hashtag,omitempty"`
+	Date       string        `json:"date,omitempty"`
+	UserID     string        `json:"userId,omitempty"`
+	Item       *AgendaItem   `json:"item,omitempty"`
+	Items      []*AgendaItem `json:"items,omitempty"`
+	Attendees  []string      `json:"attendees,omitempty"`
+}
+
+// WebhookDelivery is an entry of the delivery log
+type WebhookDelivery struct {
+	ID         string `json:"id"`
+	WebhookID  string `json:"webhookId"`
+	Event      string `json:"event"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	Attempts   int    `json:"attempts"`
+	Error      string `json:"error,omitempty"`
+	Success    bool   `json:"success"`
+	CreateAt   int64  `json:"createAt"`
+}
+
+// signWebhookPayload returns the signature of the payload
+func signWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// newWebhookSecret returns a random secret to sign the payloads of a webhook
+func newWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// isPublicIP returns false for the loopback, private, link-local, shared and unspecified addresses,
+// such as the 169.254.169.254 metadata service of cloud providers
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified() && !sharedAddressSpace.Contains(ip)
+}
+
+// validateWebhookURL returns an error if the URL can't receive webhooks. The addresses that
+// host names resolve to are checked when delivering.
+func validateWebhookURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errors.Errorf("%s is not a valid http or https URL", value)
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if ip := net.ParseIP(host); (ip != nil && !isPublicIP(ip)) || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errors.Errorf("%s is not a public URL", value)
+	}
+	return nil
+}
+
+// getWebhooks returns the webhooks of the channel
+func (p *Plugin) getWebhooks(channelID string) ([]*Webhook, error) {
+	webhooksBytes, appErr := p.API.KVGet(webhooksKeyPrefix + channelID)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if webhooksBytes == nil {
+		return nil, nil
+	}
+
+	var webhooks []*Webhook
+	if err := json.Unmarshal(webhooksBytes, &webhooks); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// saveWebhooks stores the webhooks of the channel
+func (p *Plugin) saveWebhooks(channelID string, webhooks []*Webhook) error {
+	if len(webhooks) == 0 {
+		if appErr := p.API.KVDelete(webhooksKeyPrefix + channelID); appErr != nil {
+			return appErr
+		}
+		return nil
+	}
+
+	webhooksBytes, err := json.Marshal(webhooks)
+	if err != nil {
+		return err
+	}
+	if appErr := p.API.KVSet(webhooksKeyPrefix+channelID, webhooksBytes); appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+// getWebhookLog returns the delivery log of the channel, most recent first
+func (p *Plugin) getWebhookLog(channelID string) ([]*WebhookDelivery, error) {
+	logBytes, appErr := p.API.KVGet(webhookLogKeyPrefix + channelID)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if logBytes == nil {
+		return nil, nil
+	}
+
+	var deliveries []*WebhookDelivery
+	if err := json.Unmarshal(logBytes, &deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// logWebhookDelivery adds the delivery to the delivery log of the channel, keeping the most recent ones.
+// Deliveries run concurrently, so the log is updated with compare and set.
+func (p *Plugin) logWebhookDelivery(channelID string, delivery *WebhookDelivery) error {
+	for i := 0; i < 5; i++ {
+		oldBytes, appErr := p.API.KVGet(webhookLogKeyPrefix + channelID)
+		if appErr != nil {
+			return appErr
+		}
+
+		var deliveries []*WebhookDelivery
+		if oldBytes != nil {
+			if err := json.Unmarshal(oldBytes, &deliveries); err != nil {
+				return err
+			}
+		}
+
+		deliveries = append([]*WebhookDelivery{delivery}, deliveries...)
+		if len(deliveries) > webhookLogSize {
+			deliveries = deliveries[:webhookLogSize]
+		}
+
+		newBytes, err := json.Marshal(deliveries)
+		if err != nil {
+			return err
+		}
+
+		saved, appErr := p.API.KVSetWithOptions(webhookLogKeyPrefix+channelID, newBytes, model.PluginKVSetOptions{
+			Atomic:   true,
+			OldValue: oldBytes,
+		})
+		if appErr != nil {
+			return appErr
+		}
+		if saved {
+			return nil
+		}
+	}
+
+	return errors.New("the delivery log was updated concurrently too many times")
+}
+
+// notifyWebhooks delivers the event to the webhooks of the channel in the background
+func (p *Plugin) notifyWebhooks(channelID string, payload WebhookPayload) {
+	webhooks, err := p.getWebhooks(channelID)
+	if err != nil {
+		p.API.LogWarn("Failed to get the webhooks of the channel", "error", err.Error(), "channel_id", channelID)
+		return
+	}
+
+	payload.ChannelID = channelID
+	payload.Timestamp = model.GetMillis()
+	for _, webhook := range webhooks {
+		go p.deliverWebhook(webhook, payload)
+	}
+}
+
+// deliverWebhook posts the payload to the webhook, retrying with backoff when the delivery fails
+// with a network error, a server error or too many requests, until the plugin is deactivated.
+// It logs the outcome of the delivery.
+func (p *Plugin) deliverWebhook(webhook *Webhook, payload WebhookPayload) *WebhookDelivery {
+	stop := p.stopWebhooks
+	payload.DeliveryID = model.NewId()
+	delivery := &WebhookDelivery{
+		ID:        payload.DeliveryID,
+		WebhookID: webhook.ID,
+		Event:     payload.Event,
+		CreateAt:  model.GetMillis(),
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+
+	for err == nil {
+		delivery.Attempts++
+		statusCode, retry, postErr := postWebhook(webhook, payload, body)
+		delivery.StatusCode = statusCode
+		if postErr == nil {
+			delivery.Success = true
+			delivery.Error = ""
+			break
+		}
+
+		delivery.Error = postErr.Error()
+		if !retry || delivery.Attempts > len(webhookRetryDelays) {
+			break
+		}
+
+		timer := time.NewTimer(webhookRetryDelays[delivery.Attempts-1])
+		select {
+		case <-timer.C:
+		case <-stop:
+			timer.Stop()
+			err = errors.New("the plugin was deactivated before retrying")
+			delivery.Error += ", and " + err.Error()
+		}
+	}
+
+	if !delivery.Success {
+		p.API.LogWarn("Failed to deliver webhook", "webhook_id", webhook.ID, "event", payload.Event, "channel_id", payload.ChannelID, "error", delivery.Error)
+	}
+	if err := p.logWebhookDelivery(payload.ChannelID, delivery); err != nil {
+		p.API.LogWarn("Failed to log webhook delivery", "webhook_id", webhook.ID, "error", err.Error())
+	}
+
+	return delivery
+}
+
+// postWebhook makes one delivery attempt. retry is true if the attempt failed in a way worth retrying.
+func postWebhook(webhook *Webhook, payload WebhookPayload, body []byte) (statusCode int, retry bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookEventHeader, payload.Event)
+	req.Header.Set(webhookDeliveryHeader, payload.DeliveryID)
+	req.Header.Set(webhookSignatureHeader, signWebhookPayload(webhook.Secret, body))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp.StatusCode, false, nil
+	}
+
+	retry = resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	return resp.StatusCode, retry, errors.Errorf("the webhook responded with status %d", resp.StatusCode)
+}
+
+func (p *Plugin) executeCommandWebhook(args *model.CommandArgs) *model.CommandResponse {
+	split := strings.Fields(args.Command)
+	if len(split) < 3 {
+		return responsef("Missing action. Usage: `/agenda webhook add <url>|list|remove <id>|log`")
+	}
+
+	if !p.isChannelAdmin(args.ChannelId, args.UserId) {
+		return responsef("Only channel admins can manage the webhooks of this channel")
+	}
+
+	webhooks, err := p.getWebhooks(args.ChannelId)
+	if err != nil {
+		return responsef("Error getting the webhooks of this channel")
+	}
+
+	switch split[2] {
+	case "add":
+		if len(split) < 4 {
+			return responsef("Missing URL. Usage: `/agenda webhook add <url>`")
+		}
+		if err = validateWebhookURL(split[3]); err != nil {
+			return responsef(err.Error())
+		}
+
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return responsef("Error generating the webhook secret")
+		}
+		webhook := &Webhook{
+			ID:        model.NewId(),
+			URL:       split[3],
+			Secret:    secret,
+			CreatedBy: args.UserId,
+			CreateAt:  model.GetMillis(),
+		}
+		if err = p.saveWebhooks(args.ChannelId, append(webhooks, webhook)); err != nil {
+			return responsef("Error saving the webhook")
+		}
+
+		return responsef("Added webhook `%s` for %s.\nPayloads are signed with HMAC-SHA256 in the `%s` header using the secret `%s`. "+
+			"Store the secret now, it will not be shown again.", webhook.ID, webhook.URL, webhookSignatureHeader, webhook.Secret)
+
+	case "list":
+		if len(webhooks) == 0 {
+			return responsef("No webhooks are registered for this channel.")
+		}
+		lines := []string{"#### Webhooks of this channel"}
+		for _, webhook := range webhooks {
+			lines = append(lines, fmt.Sprintf("* `%s` %s", webhook.ID, webhook.URL))
+		}
+		return responsef(strings.Join(lines, "\n"))
+
+	case "remove":
+		if len(split) < 4 {
+			return responsef("Missing webhook ID. Usage: `/agenda webhook remove <id>`")
+		}
+		for i, webhook := range webhooks {
+			if webhook.ID == split[3] {
+				if err = p.saveWebhooks(args.ChannelId, append(webhooks[:i], webhooks[i+1:]...)); err != nil {
+					return responsef("Error removing the webhook")
+				}
+				return responsef("Removed webhook `%s` for %s.", webhook.ID, webhook.URL)
+			}
+		}
+		return responsef("No webhook %s is registered for this channel", split[3])
+
+	case "log":
+		deliveries, err := p.getWebhookLog(args.ChannelId)
+		if err != nil {
+			return responsef("Error getting the webhook delivery log")
+		}
+		if len(deliveries) == 0 {
+			return responsef("No webhook deliveries yet.")
+		}
+		lines := []string{"#### Recent webhook deliveries\n| Time | Webhook | Event | Attempts | Result |\n|:--|:--|:--|:--|:--|"}
+		for _, delivery := range deliveries {
+			result := "Delivered"
+			if !delivery.Success {
+				result = "Failed: " + delivery.Error
+			}
+			lines = append(lines, fmt.Sprintf("| %s | `%s` | %s | %d | %s |",
+				time.UnixMilli(delivery.CreateAt).UTC().Format(time.RFC3339), delivery.WebhookID, delivery.Event, delivery.Attempts, result))
+		}
+		return responsef(strings.Join(lines, "\n"))
+	}
+
+	return responsef("Unknown webhook action: %s", split[2])
+}
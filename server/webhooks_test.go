@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignWebhookPayload(t *testing.T) {
+	// echo -n '{"event":"item_queued"}' | openssl dgst -sha256 -hmac secret
+	assert.Equal(t, "sha256=d7ec532498f130ddeca0b57b9a6c10702d2ffe1aa37fd0ed3898fee9a932e240", signWebhookPayload("secret", []byte(`{"event":"item_queued"}`)))
+	assert.NotEqual(t, signWebhookPayload("secret", []byte("body")), signWebhookPayload("other", []byte("body")))
+}
+
+func TestValidateWebhookURL(t *testing.T) {
+	assert.Nil(t, validateWebhookURL("https://wiki.example.com/hooks/agenda"))
+	assert.NotNil(t, validateWebhookURL("ftp://example.com"))
+	assert.NotNil(t, validateWebhookURL("not a url"))
+	assert.NotNil(t, validateWebhookURL("http://localhost:8065/hooks"))
+	assert.NotNil(t, validateWebhookURL("http://127.0.0.1/hooks"))
+	assert.NotNil(t, validateWebhookURL("http://10.0.0.12/hooks"))
+	assert.NotNil(t, validateWebhookURL("http://169.254.169.254/latest/meta-data"))
+	assert.NotNil(t, validateWebhookURL("http://[::1]/hooks"))
+}
+
+func TestIsPublicIP(t *testing.T) {
+	for _, ip := range []string{"93.184.216.34", "2606:2800:220:1:248:1893:25c8:1946"} {
+		assert.True(t, isPublicIP(net.ParseIP(ip)), ip)
+	}
+	for _, ip := range []string{"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "100.100.100.200", "0.0.0.0", "::1", "fd00:ec2::254", "fe80::1", "::ffff:127.0.0.1"} {
+		assert.False(t, isPublicIP(net.ParseIP(ip)), ip)
+	}
+}
+
+func TestWebhookClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// The test server listens on a loopback address
+	_, err := webhookClient.Get(server.URL)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "is not public")
+}
+
+func TestPlugin_deliverWebhook(t *testing.T) {
+	defaultDelays := webhookRetryDelays
+	defaultClient := webhookClient
+	webhookRetryDelays = []time.Duration{time.Millisecond, time.Millisecond}
+	// The test servers listen on loopback addresses
+	webhookClient = &http.Client{Timeout: time.Second}
+	defer func() {
+		webhookRetryDelays = defaultDelays
+		webhookClient = defaultClient
+	}()
+
+	webhook := &Webhook{ID: "webhook1", Secret: "secret"}
+	payload := WebhookPayload{
+		Event:     webhookEventItemQueued,
+		ChannelID: "channelID",
+		Hashtag:   "#dev-Jan06",
+		Item:      &AgendaItem{Number: 1, Title: "Release status"},
+	}
+
+	t.Run("signed delivery", func(t *testing.T) {
+		var received WebhookPayload
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			assert.Equal(t, signWebhookPayload("secret", body), r.Header.Get(webhookSignatureHeader))
+			assert.Equal(t, webhookEventItemQueued, r.Header.Get(webhookEventHeader))
+			assert.Nil(t, json.Unmarshal(body, &received))
+			assert.Equal(t, received.DeliveryID, r.Header.Get(webhookDeliveryHeader))
+		}))
+		defer server.Close()
+
+		api := &plugintest.API{}
+		api.On("KVGet", webhookLogKeyPrefix+"channelID").Return(nil, nil)
+		api.On("KVSetWithOptions", webhookLogKeyPrefix+"channelID", mock.AnythingOfType("[]uint8"), model.PluginKVSetOptions{Atomic: true}).Return(true, nil)
+		p := Plugin{}
+		p.SetAPI(api)
+		webhook.URL = server.URL
+
+		delivery := p.deliverWebhook(webhook, payload)
+		assert.True(t, delivery.Success)
+		assert.Equal(t, 1, delivery.Attempts)
+		assert.Equal(t, http.StatusOK, delivery.StatusCode)
+		assert.Equal(t, "Release status", received.Item.Title)
+		assert.Equal(t, "#dev-Jan06", received.Hashtag)
+		logged, err := json.Marshal([]*WebhookDelivery{delivery})
+		assert.Nil(t, err)
+		api.AssertCalled(t, "KVSetWithOptions", webhookLogKeyPrefix+"channelID", logged, model.PluginKVSetOptions{Atomic: true})
+	})
+
+	t.Run("retried after server errors", func(t *testing.T) {
+		var attempts int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&attempts, 1) < 3 {
+				w.WriteHeader(http.StatusBadGateway)
+			}
+		}))
+		defer server.Close()
+
+		api := &plugintest.API{}
+		api.On("KVGet", webhookLogKeyPrefix+"channelID").Return(nil, nil)
+		api.On("KVSetWithOptions", webhookLogKeyPrefix+"channelID", mock.AnythingOfType("[]uint8"), model.PluginKVSetOptions{Atomic: true}).Return(true, nil)
+		p := Plugin{}
+		p.SetAPI(api)
+		webhook.URL = server.URL
+
+		delivery := p.deliverWebhook(webhook, payload)
+		assert.True(t, delivery.Success)
+		assert.Equal(t, 3, delivery.Attempts)
+		logged, err := json.Marshal([]*WebhookDelivery{delivery})
+		assert.Nil(t, err)
+		api.AssertCalled(t, "KVSetWithOptions", webhookLogKeyPrefix+"channelID", logged, model.PluginKVSetOptions{Atomic: true})
+	})
+
+	t.Run("gives up after the retries", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		api := &plugintest.API{}
+		api.On("KVGet", webhookLogKeyPrefix+"channelID").Return(nil, nil)
+		api.On("KVSetWithOptions", webhookLogKeyPrefix+"channelID", mock.AnythingOfType("[]uint8"), model.PluginKVSetOptions{Atomic: true}).Return(true, nil)
+		api.On("LogWarn", "Failed to deliver webhook", "webhook_id", "webhook1", "event", webhookEventItemQueued, "channel_id", "channelID", "error", "the webhook responded with status 503").Return()
+		p := Plugin{}
+		p.SetAPI(api)
+		webhook.URL = server.URL
+
+		delivery := p.deliverWebhook(webhook, payload)
+		assert.False(t, delivery.Success)
+		assert.Equal(t, 3, delivery.Attempts)
+		assert.Equal(t, "the webhook responded with status 503", delivery.Error)
+		logged, err := json.Marshal([]*WebhookDelivery{delivery})
+		assert.Nil(t, err)
+		api.AssertCalled(t, "KVSetWithOptions", webhookLogKeyPrefix+"channelID", logged, model.PluginKVSetOptions{Atomic: true})
+	})
+
+	t.Run("retries stop when the plugin is deactivated", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		webhookRetryDelays = []time.Duration{time.Hour}
+		defer func() { webhookRetryDelays = []time.Duration{time.Millisecond, time.Millisecond} }()
+
+		api := &plugintest.API{}
+		api.On("KVGet", webhookLogKeyPrefix+"channelID").Return(nil, nil)
+		api.On("KVSetWithOptions", webhookLogKeyPrefix+"channelID", mock.AnythingOfType("[]uint8"), model.PluginKVSetOptions{Atomic: true}).Return(true, nil)
+		api.On("LogWarn", "Failed to deliver webhook", "webhook_id", "webhook1", "event", webhookEventItemQueued, "channel_id", "channelID", "error", "the webhook responded with status 503, and the plugin was deactivated before retrying").Return()
+		p := Plugin{stopWebhooks: make(chan struct{})}
+		p.SetAPI(api)
+		webhook.URL = server.URL
+		close(p.stopWebhooks)
+
+		delivery := p.deliverWebhook(webhook, payload)
+		assert.False(t, delivery.Success)
+		assert.Equal(t, 1, delivery.Attempts)
+		assert.Equal(t, "the webhook responded with status 503, and the plugin was deactivated before retrying", delivery.Error)
+		logged, err := json.Marshal([]*WebhookDelivery{delivery})
+		assert.Nil(t, err)
+		api.AssertCalled(t, "KVSetWithOptions", webhookLogKeyPrefix+"channelID", logged, model.PluginKVSetOptions{Atomic: true})
+	})
+
+	t.Run("client errors are not retried", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		api := &plugintest.API{}
+		api.On("KVGet", webhookLogKeyPrefix+"channelID").Return(nil, nil)
+		api.On("KVSetWithOptions", webhookLogKeyPrefix+"channelID", mock.AnythingOfType("[]uint8"), model.PluginKVSetOptions{Atomic: true}).Return(true, nil)
+		api.On("LogWarn", "Failed to deliver webhook", "webhook_id", "webhook1", "event", webhookEventItemQueued, "channel_id", "channelID", "error", "the webhook responded with status 404").Return()
+		p := Plugin{}
+		p.SetAPI(api)
+		webhook.URL = server.URL
+
+		delivery := p.deliverWebhook(webhook, payload)
+		assert.False(t, delivery.Success)
+		assert.Equal(t, 1, delivery.Attempts)
+		assert.Equal(t, http.StatusNotFound, delivery.StatusCode)
+		logged, err := json.Marshal([]*WebhookDelivery{delivery})
+		assert.Nil(t, err)
+		api.AssertCalled(t, "KVSetWithOptions", webhookLogKeyPrefix+"channelID", logged, model.PluginKVSetOptions{Atomic: true})
+	})
+}
+
+func TestPlugin_logWebhookDelivery(t *testing.T) {
+	old := make([]*WebhookDelivery, webhookLogSize)
+	for i := range old {
+		old[i] = &WebhookDelivery{ID: model.NewId()}
+	}
+	oldBytes, _ := json.Marshal(old)
+
+	api := &plugintest.API{}
+	api.On("KVGet", webhookLogKeyPrefix+"channelID").Return(oldBytes, nil)
+	// The first update conflicts with another delivery
+	api.On("KVSetWithOptions", webhookLogKeyPrefix+"channelID", mock.AnythingOfType("[]uint8"), model.PluginKVSetOptions{Atomic: true, OldValue: oldBytes}).Return(false, nil).Once()
+	api.On("KVSetWithOptions", webhookLogKeyPrefix+"channelID", mock.AnythingOfType("[]uint8"), model.PluginKVSetOptions{Atomic: true, OldValue: oldBytes}).Return(true, nil).Once()
+	p := Plugin{}
+	p.SetAPI(api)
+
+	assert.Nil(t, p.logWebhookDelivery("channelID", &WebhookDelivery{ID: "new"}))
+	api.AssertNumberOfCalls(t, "KVSetWithOptions", 2)
+	// The new delivery comes first and the oldest one is dropped
+	logged, _ := json.Marshal(append([]*WebhookDelivery{{ID: "new"}}, old[:webhookLogSize-1]...))
+	api.AssertCalled(t, "KVSetWithOptions", webhookLogKeyPrefix+"channelID", logged, model.PluginKVSetOptions{Atomic: true, OldValue: oldBytes})
+}
@@ -1,4 +1,5 @@
 import {searchPostsWithParams} from 'mattermost-redux/actions/search';
+import {openInteractiveDialog} from 'mattermost-redux/actions/integrations';
 
 import {getCurrentTeamId} from 'mattermost-redux/selectors/entities/teams';
 import {getConfig} from 'mattermost-redux/selectors/entities/general';
@@ -25,10 +26,10 @@ export function fetchMeetingSettings(channelId = '') {
     };
 }
 
-export function saveMeetingSettings(meeting) {
+export async function saveMeetingSettings(meeting) {
     let data;
     try {
-        data = (new Client()).saveMeetingSettings(meeting);
+        data = await (new Client()).saveMeetingSettings(meeting);
     } catch (error) {
         return {error};
     }
@@ -36,6 +37,53 @@ export function saveMeetingSettings(meeting) {
     return {data};
 }
 
+export async function getHashtagPreview(channelId, format) {
+    let data;
+    try {
+        data = await (new Client()).getHashtagPreview(channelId, format);
+    } catch (error) {
+        return {error};
+    }
+
+    return {data};
+}
+
+export async function importICS(channelId, calendar) {
+    let data;
+    try {
+        data = await (new Client()).importICS(channelId, calendar);
+    } catch (error) {
+        return {error};
+    }
+
+    return {data};
+}
+
+export async function queuePost(postId) {
+    let data;
+    try {
+        data = await (new Client()).queuePost(postId);
+    } catch (error) {
+        return {error};
+    }
+
+    return {data};
+}
+
+export function openQueueDialog(channelId) {
+    return async (dispatch) => {
+        let data;
+        try {
+            data = await (new Client()).getQueueDialog(channelId);
+        } catch (error) {
+            return {error};
+        }
+
+        dispatch(openInteractiveDialog(data));
+        return {data};
+    };
+}
+
 export const openMeetingSettingsModal = (channelId = '') => (dispatch) => {
     dispatch({
         type: ActionTypes.OPEN_MEETING_SETTINGS_MODAL,
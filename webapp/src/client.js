@@ -16,6 +16,26 @@ export default class Client {
         return this.doPost(`${this.url}/settings`, meeting);
     }
 
+    getHashtagPreview = async (channelId, format) => {
+        return this.doGet(`${this.url}/hashtag-preview?channelId=${channelId}&format=${encodeURIComponent(format)}`);
+    }
+
+    queuePost = async (postId) => {
+        return this.doPost(`${this.url}/queue-post`, {postId});
+    }
+
+    getQueueDialog = async (channelId) => {
+        return this.doGet(`${this.url}/queue-dialog?channelId=${channelId}`);
+    }
+
+    importICS = async (channelId, calendar) => {
+        return this.doFetch(`${this.url}/import-ics?channelId=${channelId}`, {
+            method: 'POST',
+            body: calendar,
+            headers: {'Content-Type': 'text/calendar'},
+        });
+    }
+
     doGet = async (url, headers = {}) => {
         return this.doFetch(url, {headers});
     }
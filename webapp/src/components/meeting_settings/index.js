@@ -2,7 +2,7 @@ import {connect} from 'react-redux';
 import {bindActionCreators} from 'redux';
 
 import {getMeetingSettingsModalState, getMeetingSettings} from 'selectors';
-import {closeMeetingSettingsModal, fetchMeetingSettings, saveMeetingSettings} from 'actions';
+import {closeMeetingSettingsModal, fetchMeetingSettings, getHashtagPreview, importICS, saveMeetingSettings} from 'actions';
 
 import MeetingSettingsModal from './meeting_settings';
 
@@ -12,6 +12,8 @@ function mapStateToProps(state) {
         channelId: getMeetingSettingsModalState(state).channelId,
         meeting: getMeetingSettings(state).meeting,
         saveMeetingSettings,
+        getHashtagPreview,
+        importICS,
     };
 }
 
@@ -11,6 +11,8 @@ export default class MeetingSettingsModal extends React.PureComponent {
         meeting: PropTypes.object,
         fetchMeetingSettings: PropTypes.func.isRequired,
         saveMeetingSettings: PropTypes.func.isRequired,
+        getHashtagPreview: PropTypes.func.isRequired,
+        importICS: PropTypes.func.isRequired,
     };
 
     constructor(props) {
@@ -18,7 +20,17 @@ export default class MeetingSettingsModal extends React.PureComponent {
 
         this.state = {
             hashtag: '{{Jan02}}',
+            name: '',
             weekdays: [1],
+            time: '',
+            timezone: '',
+            reminder: '',
+            queueCutoff: '',
+            emptyCheck: '',
+            postAs: '',
+            errors: {},
+            hashtagPreview: null,
+            importResult: '',
         };
     }
 
@@ -31,7 +43,16 @@ export default class MeetingSettingsModal extends React.PureComponent {
             // eslint-disable-next-line react/no-did-update-set-state
             this.setState({
                 hashtag: this.props.meeting.hashtagFormat,
+                name: this.props.meeting.name || '',
                 weekdays: this.props.meeting.schedule || [],
+                time: this.props.meeting.time || '',
+                timezone: this.props.meeting.timezone || '',
+                reminder: this.props.meeting.reminder || '',
+                queueCutoff: this.props.meeting.queueCutoff || '',
+                emptyCheck: this.props.meeting.emptyCheck || '',
+                postAs: this.props.meeting.postAs || '',
+                errors: {},
+                hashtagPreview: null,
             });
         }
     }
@@ -39,6 +60,86 @@ export default class MeetingSettingsModal extends React.PureComponent {
     handleHashtagChange = (e) => {
         this.setState({
             hashtag: e.target.value,
+            hashtagPreview: null,
+        });
+    }
+
+    onPreviewHashtag = async () => {
+        const {data, error} = await this.props.getHashtagPreview(this.props.channelId, this.state.hashtag);
+        if (error) {
+            this.setState({
+                hashtagPreview: {occurrences: [], problems: [error.message || 'Failed to preview the hashtag']},
+            });
+            return;
+        }
+
+        this.setState({
+            hashtagPreview: data,
+        });
+    }
+
+    onImportICS = async (e) => {
+        const file = e.target.files[0];
+        e.target.value = '';
+        if (!file) {
+            return;
+        }
+
+        const {data, error} = await this.props.importICS(this.props.channelId, await file.text());
+        if (error) {
+            this.setState({
+                importResult: '',
+                errors: {...this.state.errors, importICS: error.message || 'Failed to import the calendar file'},
+            });
+            return;
+        }
+
+        this.setState({
+            importResult: `Imported the meeting schedule: ${data.description}`,
+            errors: {...this.state.errors, importICS: ''},
+        });
+        this.props.fetchMeetingSettings(this.props.channelId);
+    }
+
+    handleNameChange = (e) => {
+        this.setState({
+            name: e.target.value,
+        });
+    }
+
+    handleTimeChange = (e) => {
+        this.setState({
+            time: e.target.value,
+        });
+    }
+
+    handleTimezoneChange = (e) => {
+        this.setState({
+            timezone: e.target.value,
+        });
+    }
+
+    handleReminderChange = (e) => {
+        this.setState({
+            reminder: e.target.value,
+        });
+    }
+
+    handleQueueCutoffChange = (e) => {
+        this.setState({
+            queueCutoff: e.target.value,
+        });
+    }
+
+    handleEmptyCheckChange = (e) => {
+        this.setState({
+            emptyCheck: e.target.value,
+        });
+    }
+
+    handlePostAsChange = (e) => {
+        this.setState({
+            postAs: e.target.value,
         });
     }
 
@@ -59,16 +160,79 @@ export default class MeetingSettingsModal extends React.PureComponent {
         });
     }
 
-    onSave = () => {
-        this.props.saveMeetingSettings({
+    onSave = async () => {
+        const {error} = await this.props.saveMeetingSettings({
+            ...this.props.meeting,
             channelId: this.props.channelId,
             hashtagFormat: this.state.hashtag,
+            name: this.state.name,
             schedule: this.state.weekdays.sort(),
+            time: this.state.time,
+            timezone: this.state.timezone,
+            reminder: this.state.reminder,
+            queueCutoff: this.state.queueCutoff,
+            emptyCheck: this.state.emptyCheck,
+            postAs: this.state.postAs,
         });
 
+        if (error) {
+            this.setState({
+                errors: this.getFieldErrors(error),
+            });
+            return;
+        }
+
+        this.setState({
+            errors: {},
+        });
         this.props.close();
     }
 
+    getFieldErrors(error) {
+        try {
+            const {fields} = JSON.parse(error.message);
+            if (fields) {
+                return fields;
+            }
+        } catch (e) {
+            // The error is not a validation error
+        }
+
+        return {general: error.message || 'Failed to save the meeting settings'};
+    }
+
+    renderError(field) {
+        const error = this.state.errors[field];
+        if (!error) {
+            return null;
+        }
+
+        return <p className='has-error pt-1'><span className='control-label'>{error}</span></p>;
+    }
+
+    renderHashtagPreview() {
+        const preview = this.state.hashtagPreview;
+        if (!preview) {
+            return null;
+        }
+
+        return (
+            <div className='pt-1'>
+                <ul>
+                    {preview.occurrences.map((occurrence) => (
+                        <li key={occurrence.date}>{`${occurrence.date}: ${occurrence.hashtag}`}</li>
+                    ))}
+                </ul>
+                {preview.problems.map((problem) => (
+                    <p
+                        key={problem}
+                        className='has-error'
+                    ><span className='control-label'>{problem}</span></p>
+                ))}
+            </div>
+        );
+    }
+
     getDaysCheckboxes() {
         const weekDays = ['Sunday', 'Monday', 'Tuesday', 'Wednesday', 'Thursday', 'Friday', 'Saturday'];
 
@@ -116,6 +280,111 @@ export default class MeetingSettingsModal extends React.PureComponent {
                         <div className='p-2'>
                             {this.getDaysCheckboxes()}
                         </div>
+                        {this.renderError('schedule')}
+                    </div>
+                    <div className='form-group'>
+                        <label className='control-label'>{'Import from Calendar'}</label>
+                        <input
+                            type='file'
+                            accept='.ics,text/calendar'
+                            onChange={this.onImportICS}
+                        />
+                        <p className='text-muted pt-1'>{'Import the meeting day, time, timezone and cancelled meetings of the first recurring event of a calendar file. The schedule is saved right away.'}</p>
+                        {this.state.importResult && <p className='pt-1'>{this.state.importResult}</p>}
+                        {this.renderError('importICS')}
+                    </div>
+                    <div className='form-group'>
+                        <label className='control-label'>{'Meeting Name'}</label>
+                        <input
+                            onChange={this.handleNameChange}
+                            className='form-control'
+                            value={this.state.name}
+                        />
+                        <p className='text-muted pt-1'>{'Available to the hashtag format as {{ .MeetingName }}.'}</p>
+                        {this.renderError('name')}
+                    </div>
+                    <div className='form-group'>
+                        <label className='control-label'>{'Meeting Time'}</label>
+                        <input
+                            type='time'
+                            onChange={this.handleTimeChange}
+                            className='form-control'
+                            value={this.state.time}
+                        />
+                        {this.renderError('time')}
+                    </div>
+                    <div className='form-group'>
+                        <label className='control-label'>{'Timezone'}</label>
+                        <input
+                            onChange={this.handleTimezoneChange}
+                            className='form-control'
+                            placeholder='America/New_York'
+                            value={this.state.timezone}
+                        />
+                        <p className='text-muted pt-1'>{'IANA timezone of the meeting. Defaults to the server timezone.'}</p>
+                        {this.renderError('timezone')}
+                    </div>
+                    <div className='form-group'>
+                        <label className='control-label'>{'Agenda Reminder'}</label>
+                        <select
+                            onChange={this.handleReminderChange}
+                            className='form-control'
+                            value={this.state.reminder}
+                        >
+                            <option value=''>{'Off'}</option>
+                            <option value='15m0s'>{'15 minutes before'}</option>
+                            <option value='30m0s'>{'30 minutes before'}</option>
+                            <option value='1h0m0s'>{'1 hour before'}</option>
+                            <option value='2h0m0s'>{'2 hours before'}</option>
+                            <option value='24h0m0s'>{'1 day before'}</option>
+                            <option value='morning'>{'The morning of the meeting'}</option>
+                        </select>
+                        {this.renderError('reminder')}
+                    </div>
+                    <div className='form-group'>
+                        <label className='control-label'>{'Queue Cutoff'}</label>
+                        <select
+                            onChange={this.handleQueueCutoffChange}
+                            className='form-control'
+                            value={this.state.queueCutoff}
+                        >
+                            <option value=''>{'None'}</option>
+                            <option value='1h0m0s'>{'1 hour before'}</option>
+                            <option value='2h0m0s'>{'2 hours before'}</option>
+                            <option value='4h0m0s'>{'4 hours before'}</option>
+                            <option value='24h0m0s'>{'1 day before'}</option>
+                        </select>
+                        <p className='text-muted pt-1'>{'Items queued after the cutoff go to the following meeting.'}</p>
+                        {this.renderError('queueCutoff')}
+                    </div>
+                    <div className='form-group'>
+                        <label className='control-label'>{'Empty Agenda Check'}</label>
+                        <select
+                            onChange={this.handleEmptyCheckChange}
+                            className='form-control'
+                            value={this.state.emptyCheck}
+                        >
+                            <option value=''>{'Off'}</option>
+                            <option value='1h0m0s'>{'1 hour before'}</option>
+                            <option value='3h0m0s'>{'3 hours before'}</option>
+                            <option value='24h0m0s'>{'1 day before'}</option>
+                        </select>
+                        <p className='text-muted pt-1'>{'Propose to cancel the meeting when nothing is queued for it.'}</p>
+                        {this.renderError('emptyCheck')}
+                    </div>
+                    <div className='form-group'>
+                        <label className='control-label'>{'Post Items As'}</label>
+                        <select
+                            onChange={this.handlePostAsChange}
+                            className='form-control'
+                            value={this.state.postAs}
+                        >
+                            <option value=''>{'Server default'}</option>
+                            <option value='user'>{'The user queueing the item'}</option>
+                            <option value='bot'>{'The Agenda bot'}</option>
+                        </select>
+                        <p className='text-muted pt-1'>{'The Agenda bot can post in read-only channels, and shows who queued each item.'}</p>
+                        {this.renderError('postAs')}
                     </div>
                     <div className='form-group'>
                         <label className='control-label'>{'Hashtag Format'}</label>
@@ -130,8 +399,20 @@ export default class MeetingSettingsModal extends React.PureComponent {
                                 rel='noopener noreferrer'
                                 href='https://godoc.org/time#pkg-constants'
                             >{'Go time package.'}</a>
-                            {' Embed a date by surrounding what January 2, 2006 would look like with double curly braces, i.e. {{Jan02}}'}
+                            {' Embed a date with {{ .Date "Jan02" }}, writing what January 2, 2006 would look like. The format can also use {{ .ChannelName }}, {{ .MeetingName }}, {{ .ISOWeek }}, {{ .Year }} and {{ .Occurrence }}, the running number of the meeting.'}
                         </p>
+                        <button
+                            type='button'
+                            className='btn btn-link pl-0'
+                            onClick={this.onPreviewHashtag}
+                        >
+                            {'Preview hashtags'}
+                        </button>
+                        {this.renderHashtagPreview()}
+                        {this.renderError('hashtagFormat')}
+                    </div>
+                    <div>
+                        {this.renderError('general')}
                     </div>
                 </Modal.Body>
                 <Modal.Footer>
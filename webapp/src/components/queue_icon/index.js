@@ -0,0 +1,3 @@
+import QueueIcon from './queue_icon';
+
+export default QueueIcon;
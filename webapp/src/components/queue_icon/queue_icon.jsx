@@ -0,0 +1,11 @@
+import React from 'react';
+
+// QueueIcon is the icon of the channel header button opening the queue dialog
+export default function QueueIcon() {
+    return (
+        <i
+            className='icon fa fa-list-ol'
+            aria-hidden='true'
+        />
+    );
+}
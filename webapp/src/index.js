@@ -1,9 +1,11 @@
+import React from 'react';
 
-import {updateSearchTerms, updateSearchResultsTerms, updateRhsState, performSearch, openMeetingSettingsModal} from './actions';
+import {updateSearchTerms, updateSearchResultsTerms, updateRhsState, performSearch, openMeetingSettingsModal, openQueueDialog, queuePost} from './actions';
 
 import reducer from './reducer';
 
 import ChannelSettingsModal from './components/meeting_settings';
+import QueueIcon from './components/queue_icon';
 
 import {id as pluginId} from './manifest';
 export default class Plugin {
@@ -19,6 +21,18 @@ export default class Plugin {
             (channelId) => {
                 store.dispatch(openMeetingSettingsModal(channelId));
             });
+
+        registry.registerChannelHeaderButtonAction(
+            <QueueIcon/>,
+            (channel) => {
+                store.dispatch(openQueueDialog(channel.id));
+            },
+            'Queue agenda item',
+            'Queue an agenda item',
+        );
+
+        // The server confirms the queued item with an ephemeral message
+        registry.registerPostDropdownMenuAction('Add to agenda', queuePost);
     }
 }
 